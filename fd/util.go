@@ -214,6 +214,21 @@ var (
 		"byte_len_cmp":  {},
 		"array_len_cmp": {},
 	}
+	doIfNumCmpOpNodes = map[string]struct{}{
+		"num_cmp": {},
+	}
+	doIfTsAgeCmpOpNodes = map[string]struct{}{
+		"ts_age_cmp": {},
+	}
+	doIfFieldCheckOpNodes = map[string]struct{}{
+		"exists":    {},
+		"is_string": {},
+		"is_number": {},
+		"is_bool":   {},
+		"is_null":   {},
+		"is_object": {},
+		"is_array":  {},
+	}
 )
 
 func extractFieldOpVals(jsonNode *simplejson.Json) [][]byte {
@@ -248,8 +263,21 @@ func extractFieldOpNode(opName string, jsonNode *simplejson.Json) (doif.Node, er
 	if has {
 		caseSensitive = caseSensitiveNode.MustBool()
 	}
-	vals := extractFieldOpVals(jsonNode)
-	result, err = doif.NewFieldOpNode(opName, fieldPath, caseSensitive, vals)
+	valuesFileNode, has := jsonNode.CheckGet("values_file")
+	if has {
+		valuesFile, strErr := valuesFileNode.String()
+		if strErr != nil {
+			return nil, strErr
+		}
+		var vals [][]byte
+		if _, has := jsonNode.CheckGet("values"); has {
+			vals = extractFieldOpVals(jsonNode)
+		}
+		result, err = doif.NewFieldOpNodeWithValuesFile(opName, fieldPath, caseSensitive, vals, valuesFile)
+	} else {
+		vals := extractFieldOpVals(jsonNode)
+		result, err = doif.NewFieldOpNode(opName, fieldPath, caseSensitive, vals)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to init field op: %w", err)
 	}
@@ -265,6 +293,9 @@ const (
 	fieldNameField    = "field"
 	fieldNameCmpOp    = "cmp_op"
 	fieldNameCmpValue = "value"
+	fieldNameFormat   = "format"
+
+	defaultTsFormat = "rfc3339nano"
 )
 
 func extractLengthCmpOpNode(opName string, jsonNode *simplejson.Json) (doif.Node, error) {
@@ -298,6 +329,93 @@ func extractLengthCmpOpNode(opName string, jsonNode *simplejson.Json) (doif.Node
 	return doif.NewLenCmpOpNode(opName, fieldPath, cmpOp, cmpValue)
 }
 
+// extractFieldAndCmpOp extracts the required field and cmp_op of the comparison op nodes.
+func extractFieldAndCmpOp(jsonNode *simplejson.Json) (string, string, error) {
+	fieldPathNode, has := jsonNode.CheckGet(fieldNameField)
+	if !has {
+		return "", "", noRequiredFieldError(fieldNameField)
+	}
+	fieldPath, err := fieldPathNode.String()
+	if err != nil {
+		return "", "", err
+	}
+
+	cmpOpNode, has := jsonNode.CheckGet(fieldNameCmpOp)
+	if !has {
+		return "", "", noRequiredFieldError(fieldNameCmpOp)
+	}
+	cmpOp, err := cmpOpNode.String()
+	if err != nil {
+		return "", "", err
+	}
+
+	return fieldPath, cmpOp, nil
+}
+
+func extractNumCmpOpNode(jsonNode *simplejson.Json) (doif.Node, error) {
+	fieldPath, cmpOp, err := extractFieldAndCmpOp(jsonNode)
+	if err != nil {
+		return nil, err
+	}
+
+	cmpValueNode, has := jsonNode.CheckGet(fieldNameCmpValue)
+	if !has {
+		return nil, noRequiredFieldError(fieldNameCmpValue)
+	}
+	cmpValue, err := cmpValueNode.Float64()
+	if err != nil {
+		return nil, err
+	}
+
+	return doif.NewNumCmpOpNode(fieldPath, cmpOp, cmpValue)
+}
+
+func extractTsAgeCmpOpNode(jsonNode *simplejson.Json) (doif.Node, error) {
+	fieldPath, cmpOp, err := extractFieldAndCmpOp(jsonNode)
+	if err != nil {
+		return nil, err
+	}
+
+	cmpValueNode, has := jsonNode.CheckGet(fieldNameCmpValue)
+	if !has {
+		return nil, noRequiredFieldError(fieldNameCmpValue)
+	}
+	cmpValueStr, err := cmpValueNode.String()
+	if err != nil {
+		return nil, err
+	}
+	cmpValue, err := time.ParseDuration(cmpValueStr)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", fieldNameCmpValue, err)
+	}
+
+	format := defaultTsFormat
+	if formatNode, has := jsonNode.CheckGet(fieldNameFormat); has {
+		if format, err = formatNode.String(); err != nil {
+			return nil, err
+		}
+	}
+	layout, err := pipeline.ParseFormatName(format)
+	if err != nil {
+		layout = format
+	}
+
+	return doif.NewTsAgeCmpOpNode(fieldPath, layout, cmpOp, cmpValue)
+}
+
+func extractFieldCheckOpNode(opName string, jsonNode *simplejson.Json) (doif.Node, error) {
+	fieldPathNode, has := jsonNode.CheckGet(fieldNameField)
+	if !has {
+		return nil, noRequiredFieldError(fieldNameField)
+	}
+	fieldPath, err := fieldPathNode.String()
+	if err != nil {
+		return nil, err
+	}
+
+	return doif.NewFieldCheckOpNode(opName, fieldPath)
+}
+
 func extractLogicalOpNode(opName string, jsonNode *simplejson.Json) (doif.Node, error) {
 	var result, operand doif.Node
 	var err error
@@ -330,6 +448,12 @@ func extractDoIfNode(jsonNode *simplejson.Json) (doif.Node, error) {
 		return extractFieldOpNode(opName, jsonNode)
 	} else if _, has := doIfLengthCmpOpNodes[opName]; has {
 		return extractLengthCmpOpNode(opName, jsonNode)
+	} else if _, has := doIfNumCmpOpNodes[opName]; has {
+		return extractNumCmpOpNode(jsonNode)
+	} else if _, has := doIfTsAgeCmpOpNodes[opName]; has {
+		return extractTsAgeCmpOpNode(jsonNode)
+	} else if _, has := doIfFieldCheckOpNodes[opName]; has {
+		return extractFieldCheckOpNode(opName, jsonNode)
 	} else {
 		return nil, fmt.Errorf("unknown op %q", opName)
 	}
@@ -348,6 +472,14 @@ func extractDoIfChecker(actionJSON *simplejson.Json) (*doif.Checker, error) {
 	return result, nil
 }
 
+// ExtractDoIfChecker builds the checker from the decoded "do_if" object.
+// It is used by plugins which have their own "do_if" conditions, e.g. per rule.
+func ExtractDoIfChecker(raw map[string]any) (*doif.Checker, error) {
+	doIfJSON := simplejson.New()
+	doIfJSON.SetPath(nil, raw)
+	return extractDoIfChecker(doIfJSON)
+}
+
 func makeActionJSON(actionJSON *simplejson.Json) []byte {
 	actionJSON.Del("type")
 	actionJSON.Del("match_fields")
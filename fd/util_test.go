@@ -2,9 +2,11 @@ package fd
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/bitly/go-simplejson"
 	"github.com/ozontech/file.d/pipeline"
@@ -51,6 +53,15 @@ type doIfTreeNode struct {
 	lenCmpOp string
 	cmpOp    string
 	cmpValue int
+
+	numCmpOp    string
+	numCmpValue float64
+
+	tsAgeCmpOp string
+	tsFormat   string
+	tsCmpValue time.Duration
+
+	fieldCheckOp string
 }
 
 // nolint:gocritic
@@ -78,6 +89,12 @@ func buildDoIfTree(node *doIfTreeNode) (doif.Node, error) {
 		)
 	case node.lenCmpOp != "":
 		return doif.NewLenCmpOpNode(node.lenCmpOp, node.fieldName, node.cmpOp, node.cmpValue)
+	case node.numCmpOp != "":
+		return doif.NewNumCmpOpNode(node.fieldName, node.cmpOp, node.numCmpValue)
+	case node.tsAgeCmpOp != "":
+		return doif.NewTsAgeCmpOpNode(node.fieldName, node.tsFormat, node.cmpOp, node.tsCmpValue)
+	case node.fieldCheckOp != "":
+		return doif.NewFieldCheckOpNode(node.fieldCheckOp, node.fieldName)
 	default:
 		return nil, errors.New("unknown type of node")
 	}
@@ -244,6 +261,64 @@ func Test_extractDoIfChecker(t *testing.T) {
 				cmpValue:  10,
 			},
 		},
+		{
+			name: "ok_num_cmp_op",
+			args: args{
+				cfgStr: `{"op":"num_cmp","field":"status","cmp_op":"ge","value":499.5}`,
+			},
+			want: &doIfTreeNode{
+				numCmpOp:    "num_cmp",
+				cmpOp:       "ge",
+				fieldName:   "status",
+				numCmpValue: 499.5,
+			},
+		},
+		{
+			name: "ok_ts_age_cmp_op",
+			args: args{
+				cfgStr: `{"op":"ts_age_cmp","field":"time","cmp_op":"gt","value":"1h"}`,
+			},
+			want: &doIfTreeNode{
+				tsAgeCmpOp: "ts_age_cmp",
+				cmpOp:      "gt",
+				fieldName:  "time",
+				tsFormat:   time.RFC3339Nano,
+				tsCmpValue: time.Hour,
+			},
+		},
+		{
+			name: "ok_ts_age_cmp_op_layout",
+			args: args{
+				cfgStr: `{"op":"ts_age_cmp","field":"time","format":"2006-01-02","cmp_op":"lt","value":"24h"}`,
+			},
+			want: &doIfTreeNode{
+				tsAgeCmpOp: "ts_age_cmp",
+				cmpOp:      "lt",
+				fieldName:  "time",
+				tsFormat:   "2006-01-02",
+				tsCmpValue: 24 * time.Hour,
+			},
+		},
+		{
+			name: "ok_exists_op",
+			args: args{
+				cfgStr: `{"op":"exists","field":"trace_id"}`,
+			},
+			want: &doIfTreeNode{
+				fieldCheckOp: "exists",
+				fieldName:    "trace_id",
+			},
+		},
+		{
+			name: "ok_is_object_op",
+			args: args{
+				cfgStr: `{"op":"is_object","field":"request"}`,
+			},
+			want: &doIfTreeNode{
+				fieldCheckOp: "is_object",
+				fieldName:    "request",
+			},
+		},
 		{
 			name: "ok_single_val",
 			args: args{
@@ -375,6 +450,36 @@ func Test_extractDoIfChecker(t *testing.T) {
 			args:    args{cfgStr: `{"op":"byte_len_cmp","field":"data","cmp_op":"lt","value":-1}`},
 			wantErr: true,
 		},
+		{
+			name:    "error_num_cmp_op_no_cmp_value",
+			args:    args{cfgStr: `{"op":"num_cmp","field":"status","cmp_op":"lt"}`},
+			wantErr: true,
+		},
+		{
+			name:    "error_num_cmp_op_cmp_value_is_not_number",
+			args:    args{cfgStr: `{"op":"num_cmp","field":"status","cmp_op":"lt","value":"500"}`},
+			wantErr: true,
+		},
+		{
+			name:    "error_ts_age_cmp_op_invalid_duration",
+			args:    args{cfgStr: `{"op":"ts_age_cmp","field":"time","cmp_op":"gt","value":"1 hour"}`},
+			wantErr: true,
+		},
+		{
+			name:    "error_ts_age_cmp_op_invalid_cmp_op",
+			args:    args{cfgStr: `{"op":"ts_age_cmp","field":"time","cmp_op":"older","value":"1h"}`},
+			wantErr: true,
+		},
+		{
+			name:    "error_field_op_values_file_not_found",
+			args:    args{cfgStr: `{"op":"equal","field":"pod","values_file":"/nonexistent/values.txt"}`},
+			wantErr: true,
+		},
+		{
+			name:    "error_exists_op_no_field",
+			args:    args{cfgStr: `{"op":"exists"}`},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		tt := tt
@@ -402,3 +507,30 @@ func Test_extractDoIfChecker(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractDoIfChecker(t *testing.T) {
+	var raw map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"op": "and",
+		"operands": [
+			{"op": "equal", "field": "service", "values": ["svc"]},
+			{"op": "byte_len_cmp", "field": "msg", "cmp_op": "gt", "value": 10}
+		]
+	}`), &raw))
+
+	got, err := ExtractDoIfChecker(raw)
+	require.NoError(t, err)
+
+	wantTree, err := buildDoIfTree(&doIfTreeNode{
+		logicalOp: "and",
+		operands: []*doIfTreeNode{
+			{fieldOp: "equal", fieldName: "service", caseSensitive: true, values: [][]byte{[]byte("svc")}},
+			{lenCmpOp: "byte_len_cmp", fieldName: "msg", cmpOp: "gt", cmpValue: 10},
+		},
+	})
+	require.NoError(t, err)
+	assert.NoError(t, doif.NewChecker(wantTree).IsEqualTo(got))
+
+	_, err = ExtractDoIfChecker(map[string]any{"op": "unknown"})
+	require.Error(t, err)
+}
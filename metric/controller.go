@@ -101,6 +101,15 @@ func (mc *Ctl) RegisterHistogramVec(name, help string, buckets []float64, labels
 	return mc.registerMetric(name, histogramVec).(*prometheus.HistogramVec)
 }
 
+// IsRegistered returns true if the metric with the name is already registered.
+func (mc *Ctl) IsRegistered(name string) bool {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	_, has := mc.metrics[name]
+	return has
+}
+
 func (mc *Ctl) registerMetric(name string, newMetric prometheus.Collector) prometheus.Collector {
 	mc.mu.RLock()
 	metric, has := mc.metrics[name]
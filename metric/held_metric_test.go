@@ -133,3 +133,17 @@ func BenchmarkPromVec(b *testing.B) {
 		})
 	}
 }
+
+func TestHolderReturnsSameHeldVec(t *testing.T) {
+	r := require.New(t)
+
+	ctl := NewCtl("test", prometheus.NewRegistry())
+	holder := NewHolder(time.Minute)
+
+	first := holder.AddCounterVec(ctl.RegisterCounterVec("errors", "", "level"))
+	second := holder.AddCounterVec(ctl.RegisterCounterVec("errors", "", "level"))
+
+	// the same store must be used, otherwise one held vec deletes the labels used by another one
+	r.Equal(first.store, second.store)
+	r.Equal(1, len(holder.heldMetrics))
+}
@@ -1,6 +1,7 @@
 package metric
 
 import (
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -12,7 +13,13 @@ type heldMetricVec interface {
 
 type Holder struct {
 	holdDuration time.Duration
-	heldMetrics  []heldMetricVec
+
+	// heldMetrics can be added by the plugins at runtime, e.g. when new processors are started
+	mu          sync.Mutex
+	heldMetrics []heldMetricVec
+	// heldByVec is used to return the same held vec for the same prometheus vec,
+	// otherwise one held vec may delete labels which are in use by another one
+	heldByVec map[prometheus.Collector]heldMetricVec
 }
 
 // NewHolder returns new metric holder. The holdDuration must be more than 1m.
@@ -23,6 +30,7 @@ func NewHolder(holdDuration time.Duration) *Holder {
 	return &Holder{
 		holdDuration: holdDuration,
 		heldMetrics:  make([]heldMetricVec, 0),
+		heldByVec:    make(map[prometheus.Collector]heldMetricVec),
 	}
 }
 
@@ -31,25 +39,36 @@ func (h *Holder) Maintenance() {
 }
 
 func (h *Holder) AddCounterVec(counterVec *prometheus.CounterVec) HeldCounterVec {
-	hcv := NewHeldCounterVec(counterVec)
-	h.heldMetrics = append(h.heldMetrics, hcv)
-	return hcv
+	return addHeldVec(h, counterVec, NewHeldCounterVec)
 }
 
 func (h *Holder) AddGaugeVec(gaugeVec *prometheus.GaugeVec) HeldGaugeVec {
-	hgv := NewHeldGaugeVec(gaugeVec)
-	h.heldMetrics = append(h.heldMetrics, hgv)
-	return hgv
+	return addHeldVec(h, gaugeVec, NewHeldGaugeVec)
 }
 
 func (h *Holder) AddHistogramVec(histogramVec *prometheus.HistogramVec) HeldHistogramVec {
-	hhv := NewHeldHistogramVec(histogramVec)
-	h.heldMetrics = append(h.heldMetrics, hhv)
-	return hhv
+	return addHeldVec(h, histogramVec, NewHeldHistogramVec)
+}
+
+func addHeldVec[V prometheus.Collector, H heldMetricVec](h *Holder, vec V, newHeld func(V) H) H {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if held, has := h.heldByVec[vec]; has {
+		return held.(H)
+	}
+
+	held := newHeld(vec)
+	h.heldByVec[vec] = held
+	h.heldMetrics = append(h.heldMetrics, held)
+	return held
 }
 
 // DeleteOldMetrics delete old metric labels, that aren't in use since last update.
 func (h *Holder) DeleteOldMetrics() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	for i := range h.heldMetrics {
 		h.heldMetrics[i].DeleteOldMetrics(h.holdDuration)
 	}
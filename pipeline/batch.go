@@ -98,6 +98,11 @@ func (b *Batch) updateStatus() BatchStatus {
 	return b.status
 }
 
+// Seq returns the sequence number of the batch, it changes every time the batch is sent by the batcher.
+func (b *Batch) Seq() int64 {
+	return b.seq
+}
+
 func (b *Batch) ForEach(cb func(event *Event)) {
 	for _, event := range b.events {
 		if event.IsChildParentKind() {
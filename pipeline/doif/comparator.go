@@ -1,6 +1,9 @@
 package doif
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type comparisonOperation string
 
@@ -18,12 +21,20 @@ type comparator struct {
 	cmpValue int
 }
 
-func newComparator(cmpOp string, cmpValue int) (comparator, error) {
+func newComparisonOperation(cmpOp string) (comparisonOperation, error) {
 	typedCmpOp := comparisonOperation(cmpOp)
 	switch typedCmpOp {
 	case cmpOpLess, cmpOpLessOrEqual, cmpOpGreater, cmpOpGreaterOrEqual, cmpOpEqual, cmpOpNotEqual:
+		return typedCmpOp, nil
 	default:
-		return comparator{}, fmt.Errorf("unknown comparison operation: %s", typedCmpOp)
+		return "", fmt.Errorf("unknown comparison operation: %s", typedCmpOp)
+	}
+}
+
+func newComparator(cmpOp string, cmpValue int) (comparator, error) {
+	typedCmpOp, err := newComparisonOperation(cmpOp)
+	if err != nil {
+		return comparator{}, err
 	}
 
 	if cmpValue < 0 {
@@ -37,19 +48,23 @@ func newComparator(cmpOp string, cmpValue int) (comparator, error) {
 }
 
 func (c comparator) compare(value int) bool {
-	switch c.cmpOp {
+	return compareValues(c.cmpOp, value, c.cmpValue)
+}
+
+func compareValues[T int | float64 | time.Duration](cmpOp comparisonOperation, lhs, rhs T) bool {
+	switch cmpOp {
 	case cmpOpLess:
-		return value < c.cmpValue
+		return lhs < rhs
 	case cmpOpLessOrEqual:
-		return value <= c.cmpValue
+		return lhs <= rhs
 	case cmpOpGreater:
-		return value > c.cmpValue
+		return lhs > rhs
 	case cmpOpGreaterOrEqual:
-		return value >= c.cmpValue
+		return lhs >= rhs
 	case cmpOpEqual:
-		return value == c.cmpValue
+		return lhs == rhs
 	case cmpOpNotEqual:
-		return value != c.cmpValue
+		return lhs != rhs
 	default:
 		panic("invalid cmp op")
 	}
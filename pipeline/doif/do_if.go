@@ -20,6 +20,15 @@ const (
 
 	// > Type of node where logical rules for applying other rules are stored.
 	NodeLogicalOp // *
+
+	// > Type of node where numeric comparison rules for fields are stored.
+	NodeNumCmpOp // *
+
+	// > Type of node where timestamp age comparison rules for fields are stored.
+	NodeTsAgeCmpOp // *
+
+	// > Type of node where existence and type checks of fields are stored.
+	NodeFieldCheckOp // *
 )
 
 type Node interface {
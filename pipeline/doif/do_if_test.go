@@ -3,9 +3,12 @@ package doif
 import (
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -24,6 +27,15 @@ type treeNode struct {
 	lenCmpOp string
 	cmpOp    string
 	cmpValue int
+
+	numCmpOp    string
+	numCmpValue float64
+
+	tsAgeCmpOp string
+	tsFormat   string
+	tsCmpValue time.Duration
+
+	fieldCheckOp string
 }
 
 // nolint:gocritic
@@ -51,6 +63,12 @@ func buildTree(node treeNode) (Node, error) {
 		)
 	case node.lenCmpOp != "":
 		return NewLenCmpOpNode(node.lenCmpOp, node.fieldName, node.cmpOp, node.cmpValue)
+	case node.numCmpOp != "":
+		return NewNumCmpOpNode(node.fieldName, node.cmpOp, node.numCmpValue)
+	case node.tsAgeCmpOp != "":
+		return NewTsAgeCmpOpNode(node.fieldName, node.tsFormat, node.cmpOp, node.tsCmpValue)
+	case node.fieldCheckOp != "":
+		return NewFieldCheckOpNode(node.fieldCheckOp, node.fieldName)
 	default:
 		return nil, errors.New("unknown type of node")
 	}
@@ -107,6 +125,8 @@ func checkNode(t *testing.T, want, got Node) {
 		assert.Equal(t, wantNode.lenCmpOp, gotNode.lenCmpOp)
 		assert.NoError(t, wantNode.comparator.isEqualTo(gotNode.comparator))
 		assert.Equal(t, 0, slices.Compare[[]string](wantNode.fieldPath, gotNode.fieldPath))
+	case NodeNumCmpOp, NodeTsAgeCmpOp, NodeFieldCheckOp:
+		assert.NoError(t, want.isEqualTo(got, 1))
 	default:
 		t.Error("unknown node type")
 	}
@@ -685,6 +705,87 @@ func TestCheck(t *testing.T) {
 				{`{"pod":"my-TEST-2","test-field":"non-empty"}`, false},
 			},
 		},
+		{
+			name: "num_cmp_ge",
+			tree: treeNode{
+				numCmpOp:    numCmpOpTag,
+				cmpOp:       "ge",
+				fieldName:   "status",
+				numCmpValue: 500,
+			},
+			data: []argsResp{
+				{`{"status":500}`, true},
+				{`{"status":503.5}`, true},
+				{`{"status":"502"}`, true},
+				{`{"status":499}`, false},
+				{`{"status":"4e2"}`, false},
+				{`{"status":"error"}`, false},
+				{`{"status":true}`, false},
+				{`{"status":[500]}`, false},
+				{`{"code":500}`, false},
+			},
+		},
+		{
+			name: "num_cmp_lt_float",
+			tree: treeNode{
+				numCmpOp:    numCmpOpTag,
+				cmpOp:       "lt",
+				fieldName:   "took",
+				numCmpValue: 0.5,
+			},
+			data: []argsResp{
+				{`{"took":0.25}`, true},
+				{`{"took":-1}`, true},
+				{`{"took":0.5}`, false},
+			},
+		},
+		{
+			name: "exists",
+			tree: treeNode{
+				fieldCheckOp: fieldCheckExistsOpTag,
+				fieldName:    "a.b",
+			},
+			data: []argsResp{
+				{`{"a":{"b":null}}`, true},
+				{`{"a":{"b":""}}`, true},
+				{`{"a":{"c":1}}`, false},
+				{`{"a":"b"}`, false},
+			},
+		},
+		{
+			name: "is_number",
+			tree: treeNode{
+				fieldCheckOp: fieldCheckIsNumberOpTag,
+				fieldName:    "v",
+			},
+			data: []argsResp{
+				{`{"v":1}`, true},
+				{`{"v":-1.5e3}`, true},
+				{`{"v":"1"}`, false},
+				{`{}`, false},
+			},
+		},
+		{
+			name: "type_checks",
+			tree: treeNode{
+				logicalOp: "or",
+				operands: []treeNode{
+					{fieldCheckOp: fieldCheckIsStringOpTag, fieldName: "s"},
+					{fieldCheckOp: fieldCheckIsBoolOpTag, fieldName: "b"},
+					{fieldCheckOp: fieldCheckIsNullOpTag, fieldName: "n"},
+					{fieldCheckOp: fieldCheckIsObjectOpTag, fieldName: "o"},
+					{fieldCheckOp: fieldCheckIsArrayOpTag, fieldName: "a"},
+				},
+			},
+			data: []argsResp{
+				{`{"s":""}`, true},
+				{`{"b":false}`, true},
+				{`{"n":null}`, true},
+				{`{"o":{}}`, true},
+				{`{"a":[]}`, true},
+				{`{"s":1,"b":"true","n":0,"o":[],"a":{}}`, false},
+			},
+		},
 		{
 			name: "byte_len_cmp_lt",
 			tree: treeNode{
@@ -894,6 +995,125 @@ func TestCheck(t *testing.T) {
 	}
 }
 
+func TestCheckTsAgeCmp(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		format   string
+		cmpOp    string
+		cmpValue time.Duration
+		data     map[string]bool
+	}{
+		{
+			name:     "older_than",
+			format:   time.RFC3339,
+			cmpOp:    "gt",
+			cmpValue: time.Hour,
+			data: map[string]bool{
+				`{"ts":"2024-01-01T10:00:00Z"}`:      true,
+				`{"ts":"2024-01-01T12:30:00+02:00"}`: true,
+				`{"ts":"2024-01-01T11:30:00Z"}`:      false,
+				`{"ts":"2024-01-01T13:00:00Z"}`:      false,
+				`{"ts":"yesterday"}`:                 false,
+				`{"ts":1704096000}`:                  false,
+				`{"time":"2024-01-01T10:00:00Z"}`:    false,
+			},
+		},
+		{
+			name:     "unixtime",
+			format:   unixTimeFormat,
+			cmpOp:    "le",
+			cmpValue: time.Minute,
+			data: map[string]bool{
+				`{"ts":1704110370}`:     true,
+				`{"ts":"1704110340.5"}`: true,
+				`{"ts":1704110339}`:     false,
+				`{"ts":"now"}`:          false,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := buildTree(treeNode{
+				tsAgeCmpOp: tsAgeCmpOpTag,
+				fieldName:  "ts",
+				tsFormat:   tt.format,
+				cmpOp:      tt.cmpOp,
+				tsCmpValue: tt.cmpValue,
+			})
+			require.NoError(t, err)
+			root.(*tsAgeCmpOpNode).nowFn = func() time.Time { return now }
+
+			checker := NewChecker(root)
+			for event, want := range tt.data {
+				eventRoot, err := insaneJSON.DecodeString(event)
+				require.NoError(t, err)
+				assert.Equal(t, want, checker.Check(eventRoot), "invalid result for event %q", event)
+			}
+		})
+	}
+}
+
+func TestFieldOpValuesFile(t *testing.T) {
+	defer func(interval time.Duration) {
+		valuesFileCheckInterval = interval
+	}(valuesFileCheckInterval)
+	valuesFileCheckInterval = 0
+
+	path := filepath.Join(t.TempDir(), "values.txt")
+	require.NoError(t, os.WriteFile(path, []byte("pod-1\r\n\npod-2\n"), 0o644))
+
+	node, err := NewFieldOpNodeWithValuesFile(fieldEqualOpTag, "pod", false, [][]byte{[]byte("pod-0")}, path)
+	require.NoError(t, err)
+	checker := NewChecker(node)
+
+	check := func(event string) bool {
+		eventRoot, err := insaneJSON.DecodeString(event)
+		require.NoError(t, err)
+		defer insaneJSON.Release(eventRoot)
+		return checker.Check(eventRoot)
+	}
+
+	assert.True(t, check(`{"pod":"pod-0"}`))
+	assert.True(t, check(`{"pod":"POD-1"}`))
+	assert.True(t, check(`{"pod":"pod-2"}`))
+	assert.False(t, check(`{"pod":""}`))
+	assert.False(t, check(`{"pod":"pod-3"}`))
+
+	require.NoError(t, os.WriteFile(path, []byte("pod-3\n"), 0o644))
+	// make sure the modification time is changed even on coarse-grained file systems
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	require.Eventually(t, func() bool {
+		return check(`{"pod":"pod-3"}`)
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, check(`{"pod":"pod-0"}`))
+	assert.False(t, check(`{"pod":"pod-1"}`))
+
+	// the previous values are kept if the file is removed
+	require.NoError(t, os.Remove(path))
+	for i := 0; i < 10; i++ {
+		assert.True(t, check(`{"pod":"pod-3"}`))
+	}
+
+	_, err = NewFieldOpNodeWithValuesFile(fieldEqualOpTag, "pod", true, nil, path)
+	require.Error(t, err)
+}
+
+func TestFieldOpEmptyValuesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "values.txt")
+	require.NoError(t, os.WriteFile(path, nil, 0o644))
+
+	node, err := NewFieldOpNodeWithValuesFile(fieldRegexOpTag, "pod", true, nil, path)
+	require.NoError(t, err)
+
+	eventRoot, err := insaneJSON.DecodeString(`{"pod":"pod-1"}`)
+	require.NoError(t, err)
+	defer insaneJSON.Release(eventRoot)
+	assert.False(t, NewChecker(node).Check(eventRoot))
+}
+
 const userInfoRawJSON = `
 {
 	"name": "jack",
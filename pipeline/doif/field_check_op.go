@@ -0,0 +1,142 @@
+package doif
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/ozontech/file.d/cfg"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+/*{ do-if-field-check-op-node
+DoIf field check op node is considered to always be a leaf in the DoIf tree like DoIf field op node.
+It checks whether the field exists or has certain JSON type.
+
+Params:
+  - `op` - one of `exists`, `is_string`, `is_number`, `is_bool`, `is_null`, `is_object`, `is_array`. Required.
+  - `field` - name of the field to check. Required.
+
+The `exists` op matches the field with any value, including `null`.
+
+Example:
+```yaml
+pipelines:
+  test:
+    actions:
+      - type: discard
+        do_if:
+          op: not
+          operands:
+            - op: is_object
+              field: request
+```
+
+Result:
+```
+{"request":{"id":1}}  # not discarded
+{"request":"id=1"}    # discarded
+{"response":{}}       # discarded ('request' not found)
+```
+}*/
+
+type fieldCheckOpType int
+
+const (
+	fieldCheckExistsOp fieldCheckOpType = iota
+	fieldCheckIsStringOp
+	fieldCheckIsNumberOp
+	fieldCheckIsBoolOp
+	fieldCheckIsNullOp
+	fieldCheckIsObjectOp
+	fieldCheckIsArrayOp
+)
+
+const (
+	fieldCheckExistsOpTag   = "exists"
+	fieldCheckIsStringOpTag = "is_string"
+	fieldCheckIsNumberOpTag = "is_number"
+	fieldCheckIsBoolOpTag   = "is_bool"
+	fieldCheckIsNullOpTag   = "is_null"
+	fieldCheckIsObjectOpTag = "is_object"
+	fieldCheckIsArrayOpTag  = "is_array"
+)
+
+type fieldCheckOpNode struct {
+	op        fieldCheckOpType
+	fieldPath []string
+}
+
+func NewFieldCheckOpNode(op string, field string) (Node, error) {
+	var checkOp fieldCheckOpType
+	switch op {
+	case fieldCheckExistsOpTag:
+		checkOp = fieldCheckExistsOp
+	case fieldCheckIsStringOpTag:
+		checkOp = fieldCheckIsStringOp
+	case fieldCheckIsNumberOpTag:
+		checkOp = fieldCheckIsNumberOp
+	case fieldCheckIsBoolOpTag:
+		checkOp = fieldCheckIsBoolOp
+	case fieldCheckIsNullOpTag:
+		checkOp = fieldCheckIsNullOp
+	case fieldCheckIsObjectOpTag:
+		checkOp = fieldCheckIsObjectOp
+	case fieldCheckIsArrayOpTag:
+		checkOp = fieldCheckIsArrayOp
+	default:
+		return nil, fmt.Errorf("bad field check op: %s", op)
+	}
+
+	return &fieldCheckOpNode{
+		op:        checkOp,
+		fieldPath: cfg.ParseFieldSelector(field),
+	}, nil
+}
+
+func (n *fieldCheckOpNode) Type() NodeType {
+	return NodeFieldCheckOp
+}
+
+func (n *fieldCheckOpNode) Check(eventRoot *insaneJSON.Root) bool {
+	node := eventRoot.Dig(n.fieldPath...)
+	if node == nil {
+		return false
+	}
+
+	switch n.op {
+	case fieldCheckExistsOp:
+		return true
+	case fieldCheckIsStringOp:
+		return node.IsString()
+	case fieldCheckIsNumberOp:
+		return node.IsNumber()
+	case fieldCheckIsBoolOp:
+		return node.IsTrue() || node.IsFalse()
+	case fieldCheckIsNullOp:
+		return node.IsNull()
+	case fieldCheckIsObjectOp:
+		return node.IsObject()
+	case fieldCheckIsArrayOp:
+		return node.IsArray()
+	default:
+		panic("impossible: bad field check op")
+	}
+}
+
+func (n *fieldCheckOpNode) isEqualTo(n2 Node, _ int) error {
+	n2Explicit, ok := n2.(*fieldCheckOpNode)
+	if !ok {
+		return errors.New("nodes have different types; expected: fieldCheckOpNode")
+	}
+
+	if n.op != n2Explicit.op {
+		return fmt.Errorf("nodes have different field check operations: %d != %d", n.op, n2Explicit.op)
+	}
+
+	if slices.Compare(n.fieldPath, n2Explicit.fieldPath) != 0 {
+		return fmt.Errorf("nodes have different fieldPathStr; expected: fieldPath=%v", n.fieldPath)
+	}
+
+	return nil
+}
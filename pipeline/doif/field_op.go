@@ -169,7 +169,10 @@ the values to check against.
 Params:
   - `op` - value from field operations list. Required.
   - `field` - name of the field to apply operation. Required.
-  - `values` - list of values to check field. Required non-empty.
+  - `values` - list of values to check field. Required non-empty if `values_file` isn't set.
+  - `values_file` - path to the file with the values to check field, one value per line, the empty lines are skipped.
+    The values are added to `values`. The file is checked for changes every 10 seconds while the events are checked,
+    and the changed values are applied without the restart. If the changed file can't be read, the previous values are used.
   - `case_sensitive` - flag indicating whether checks are performed in case sensitive way. Default `true`.
     Note: case insensitive checks can cause CPU and memory overhead since every field value will be converted to lower letters.
 
@@ -186,6 +189,18 @@ pipelines:
           case_sensitive: true
 ```
 
+Example with the values file:
+```yaml
+pipelines:
+  tests:
+    actions:
+      - type: discard
+        do_if:
+          op: equal
+          field: tenant
+          values_file: /etc/file.d/blocked_tenants.txt
+```
+
 }*/
 
 type fieldOpNode struct {
@@ -199,9 +214,58 @@ type fieldOpNode struct {
 
 	minValLen int
 	maxValLen int
+
+	// valuesFile is set if the values are loaded from the file, the values above are empty then
+	valuesFile *valuesFile
 }
 
 func NewFieldOpNode(op string, field string, caseSensitive bool, values [][]byte) (Node, error) {
+	return newFieldOpNode(op, field, caseSensitive, values)
+}
+
+// NewFieldOpNodeWithValuesFile creates the field op node which checks the values
+// and the values from the newline-delimited file. The file is reloaded when it's changed.
+func NewFieldOpNodeWithValuesFile(op string, field string, caseSensitive bool, values [][]byte, valuesFile string) (Node, error) {
+	if field == "" {
+		return nil, errors.New("field is not specified")
+	}
+	fop, err := parseFieldOpType(op)
+	if err != nil {
+		return nil, err
+	}
+
+	vf, err := newValuesFile(valuesFile, op, field, caseSensitive, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load values file: %w", err)
+	}
+
+	return &fieldOpNode{
+		op:            fop,
+		fieldPath:     cfg.ParseFieldSelector(field),
+		fieldPathStr:  field,
+		caseSensitive: caseSensitive,
+		valuesFile:    vf,
+	}, nil
+}
+
+func parseFieldOpType(op string) (fieldOpType, error) {
+	switch op {
+	case fieldEqualOpTag:
+		return fieldEqualOp, nil
+	case fieldContainsOpTag:
+		return fieldContainsOp, nil
+	case fieldPrefixOpTag:
+		return fieldPrefixOp, nil
+	case fieldSuffixOpTag:
+		return fieldSuffixOp, nil
+	case fieldRegexOpTag:
+		return fieldRegexOp, nil
+	default:
+		return fieldUnknownOp, fmt.Errorf("unknown field op %q", op)
+	}
+}
+
+func newFieldOpNode(op string, field string, caseSensitive bool, values [][]byte) (*fieldOpNode, error) {
 	if field == "" {
 		return nil, errors.New("field is not specified")
 	}
@@ -212,21 +276,14 @@ func NewFieldOpNode(op string, field string, caseSensitive bool, values [][]byte
 	var valsBySize map[int][][]byte
 	var reValues []*regexp.Regexp
 	var minValLen, maxValLen int
-	var fop fieldOpType
 
 	fieldPath := cfg.ParseFieldSelector(field)
 
-	switch op {
-	case fieldEqualOpTag:
-		fop = fieldEqualOp
-	case fieldContainsOpTag:
-		fop = fieldContainsOp
-	case fieldPrefixOpTag:
-		fop = fieldPrefixOp
-	case fieldSuffixOpTag:
-		fop = fieldSuffixOp
-	case fieldRegexOpTag:
-		fop = fieldRegexOp
+	fop, err := parseFieldOpType(op)
+	if err != nil {
+		return nil, err
+	}
+	if fop == fieldRegexOp {
 		reValues = make([]*regexp.Regexp, 0, len(values))
 		for _, v := range values {
 			re, err := regexp.Compile(string(v))
@@ -235,8 +292,6 @@ func NewFieldOpNode(op string, field string, caseSensitive bool, values [][]byte
 			}
 			reValues = append(reValues, re)
 		}
-	default:
-		return nil, fmt.Errorf("unknown field op %q", op)
 	}
 
 	if fop != fieldRegexOp {
@@ -288,6 +343,10 @@ func (n *fieldOpNode) Type() NodeType {
 }
 
 func (n *fieldOpNode) Check(eventRoot *insaneJSON.Root) bool {
+	if n.valuesFile != nil {
+		return n.valuesFile.check(eventRoot)
+	}
+
 	var data []byte
 	node := eventRoot.Dig(n.fieldPath...)
 	if !node.IsNull() {
@@ -415,5 +474,9 @@ func (n *fieldOpNode) isEqualTo(n2 Node, _ int) error {
 	if n.maxValLen != n2f.maxValLen {
 		return fmt.Errorf("nodes have different maxValLem expected: %d", n.maxValLen)
 	}
+	if (n.valuesFile == nil) != (n2f.valuesFile == nil) ||
+		n.valuesFile != nil && n.valuesFile.path != n2f.valuesFile.path {
+		return errors.New("nodes have different values files")
+	}
 	return nil
 }
@@ -0,0 +1,107 @@
+package doif
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/ozontech/file.d/cfg"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+/*{ do-if-num-cmp-op-node
+DoIf numeric comparison op node is considered to always be a leaf in the DoIf tree like DoIf field op node.
+It contains operation that compares the numeric field value with certain value.
+The numbers and the strings with the numbers are compared, the other values don't match.
+
+Params:
+  - `op` - must be `num_cmp`. Required.
+  - `field` - name of the field to apply operation. Required.
+  - `cmp_op` - comparison operation name, the same as in the length comparison op node. Required.
+  - `value` - number to compare the field value with. Required.
+
+Example:
+```yaml
+pipelines:
+  test:
+    actions:
+      - type: discard
+        do_if:
+          op: num_cmp
+          field: status
+          cmp_op: lt
+          value: 500
+```
+
+Result:
+```
+{"status":200}    # discarded
+{"status":"404"}  # discarded
+{"status":500}    # not discarded
+{"status":"abc"}  # not discarded ('status' is not a number)
+{"code":200}      # not discarded ('status' not found)
+```
+}*/
+
+const (
+	numCmpOpTag = "num_cmp"
+)
+
+type numCmpOpNode struct {
+	fieldPath []string
+	cmpOp     comparisonOperation
+	cmpValue  float64
+}
+
+func NewNumCmpOpNode(field string, cmpOp string, cmpValue float64) (Node, error) {
+	typedCmpOp, err := newComparisonOperation(cmpOp)
+	if err != nil {
+		return nil, fmt.Errorf("init num cmp op node: %w", err)
+	}
+
+	return &numCmpOpNode{
+		fieldPath: cfg.ParseFieldSelector(field),
+		cmpOp:     typedCmpOp,
+		cmpValue:  cmpValue,
+	}, nil
+}
+
+func (n *numCmpOpNode) Type() NodeType {
+	return NodeNumCmpOp
+}
+
+func (n *numCmpOpNode) Check(eventRoot *insaneJSON.Root) bool {
+	node := eventRoot.Dig(n.fieldPath...)
+	if node == nil || !(node.IsNumber() || node.IsString()) {
+		return false
+	}
+
+	value, err := strconv.ParseFloat(node.AsString(), 64)
+	if err != nil {
+		return false
+	}
+
+	return compareValues(n.cmpOp, value, n.cmpValue)
+}
+
+func (n *numCmpOpNode) isEqualTo(n2 Node, _ int) error {
+	n2Explicit, ok := n2.(*numCmpOpNode)
+	if !ok {
+		return errors.New("nodes have different types; expected: numCmpOpNode")
+	}
+
+	if n.cmpOp != n2Explicit.cmpOp {
+		return fmt.Errorf("unequal cmp operations: %s != %s", n.cmpOp, n2Explicit.cmpOp)
+	}
+
+	if n.cmpValue != n2Explicit.cmpValue {
+		return fmt.Errorf("unequal cmp values: %v != %v", n.cmpValue, n2Explicit.cmpValue)
+	}
+
+	if slices.Compare(n.fieldPath, n2Explicit.fieldPath) != 0 {
+		return fmt.Errorf("nodes have different fieldPathStr; expected: fieldPath=%v", n.fieldPath)
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package doif
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ozontech/file.d/cfg"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+/*{ do-if-ts-age-cmp-op-node
+DoIf timestamp age comparison op node is considered to always be a leaf in the DoIf tree like DoIf field op node.
+It contains operation that compares the age of the timestamp in the field, i.e. the time passed since the timestamp,
+with certain duration. The values which can't be parsed with the format don't match.
+
+Params:
+  - `op` - must be `ts_age_cmp`. Required.
+  - `field` - name of the field with the timestamp. Required.
+  - `format` - format of the timestamp. It's either the Go time layout or one of the format names
+    which the `convert_date` plugin accepts. The `unixtime` format means the number of seconds, possibly fractional.
+    Default `rfc3339nano`.
+  - `cmp_op` - comparison operation name, the same as in the length comparison op node. Required.
+  - `value` - duration to compare the age with, e.g. `1h` or `30s`. Required.
+
+Example (discard the events older than 1 hour):
+```yaml
+pipelines:
+  test:
+    actions:
+      - type: discard
+        do_if:
+          op: ts_age_cmp
+          field: time
+          format: rfc3339
+          cmp_op: gt
+          value: 1h
+```
+
+Result at 2024-01-01T12:00:00Z:
+```
+{"time":"2024-01-01T10:00:00Z"}  # discarded
+{"time":"2024-01-01T11:30:00Z"}  # not discarded
+{"time":"yesterday"}             # not discarded ('time' can't be parsed)
+```
+}*/
+
+const (
+	tsAgeCmpOpTag = "ts_age_cmp"
+
+	// unixTimeFormat is the same as pipeline.UnixTime, the package can't depend on the pipeline
+	unixTimeFormat = "unixtime"
+)
+
+type tsAgeCmpOpNode struct {
+	fieldPath []string
+	format    string
+	cmpOp     comparisonOperation
+	cmpValue  time.Duration
+
+	nowFn func() time.Time
+}
+
+// NewTsAgeCmpOpNode creates the node, the format is the Go time layout or "unixtime".
+func NewTsAgeCmpOpNode(field string, format string, cmpOp string, cmpValue time.Duration) (Node, error) {
+	if format == "" {
+		return nil, errors.New("init ts age cmp op node: empty format")
+	}
+
+	typedCmpOp, err := newComparisonOperation(cmpOp)
+	if err != nil {
+		return nil, fmt.Errorf("init ts age cmp op node: %w", err)
+	}
+
+	return &tsAgeCmpOpNode{
+		fieldPath: cfg.ParseFieldSelector(field),
+		format:    format,
+		cmpOp:     typedCmpOp,
+		cmpValue:  cmpValue,
+		nowFn:     time.Now,
+	}, nil
+}
+
+func (n *tsAgeCmpOpNode) Type() NodeType {
+	return NodeTsAgeCmpOp
+}
+
+func (n *tsAgeCmpOpNode) Check(eventRoot *insaneJSON.Root) bool {
+	node := eventRoot.Dig(n.fieldPath...)
+	if node == nil || !(node.IsNumber() || node.IsString()) {
+		return false
+	}
+
+	ts, ok := n.parse(node.AsString())
+	if !ok {
+		return false
+	}
+
+	return compareValues(n.cmpOp, n.nowFn().Sub(ts), n.cmpValue)
+}
+
+func (n *tsAgeCmpOpNode) parse(value string) (time.Time, bool) {
+	if n.format != unixTimeFormat {
+		ts, err := time.Parse(n.format, value)
+		return ts, err == nil
+	}
+
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return time.Time{}, false
+	}
+	sec, frac := math.Modf(seconds)
+	return time.Unix(int64(sec), int64(frac*float64(time.Second))), true
+}
+
+func (n *tsAgeCmpOpNode) isEqualTo(n2 Node, _ int) error {
+	n2Explicit, ok := n2.(*tsAgeCmpOpNode)
+	if !ok {
+		return errors.New("nodes have different types; expected: tsAgeCmpOpNode")
+	}
+
+	if n.format != n2Explicit.format {
+		return fmt.Errorf("unequal formats: %q != %q", n.format, n2Explicit.format)
+	}
+
+	if n.cmpOp != n2Explicit.cmpOp {
+		return fmt.Errorf("unequal cmp operations: %s != %s", n.cmpOp, n2Explicit.cmpOp)
+	}
+
+	if n.cmpValue != n2Explicit.cmpValue {
+		return fmt.Errorf("unequal cmp values: %s != %s", n.cmpValue, n2Explicit.cmpValue)
+	}
+
+	if slices.Compare(n.fieldPath, n2Explicit.fieldPath) != 0 {
+		return fmt.Errorf("nodes have different fieldPathStr; expected: fieldPath=%v", n.fieldPath)
+	}
+
+	return nil
+}
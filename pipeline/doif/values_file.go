@@ -0,0 +1,127 @@
+package doif
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/ozontech/file.d/logger"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+// valuesFileCheckInterval is how often the values file is checked for changes.
+var valuesFileCheckInterval = 10 * time.Second
+
+// valuesFile holds the field op node built from the values in the file.
+// The file is checked for changes lazily by the node checks, so the idle nodes don't need a goroutine
+// and are collected with the pipeline. The rebuilt node is swapped atomically.
+type valuesFile struct {
+	path          string
+	op            string
+	field         string
+	caseSensitive bool
+	values        [][]byte
+
+	// node is nil if there are no values, such node matches nothing
+	node      atomic.Pointer[fieldOpNode]
+	modTime   time.Time
+	size      int64
+	nextCheck atomic.Int64
+	reloading atomic.Bool
+}
+
+func newValuesFile(path, op, field string, caseSensitive bool, values [][]byte) (*valuesFile, error) {
+	f := &valuesFile{
+		path:          path,
+		op:            op,
+		field:         field,
+		caseSensitive: caseSensitive,
+		values:        values,
+	}
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+	f.nextCheck.Store(time.Now().Add(valuesFileCheckInterval).UnixNano())
+
+	return f, nil
+}
+
+func (f *valuesFile) check(eventRoot *insaneJSON.Root) bool {
+	now := time.Now()
+	if now.UnixNano() >= f.nextCheck.Load() && f.reloading.CompareAndSwap(false, true) {
+		f.nextCheck.Store(now.Add(valuesFileCheckInterval).UnixNano())
+		go f.reload()
+	}
+
+	node := f.node.Load()
+	if node == nil {
+		return false
+	}
+	return node.Check(eventRoot)
+}
+
+func (f *valuesFile) reload() {
+	defer f.reloading.Store(false)
+
+	stat, err := os.Stat(f.path)
+	if err != nil {
+		logger.Errorf("can't stat do_if values file %q: %s", f.path, err.Error())
+		return
+	}
+	if stat.ModTime().Equal(f.modTime) && stat.Size() == f.size {
+		return
+	}
+
+	// keep checking the previous values if the new ones are broken
+	if err := f.load(); err != nil {
+		logger.Errorf("can't reload do_if values file %q: %s", f.path, err.Error())
+		return
+	}
+	logger.Infof("do_if values file %q is reloaded", f.path)
+}
+
+func (f *valuesFile) load() error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	values := make([][]byte, 0, len(f.values))
+	values = append(values, f.values...)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := bytes.TrimRight(scanner.Bytes(), "\r")
+		if len(line) == 0 {
+			continue
+		}
+		values = append(values, bytes.Clone(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read values file %q: %w", f.path, err)
+	}
+
+	var node *fieldOpNode
+	if len(values) > 0 {
+		n, err := newFieldOpNode(f.op, f.field, f.caseSensitive, values)
+		if err != nil {
+			return fmt.Errorf("values file %q: %w", f.path, err)
+		}
+		node = n
+	}
+
+	f.node.Store(node)
+	f.modTime = stat.ModTime()
+	f.size = stat.Size()
+
+	return nil
+}
@@ -145,6 +145,7 @@ type Settings struct {
 // New creates new pipeline. Consider using `SetupHTTPHandlers` next.
 func New(name string, settings *Settings, registry *prometheus.Registry) *Pipeline {
 	metricCtl := metric.NewCtl("pipeline_"+name, registry)
+	metricHolder := metric.NewHolder(settings.MetricHoldDuration)
 
 	lg := logger.Instance.Named(name).Desugar()
 
@@ -158,12 +159,13 @@ func New(name string, settings *Settings, registry *prometheus.Registry) *Pipeli
 			PipelineName:     name,
 			PipelineSettings: settings,
 			MetricCtl:        metricCtl,
+			MetricHolder:     metricHolder,
 		},
 		actionMetrics: actionMetrics{
 			m:  make(map[string]*actionMetric),
 			mu: new(sync.RWMutex),
 		},
-		metricHolder: metric.NewHolder(settings.MetricHoldDuration),
+		metricHolder: metricHolder,
 		streamer:     newStreamer(settings.EventTimeout),
 		eventPool:    newEventPool(settings.Capacity, settings.AvgEventSize),
 		antispamer: antispam.NewAntispammer(antispam.Options{
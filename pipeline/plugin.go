@@ -47,12 +47,16 @@ type PluginDefaultParams struct {
 	PipelineName     string
 	PipelineSettings *Settings
 	MetricCtl        *metric.Ctl
+	// MetricHolder deletes the labels of the held metrics which aren't in use for a long time
+	MetricHolder *metric.Holder
 }
 
 type ActionPluginParams struct {
 	PluginDefaultParams
 	Controller ActionPluginController
 	Logger     *zap.SugaredLogger
+	// Index is the index of the action in the pipeline, it distinguishes the actions of the same type
+	Index int
 }
 
 type OutputPluginParams struct {
@@ -76,6 +80,12 @@ type PluginStaticInfo struct {
 	// Every plugin can provide their own API through Endpoints.
 	Endpoints         map[string]func(http.ResponseWriter, *http.Request)
 	AdditionalActions []string // used only for input plugins, defines actions that should be run right after input plugin with input config
+
+	// FlushOnNotMatched is used only for action plugins which hold the events.
+	// The busy action receives all the events of the stream regardless of its conditions, e.g. join needs them.
+	// If it's set, the busy action gets the timeout event instead of the event which doesn't match the action,
+	// so the action flushes the held events before the event goes further.
+	FlushOnNotMatched bool
 }
 
 type PluginRuntimeInfo struct {
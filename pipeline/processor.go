@@ -105,6 +105,7 @@ func (p *processor) start(params PluginDefaultParams, log *zap.SugaredLogger) {
 			PluginDefaultParams: params,
 			Controller:          p,
 			Logger:              log.Named("action").Named(actionInfo.Type),
+			Index:               i,
 		})
 	}
 
@@ -187,8 +188,14 @@ func (p *processor) doActions(event *Event) (isPassed bool, lastAction int) {
 		event.action = index
 		p.countEvent(event, index, eventStatusReceived)
 
-		if !p.busyActions[index] && !event.IsTimeoutKind() {
+		busy := p.busyActions[index]
+		if (!busy || p.actionInfos[index].FlushOnNotMatched) && !event.IsTimeoutKind() {
 			if !p.isMatch(index, event) {
+				// the busy action holds the previous events of the stream,
+				// so it should flush them before the event goes further
+				if busy && !event.IsChildKind() {
+					p.sendTimeout(event.stream, index)
+				}
 				p.countEvent(event, index, eventStatusNotMatched)
 				continue
 			}
@@ -380,10 +387,17 @@ func (p *processor) AddActionPlugin(info *ActionPluginInfo) {
 }
 
 // Propagate flushes an event after ActionHold.
+// The parent of the spawned events goes to the output right away as after ActionBreak,
+// since the next actions have already got its children and the output doesn't write it.
 func (p *processor) Propagate(event *Event) {
 	event.action++
 	nextActionIdx := event.action
 	p.tryResetBusy(nextActionIdx - 1)
+	if event.IsChildParentKind() {
+		event.stage = eventStageOutput
+		p.output.Out(event)
+		return
+	}
 	p.processSequence(event)
 }
 
@@ -425,12 +439,17 @@ func (p *processor) Spawn(parent *Event, nodes []*insaneJSON.Node) {
 			continue
 		}
 
-		timeout := newTimeoutEvent(parent.stream)
-		timeout.action = i
-		p.doActions(timeout)
+		p.sendTimeout(parent.stream, i)
 	}
 }
 
+// sendTimeout passes the timeout event to the busy action to make it flush the held events.
+func (p *processor) sendTimeout(stream *stream, index int) {
+	timeout := newTimeoutEvent(stream)
+	timeout.action = index
+	p.doActions(timeout)
+}
+
 func (p *processor) RecoverFromPanic() {
 	p.recoverFromPanic()
 }
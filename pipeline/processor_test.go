@@ -3,6 +3,7 @@ package pipeline
 import (
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	insaneJSON "github.com/vitkovskii/insane-json"
@@ -111,3 +112,127 @@ func Test_processor_isMatch(t *testing.T) {
 		})
 	}
 }
+
+// holdAction holds the events and flushes the held one on the timeout.
+type holdAction struct {
+	controller ActionPluginController
+	held       *Event
+	received   []*Event
+}
+
+func (a *holdAction) Start(_ AnyConfig, _ *ActionPluginParams) {}
+
+func (a *holdAction) Stop() {}
+
+func (a *holdAction) Do(event *Event) ActionResult {
+	a.received = append(a.received, event)
+	if !event.IsTimeoutKind() {
+		a.held = event
+		return ActionHold
+	}
+	if a.held != nil {
+		held := a.held
+		a.held = nil
+		a.controller.Propagate(held)
+	}
+	return ActionDiscard
+}
+
+type collectOutput struct {
+	events []*Event
+}
+
+func (o *collectOutput) Start(_ AnyConfig, _ *OutputPluginParams) {}
+
+func (o *collectOutput) Stop() {}
+
+func (o *collectOutput) Out(event *Event) {
+	o.events = append(o.events, event)
+}
+
+func newHoldTestProcessor(t *testing.T, flushOnNotMatched bool, actions ...ActionPlugin) (*processor, *collectOutput) {
+	t.Helper()
+
+	output := &collectOutput{}
+	proc := newProcessor(0, nil, nil, output, nil, func(*Event, bool, bool) {}, func() {})
+	for _, action := range actions {
+		if a, ok := action.(*holdAction); ok {
+			a.controller = proc
+		}
+		proc.AddActionPlugin(&ActionPluginInfo{
+			ActionPluginStaticInfo: &ActionPluginStaticInfo{
+				PluginStaticInfo: &PluginStaticInfo{FlushOnNotMatched: flushOnNotMatched},
+				MatchConditions:  MatchConditions{{Field: []string{"hold"}, Values: []string{"true"}}},
+				MatchMode:        MatchModeAnd,
+			},
+			PluginRuntimeInfo: &PluginRuntimeInfo{Plugin: action},
+		})
+	}
+	return proc, output
+}
+
+func newHoldTestEvent(t *testing.T, st *stream, json string) *Event {
+	t.Helper()
+
+	event := newEvent()
+	require.NoError(t, event.parseJSON([]byte(json)))
+	t.Cleanup(func() { insaneJSON.Release(event.Root) })
+	event.stream = st
+	return event
+}
+
+func TestProcessorFlushBusyOnNotMatched(t *testing.T) {
+	action := &holdAction{}
+	proc, output := newHoldTestProcessor(t, true, action)
+	st := newStream("test", 1, newStreamer(time.Second))
+
+	held := newHoldTestEvent(t, st, `{"hold":"true"}`)
+	passed, _ := proc.doActions(held)
+	require.False(t, passed)
+	require.Equal(t, 1, proc.busyActionsTotal)
+
+	// the busy action gets the timeout to flush the held event before the event which doesn't match goes further
+	other := newHoldTestEvent(t, st, `{"hold":"false"}`)
+	passed, _ = proc.doActions(other)
+	require.True(t, passed)
+	require.Equal(t, 0, proc.busyActionsTotal)
+	require.Len(t, action.received, 2)
+	require.True(t, action.received[1].IsTimeoutKind())
+	require.Equal(t, []*Event{held}, output.events)
+}
+
+func TestProcessorBusyGetsNotMatched(t *testing.T) {
+	action := &holdAction{}
+	proc, output := newHoldTestProcessor(t, false, action)
+	st := newStream("test", 1, newStreamer(time.Second))
+
+	held := newHoldTestEvent(t, st, `{"hold":"true"}`)
+	passed, _ := proc.doActions(held)
+	require.False(t, passed)
+
+	// by default the busy action gets all the events of the stream, e.g. join needs it
+	other := newHoldTestEvent(t, st, `{"hold":"false"}`)
+	passed, _ = proc.doActions(other)
+	require.False(t, passed)
+	require.Equal(t, 1, proc.busyActionsTotal)
+	require.Equal(t, []*Event{held, other}, action.received)
+	require.Empty(t, output.events)
+}
+
+func TestProcessorPropagateChildParent(t *testing.T) {
+	action := &holdAction{}
+	next := &holdAction{}
+	proc, output := newHoldTestProcessor(t, true, action, next)
+	st := newStream("test", 1, newStreamer(time.Second))
+
+	held := newHoldTestEvent(t, st, `{"hold":"true"}`)
+	passed, _ := proc.doActions(held)
+	require.False(t, passed)
+
+	// the parent of the spawned events skips the next actions
+	held.SetChildParentKind()
+	proc.Propagate(held)
+	require.Equal(t, 0, proc.busyActionsTotal)
+	require.Empty(t, next.received)
+	require.Equal(t, []*Event{held}, output.events)
+}
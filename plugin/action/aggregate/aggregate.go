@@ -0,0 +1,422 @@
+package aggregate
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ozontech/file.d/cfg"
+	"github.com/ozontech/file.d/fd"
+	"github.com/ozontech/file.d/metric"
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/prometheus/client_golang/prometheus"
+	insaneJSON "github.com/vitkovskii/insane-json"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It rolls up the events into one summary event per group per time window.
+The summary holds the number of events and the sum, min, max and percentiles of the `field` values,
+and the values of the `group_by` fields.
+
+The windows are aligned to the `interval` by the processing time.
+The summaries of the closed window are emitted together with the first event after the window is closed.
+The last aggregated event is held to flush the window if no events come:
+if the plugin gets no events for the pipeline `event_timeout`, the window is closed before its end.
+The original events are committed once they are aggregated, so the summaries of the current window are lost on file.d restart.
+
+The percentiles are estimated by a uniform random sample of `max_samples` values of each group.
+
+**Example of summarizing the access logs:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: aggregate
+      group_by:
+        - service
+        - status
+      field: duration_ms
+      interval: 1m
+      percentiles: [50, 99]
+    ...
+```
+
+The summary event:
+```json
+{
+  "service": "checkout",
+  "status": 200,
+  "count": 15230,
+  "stats": {"sum": 2011843, "min": 3, "max": 1520, "p50": 97, "p99": 811},
+  "window": {"start": "2024-01-01T10:00:00Z", "end": "2024-01-01T10:01:00Z"}
+}
+```
+}*/
+
+const (
+	// keySeparator separates the values of the group fields to avoid collisions like ("ab", "c") and ("a", "bc")
+	keySeparator = 0xff
+)
+
+var defaultPercentiles = []float64{50, 95, 99}
+
+type Plugin struct {
+	config     *Config
+	logger     *zap.Logger
+	controller pipeline.ActionPluginController
+
+	aggregator *aggregator
+	groupBy    [][]string
+
+	percentiles     []float64
+	percentileNames []string
+
+	keyBuf     []byte
+	valueEnds  []int
+	nowFn      func() time.Time
+	timeLayout string
+
+	// held is the event held to flush the window on the timeout, only one event is held per aggregator
+	held        *pipeline.Event
+	idleTimeout time.Duration
+	summaryRoot *insaneJSON.Root
+	buf         []byte
+
+	// plugin metrics
+	groupsOverflowMetric prometheus.Counter
+	invalidValuesMetric  prometheus.Counter
+}
+
+// ! config-params
+// ^ config-params
+type Config struct {
+	// > @3@4@5@6
+	// >
+	// > The event fields to group the events by. The values of the fields are copied into the summary.
+	// > If not set, all the events are aggregated into one summary.
+	GroupBy []cfg.FieldSelector `json:"group_by" slice:"true"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The numeric event field to compute the statistics of.
+	// > If not set, only the events are counted.
+	// > Events which have no field or have the non-numeric value are only counted.
+	// > `NaN` and the infinite values are considered as the non-numeric ones.
+	Field  cfg.FieldSelector `json:"field" parse:"selector"` // *
+	Field_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The length of the window.
+	Interval  cfg.Duration `json:"interval" parse:"duration" default:"1m"` // *
+	Interval_ time.Duration
+
+	// > @3@4@5@6
+	// >
+	// > The percentiles of the `field` values to compute, from `0` to `100`.
+	// > If not set, `[50, 95, 99]` are computed.
+	Percentiles []float64 `json:"percentiles" slice:"true"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The maximum number of the values per group kept to estimate the percentiles.
+	MaxSamples int `json:"max_samples" default:"1000"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The maximum number of groups in the window.
+	// > The events of the new groups above the limit pass the plugin as is.
+	MaxGroups int `json:"max_groups" default:"10000"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The summary field to write the number of the events into.
+	CountField  cfg.FieldSelector `json:"count_field" parse:"selector" default:"count"` // *
+	CountField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The summary field to write the statistics of the `field` values into.
+	StatsField  cfg.FieldSelector `json:"stats_field" parse:"selector" default:"stats"` // *
+	StatsField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The summary field to write the start and the end of the window into.
+	WindowField  cfg.FieldSelector `json:"window_field" parse:"selector" default:"window"` // *
+	WindowField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The format of the window start and end. It's either the Go time layout or one of the format names
+	// > which the `convert_date` plugin accepts. The `unixtime` format writes the number of seconds.
+	WindowFormat string `json:"window_format" default:"rfc3339nano"` // *
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:              "aggregate",
+		Factory:           factory,
+		FlushOnNotMatched: true,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger.Desugar()
+	p.controller = params.Controller
+	p.nowFn = time.Now
+	p.idleTimeout = params.PipelineSettings.EventTimeout
+	p.registerMetrics(params.MetricCtl)
+
+	if p.config.Interval_ <= 0 {
+		p.logger.Fatal("interval must be > 0")
+	}
+	if p.config.MaxSamples <= 0 {
+		p.logger.Fatal("max_samples must be > 0", zap.Int("max_samples", p.config.MaxSamples))
+	}
+	if p.config.MaxGroups <= 0 {
+		p.logger.Fatal("max_groups must be > 0", zap.Int("max_groups", p.config.MaxGroups))
+	}
+
+	format, err := pipeline.ParseFormatName(p.config.WindowFormat)
+	if err != nil {
+		format = p.config.WindowFormat
+	}
+	p.timeLayout = format
+
+	p.percentiles = p.config.Percentiles
+	if len(p.percentiles) == 0 {
+		p.percentiles = defaultPercentiles
+	}
+	for _, percentile := range p.percentiles {
+		if percentile < 0 || percentile > 100 {
+			p.logger.Fatal("percentiles must be in range [0, 100]", zap.Float64("percentile", percentile))
+		}
+		name := "p" + strings.ReplaceAll(strconv.FormatFloat(percentile, 'f', -1, 64), ".", "_")
+		p.percentileNames = append(p.percentileNames, name)
+	}
+
+	for _, field := range p.config.GroupBy {
+		p.groupBy = append(p.groupBy, cfg.ParseFieldSelector(string(field)))
+	}
+	p.valueEnds = make([]int, len(p.groupBy))
+
+	p.summaryRoot = insaneJSON.Spawn()
+	p.aggregator = acquireAggregator(p.config)
+}
+
+func (p *Plugin) registerMetrics(ctl *metric.Ctl) {
+	p.groupsOverflowMetric = ctl.RegisterCounter(
+		"aggregate_groups_overflow_total",
+		"Number of events passed by the aggregate plugin as is because the max_groups limit is reached",
+	)
+	p.invalidValuesMetric = ctl.RegisterCounter(
+		"aggregate_invalid_values_total",
+		"Number of events which field value isn't a finite number, such events are only counted",
+	)
+}
+
+func (p *Plugin) Stop() {
+	if w := releaseAggregator(p.config); w != nil && len(w.groups) > 0 {
+		p.logger.Warn("summaries of the current window are lost",
+			zap.Int("groups", len(w.groups)), zap.Time("window_start", w.start),
+		)
+	}
+	insaneJSON.Release(p.summaryRoot)
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	if event.IsTimeoutKind() {
+		p.flushIdle()
+		return pipeline.ActionDiscard
+	}
+
+	p.appendKey(event.Root)
+	value, hasValue := p.fieldValue(event.Root)
+
+	// spawned events can't be the parents of the summaries, so they don't close the window
+	closed, added := p.aggregator.add(p.nowFn(), !event.IsChildKind(), p.keyBuf, p.groupValues, value, hasValue)
+	if !added {
+		p.groupsOverflowMetric.Inc()
+	}
+
+	if event.IsChildKind() {
+		if !added {
+			return pipeline.ActionPass
+		}
+		return pipeline.ActionDiscard
+	}
+
+	// the held event is earlier in the stream, so it's released first
+	if p.held != nil {
+		p.release(closed)
+		closed = nil
+	}
+
+	// the event which closes the window is always accounted, so it can be the parent of the summaries
+	if closed != nil && len(closed.groups) > 0 {
+		p.controller.Spawn(event, p.makeSummaries(event.Root, closed))
+
+		// the event is committed by the output but isn't written,
+		// because it's the parent of the summaries
+		return pipeline.ActionBreak
+	}
+
+	if !added {
+		return pipeline.ActionPass
+	}
+
+	if p.aggregator.tryHold() {
+		p.held = event
+		return pipeline.ActionHold
+	}
+	return pipeline.ActionDiscard
+}
+
+// flushIdle releases the held event on the timeout.
+// The window is closed before its end if the aggregator gets no events for the idle timeout,
+// otherwise the next held event flushes the window.
+func (p *Plugin) flushIdle() {
+	if p.held == nil {
+		return
+	}
+
+	now := p.nowFn()
+	closed := p.aggregator.rotate(now)
+	if closed == nil {
+		closed = p.aggregator.closeIdle(now, p.idleTimeout)
+	}
+	p.release(closed)
+}
+
+// release passes the held event to the output, the event is the parent of the summaries of the closed window if any.
+// The event is already aggregated, so it's committed, but isn't written.
+func (p *Plugin) release(closed *window) {
+	held := p.held
+	p.held = nil
+	p.aggregator.releaseHold()
+
+	held.SetChildParentKind()
+	if closed != nil && len(closed.groups) > 0 {
+		p.controller.Spawn(held, p.makeSummaries(held.Root, closed))
+	}
+	p.controller.Propagate(held)
+}
+
+func (p *Plugin) appendKey(root *insaneJSON.Root) {
+	p.keyBuf = p.keyBuf[:0]
+	for i, field := range p.groupBy {
+		if node := root.Dig(field...); node != nil {
+			p.keyBuf = node.Encode(p.keyBuf)
+		}
+		p.valueEnds[i] = len(p.keyBuf)
+		p.keyBuf = append(p.keyBuf, keySeparator)
+	}
+}
+
+// groupValues returns the encoded values of the group_by fields from the key buffer.
+func (p *Plugin) groupValues() []string {
+	values := make([]string, len(p.groupBy))
+	start := 0
+	for i, end := range p.valueEnds {
+		values[i] = string(p.keyBuf[start:end])
+		start = end + 1
+	}
+	return values
+}
+
+func (p *Plugin) fieldValue(root *insaneJSON.Root) (float64, bool) {
+	if len(p.config.Field_) == 0 {
+		return 0, false
+	}
+
+	node := root.Dig(p.config.Field_...)
+	if node == nil {
+		return 0, false
+	}
+
+	value, ok := 0.0, false
+	switch {
+	case node.IsNumber():
+		value, ok = node.AsFloat(), true
+	case node.IsString():
+		var err error
+		value, err = strconv.ParseFloat(node.AsString(), 64)
+		ok = err == nil
+	}
+	// NaN and the infinities would spoil the statistics and can't be written to JSON
+	if !ok || math.IsNaN(value) || math.IsInf(value, 0) {
+		p.invalidValuesMetric.Inc()
+		return 0, false
+	}
+	return value, true
+}
+
+func (p *Plugin) makeSummaries(root *insaneJSON.Root, w *window) []*insaneJSON.Node {
+	summaries := make([]*insaneJSON.Node, 0, len(w.groups))
+	for _, g := range w.sortedGroups() {
+		summary, err := p.makeSummary(root, w, g)
+		if err != nil {
+			p.logger.Error("can't make a summary", zap.Error(err))
+			continue
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// makeSummary makes the summary node within the root of the parent event,
+// so the node lives as long as the parent.
+// The summary is built in the separate root first to use the field helpers of the pipeline.
+func (p *Plugin) makeSummary(root *insaneJSON.Root, w *window, g *group) (*insaneJSON.Node, error) {
+	summary := p.summaryRoot
+	if err := summary.DecodeString("{}"); err != nil {
+		return nil, err
+	}
+
+	for i, value := range g.values {
+		if value == "" {
+			continue
+		}
+		pipeline.CreateNestedField(summary, p.groupBy[i]).MutateToJSON(summary, value)
+	}
+
+	pipeline.CreateNestedField(summary, p.config.CountField_).MutateToInt(g.count)
+
+	if g.stats.count > 0 {
+		statsNode := pipeline.CreateNestedField(summary, p.config.StatsField_)
+		statsNode.AddFieldNoAlloc(summary, "sum").MutateToFloat(g.stats.sum)
+		statsNode.AddFieldNoAlloc(summary, "min").MutateToFloat(g.stats.min)
+		statsNode.AddFieldNoAlloc(summary, "max").MutateToFloat(g.stats.max)
+
+		samples := g.stats.samples
+		slices.Sort(samples)
+		for i, q := range p.percentiles {
+			statsNode.AddFieldNoAlloc(summary, p.percentileNames[i]).MutateToFloat(percentile(samples, q))
+		}
+	}
+
+	windowNode := pipeline.CreateNestedField(summary, p.config.WindowField_)
+	p.setTime(windowNode.AddFieldNoAlloc(summary, "start"), w.start)
+	p.setTime(windowNode.AddFieldNoAlloc(summary, "end"), w.end)
+
+	p.buf = summary.Encode(p.buf[:0])
+	return root.DecodeStringAdditional(string(p.buf))
+}
+
+func (p *Plugin) setTime(node *insaneJSON.Node, t time.Time) {
+	if p.timeLayout == pipeline.UnixTime {
+		node.MutateToInt64(t.Unix())
+		return
+	}
+	node.MutateToString(t.Format(p.timeLayout))
+}
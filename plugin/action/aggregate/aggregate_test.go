@@ -0,0 +1,253 @@
+package aggregate
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ozontech/file.d/cfg"
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/test"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+type fakeController struct {
+	spawned    []string
+	parents    []*pipeline.Event
+	propagated []*pipeline.Event
+}
+
+func (c *fakeController) Propagate(event *pipeline.Event) {
+	c.propagated = append(c.propagated, event)
+}
+
+func (c *fakeController) Spawn(parent *pipeline.Event, nodes []*insaneJSON.Node) {
+	c.parents = append(c.parents, parent)
+	for _, node := range nodes {
+		c.spawned = append(c.spawned, node.EncodeToString())
+	}
+}
+
+func (c *fakeController) IncMaxEventSizeExceeded() {}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func startAggregate(t *testing.T, config *Config, clock *fakeClock) (*Plugin, *fakeController) {
+	t.Helper()
+
+	ctl := &fakeController{}
+	params := test.NewEmptyActionPluginParams()
+	params.Controller = ctl
+
+	plugin := &Plugin{}
+	plugin.Start(test.NewConfig(config, nil), params)
+	plugin.nowFn = clock.Now
+	t.Cleanup(plugin.Stop)
+
+	return plugin, ctl
+}
+
+func doAggregate(t *testing.T, plugin *Plugin, e string) (*pipeline.Event, pipeline.ActionResult) {
+	t.Helper()
+
+	root, err := insaneJSON.DecodeString(e)
+	require.NoError(t, err)
+	// the event may be held by the plugin
+	t.Cleanup(func() { insaneJSON.Release(root) })
+
+	event := &pipeline.Event{Root: root}
+	return event, plugin.Do(event)
+}
+
+func doTimeout(plugin *Plugin) pipeline.ActionResult {
+	event := &pipeline.Event{}
+	event.SetTimeoutKind()
+	return plugin.Do(event)
+}
+
+func TestAggregate(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start.Add(10 * time.Second)}
+
+	plugin, ctl := startAggregate(t, &Config{
+		GroupBy:     []cfg.FieldSelector{"service", "request.status"},
+		Field:       "duration_ms",
+		Interval:    "1m",
+		Percentiles: []float64{50, 99.9},
+	}, clock)
+
+	in := []string{
+		`{"service":"checkout","request":{"status":200},"duration_ms":10}`,
+		`{"service":"checkout","request":{"status":200},"duration_ms":"30"}`,
+		`{"service":"checkout","request":{"status":200},"duration_ms":20}`,
+		`{"service":"checkout","request":{"status":500}}`,
+		`{"request":{"status":200},"duration_ms":5}`,
+	}
+	// the last event is held, the previous one is released when the next one comes
+	var held *pipeline.Event
+	for i, e := range in {
+		event, result := doAggregate(t, plugin, e)
+		require.Equal(t, pipeline.ActionHold, result)
+		if i > 0 {
+			require.Same(t, held, ctl.propagated[i-1])
+		}
+		held = event
+	}
+	require.Empty(t, ctl.spawned)
+
+	// the held event is the parent of the summaries of the closed window
+	clock.now = start.Add(time.Minute + time.Second)
+	_, result := doAggregate(t, plugin, `{"service":"search","duration_ms":1}`)
+	require.Equal(t, pipeline.ActionHold, result)
+	require.Equal(t, []*pipeline.Event{held}, ctl.parents)
+	require.True(t, held.IsChildParentKind())
+
+	window := `"window":{"start":"2024-01-01T10:00:00Z","end":"2024-01-01T10:01:00Z"}`
+	require.Equal(t, []string{
+		`{"service":"checkout","request":{"status":200},"count":3,"stats":{"sum":60,"min":10,"max":30,"p50":20,"p99_9":30},` + window + `}`,
+		`{"service":"checkout","request":{"status":500},"count":1,` + window + `}`,
+		`{"request":{"status":200},"count":1,"stats":{"sum":5,"min":5,"max":5,"p50":5,"p99_9":5},` + window + `}`,
+	}, ctl.spawned)
+
+	// the held event flushes the window on the timeout
+	ctl.spawned = nil
+	clock.now = start.Add(3 * time.Minute)
+	require.Equal(t, pipeline.ActionDiscard, doTimeout(plugin))
+	require.Equal(t, []string{
+		`{"service":"search","count":1,"stats":{"sum":1,"min":1,"max":1,"p50":1,"p99_9":1},` +
+			`"window":{"start":"2024-01-01T10:01:00Z","end":"2024-01-01T10:02:00Z"}}`,
+	}, ctl.spawned)
+	require.Nil(t, plugin.held)
+}
+
+func TestAggregateFlushIdle(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start.Add(10 * time.Second)}
+
+	plugin, ctl := startAggregate(t, &Config{
+		Interval:     "1m",
+		WindowFormat: "unixtime",
+	}, clock)
+	plugin.idleTimeout = 30 * time.Second
+
+	_, result := doAggregate(t, plugin, `{}`)
+	require.Equal(t, pipeline.ActionHold, result)
+
+	// the window isn't closed while the aggregator gets the events
+	clock.now = start.Add(20 * time.Second)
+	require.Equal(t, pipeline.ActionDiscard, doTimeout(plugin))
+	require.Empty(t, ctl.spawned)
+	require.Len(t, ctl.propagated, 1)
+
+	_, result = doAggregate(t, plugin, `{}`)
+	require.Equal(t, pipeline.ActionHold, result)
+
+	// the window is closed before its end if no events come
+	clock.now = start.Add(50 * time.Second)
+	require.Equal(t, pipeline.ActionDiscard, doTimeout(plugin))
+	closedAt := clock.now.Unix()
+	require.Equal(t, []string{
+		fmt.Sprintf(`{"count":2,"window":{"start":%d,"end":%d}}`, start.Unix(), closedAt),
+	}, ctl.spawned)
+
+	// the next window starts from the end of the closed one
+	ctl.spawned = nil
+	clock.now = start.Add(55 * time.Second)
+	_, result = doAggregate(t, plugin, `{}`)
+	require.Equal(t, pipeline.ActionHold, result)
+	clock.now = start.Add(2 * time.Minute)
+	require.Equal(t, pipeline.ActionDiscard, doTimeout(plugin))
+	require.Equal(t, []string{
+		fmt.Sprintf(`{"count":1,"window":{"start":%d,"end":%d}}`, closedAt, start.Add(time.Minute).Unix()),
+	}, ctl.spawned)
+}
+
+func TestAggregateMaxGroups(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	plugin, ctl := startAggregate(t, &Config{
+		GroupBy:      []cfg.FieldSelector{"id"},
+		MaxGroups:    2,
+		WindowFormat: "unixtime",
+	}, clock)
+
+	doResult := func(e string) pipeline.ActionResult {
+		_, result := doAggregate(t, plugin, e)
+		return result
+	}
+
+	require.Equal(t, pipeline.ActionHold, doResult(`{"id":1}`))
+	require.Equal(t, pipeline.ActionHold, doResult(`{"id":2}`))
+	// the held event is released before the event passes
+	require.Equal(t, pipeline.ActionPass, doResult(`{"id":3}`))
+	require.Len(t, ctl.propagated, 2)
+	require.Equal(t, pipeline.ActionHold, doResult(`{"id":1}`))
+
+	// the event which closes the window is accounted in the new one even if the closed window is full
+	clock.now = clock.now.Add(2 * time.Minute)
+	require.Equal(t, pipeline.ActionHold, doResult(`{"id":3}`))
+	require.Len(t, ctl.spawned, 2)
+}
+
+func TestAggregateInvalidValues(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+
+	plugin, ctl := startAggregate(t, &Config{
+		Field:       "v",
+		Percentiles: []float64{50},
+	}, clock)
+
+	for _, e := range []string{
+		`{"v":2}`,
+		`{"v":"NaN"}`,
+		`{"v":"-Inf"}`,
+		`{"v":1e400}`,
+		`{"v":"wrong"}`,
+		`{"v":{"a":1}}`,
+		`{}`,
+	} {
+		doAggregate(t, plugin, e)
+	}
+	require.Equal(t, 5.0, testutil.ToFloat64(plugin.invalidValuesMetric))
+
+	clock.now = start.Add(2 * time.Minute)
+	require.Equal(t, pipeline.ActionDiscard, doTimeout(plugin))
+	require.Equal(t, []string{
+		`{"count":7,"stats":{"sum":2,"min":2,"max":2,"p50":2},` +
+			`"window":{"start":"2024-01-01T10:00:00Z","end":"2024-01-01T10:01:00Z"}}`,
+	}, ctl.spawned)
+}
+
+func TestPercentileSampling(t *testing.T) {
+	a := &aggregator{
+		interval:   time.Minute,
+		maxGroups:  1,
+		maxSamples: 100,
+		rnd:        rand.New(rand.NewSource(1)),
+	}
+
+	const total = 10000
+	s := &stats{}
+	for i := 1; i <= total; i++ {
+		a.observe(s, float64(i))
+	}
+
+	require.Equal(t, total, s.count)
+	require.Len(t, s.samples, 100)
+	require.Equal(t, 1.0, s.min)
+	require.Equal(t, float64(total), s.max)
+	require.Equal(t, float64(total*(total+1)/2), s.sum)
+
+	slices.Sort(s.samples)
+	require.InDelta(t, total/2, percentile(s.samples, 50), total/5)
+}
@@ -0,0 +1,248 @@
+package aggregate
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	// aggregators should be shared across the processors, so let's have a map by the action config
+	aggregators   = map[*Config]*aggregator{}
+	aggregatorsMu = &sync.Mutex{}
+)
+
+// aggregator keeps the groups of the current window.
+type aggregator struct {
+	interval   time.Duration
+	maxGroups  int
+	maxSamples int
+
+	mu     sync.Mutex
+	rnd    *rand.Rand
+	window *window
+	// closedUntil is the end of the window closed before its end, the next window starts from it
+	closedUntil time.Time
+	lastAdd     time.Time
+	// held is true if one of the plugin instances holds the event to flush the window
+	held bool
+
+	refs int
+}
+
+type window struct {
+	start  time.Time
+	end    time.Time
+	groups map[string]*group
+}
+
+type group struct {
+	// values are the encoded values of the group_by fields, empty if the event has no field
+	values []string
+
+	count int
+	stats stats
+}
+
+type stats struct {
+	count int
+	sum   float64
+	min   float64
+	max   float64
+
+	// samples is the uniform random sample of the values to estimate the percentiles
+	samples []float64
+}
+
+func acquireAggregator(config *Config) *aggregator {
+	aggregatorsMu.Lock()
+	defer aggregatorsMu.Unlock()
+
+	if a, has := aggregators[config]; has {
+		a.refs++
+		return a
+	}
+
+	a := &aggregator{
+		interval:   config.Interval_,
+		maxGroups:  config.MaxGroups,
+		maxSamples: config.MaxSamples,
+		rnd:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		refs:       1,
+	}
+	aggregators[config] = a
+	return a
+}
+
+// releaseAggregator returns the current window if the aggregator isn't used anymore.
+func releaseAggregator(config *Config) *window {
+	aggregatorsMu.Lock()
+	defer aggregatorsMu.Unlock()
+
+	a, has := aggregators[config]
+	if !has {
+		return nil
+	}
+	a.refs--
+	if a.refs > 0 {
+		return nil
+	}
+	delete(aggregators, config)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.window
+}
+
+// rotate starts a new window if the current one is over and returns the closed window.
+func (a *aggregator) rotate(now time.Time) *window {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.rotateLocked(now)
+}
+
+func (a *aggregator) rotateLocked(now time.Time) *window {
+	if a.window == nil {
+		a.window = a.newWindow(now)
+		return nil
+	}
+	if now.Before(a.window.end) {
+		return nil
+	}
+
+	closed := a.window
+	a.window = a.newWindow(now)
+	return closed
+}
+
+// closeIdle closes the current window before its end if there are no events for the idle timeout.
+func (a *aggregator) closeIdle(now time.Time, idle time.Duration) *window {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.window == nil || now.Sub(a.lastAdd) < idle {
+		return nil
+	}
+
+	closed := a.window
+	closed.end = now
+	a.window = nil
+	a.closedUntil = now
+	return closed
+}
+
+func (a *aggregator) newWindow(now time.Time) *window {
+	start := now.Truncate(a.interval)
+	end := start.Add(a.interval)
+	if start.Before(a.closedUntil) {
+		start = a.closedUntil
+	}
+	return &window{
+		start:  start,
+		end:    end,
+		groups: make(map[string]*group),
+	}
+}
+
+func (a *aggregator) tryHold() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.held {
+		return false
+	}
+	a.held = true
+	return true
+}
+
+func (a *aggregator) releaseHold() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.held = false
+}
+
+// add accounts the event in the group of the current window, the window is rotated first if rotate is true.
+// It returns the closed window if any and false if the group limit is reached and the event can't be accounted.
+// The rotation and the accounting are done under the same lock,
+// so the event which closes the window is always accounted in the new one.
+func (a *aggregator) add(now time.Time, rotate bool, key []byte, newValues func() []string, value float64, hasValue bool) (*window, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var closed *window
+	if rotate {
+		closed = a.rotateLocked(now)
+	}
+
+	a.lastAdd = now
+	if a.window == nil {
+		a.window = a.newWindow(now)
+	}
+
+	g, has := a.window.groups[string(key)]
+	if !has {
+		if len(a.window.groups) >= a.maxGroups {
+			return closed, false
+		}
+		g = &group{values: newValues()}
+		a.window.groups[string(key)] = g
+	}
+
+	g.count++
+	if hasValue {
+		a.observe(&g.stats, value)
+	}
+
+	return closed, true
+}
+
+func (a *aggregator) observe(s *stats, value float64) {
+	s.count++
+	s.sum += value
+	if s.count == 1 || value < s.min {
+		s.min = value
+	}
+	if s.count == 1 || value > s.max {
+		s.max = value
+	}
+
+	// reservoir sampling keeps each value with the same probability
+	if len(s.samples) < a.maxSamples {
+		s.samples = append(s.samples, value)
+		return
+	}
+	if i := a.rnd.Intn(s.count); i < a.maxSamples {
+		s.samples[i] = value
+	}
+}
+
+// sortedGroups returns the groups in the order of their keys to have the stable order of the summaries.
+func (w *window) sortedGroups() []*group {
+	keys := make([]string, 0, len(w.groups))
+	for key := range w.groups {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	groups := make([]*group, 0, len(keys))
+	for _, key := range keys {
+		groups = append(groups, w.groups[key])
+	}
+	return groups
+}
+
+// percentile returns the nearest-rank percentile of the sorted samples.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,374 @@
+package convert
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ozontech/file.d/cfg"
+	"github.com/ozontech/file.d/fd"
+	"github.com/ozontech/file.d/metric"
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/prometheus/client_golang/prometheus"
+	insaneJSON "github.com/vitkovskii/insane-json"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It converts the types of the event fields. It's useful after the plugins which produce only strings,
+e.g. `parse_re2` or `join_template`, when the output requires the typed values.
+
+The supported types:
+* `int` – the integer numbers, the strings with the integer numbers and the floats without the fractional part.
+`true` and `false` are converted to `1` and `0`.
+* `float` – the numbers and the strings with the numbers. `NaN` and the infinities aren't valid JSON numbers, so they can't be converted.
+* `bool` – `true`, `false`, `1`, `0` and the strings with them, the case is ignored.
+* `string` – any value, the objects and the arrays are converted to JSON strings.
+* `duration` – Go durations like `1.5s` or `250ms` converted to the number of milliseconds. The numbers are considered as milliseconds.
+* `bytes` – sizes like `10KB`, `1.5 MiB` or `512` converted to the number of bytes.
+`KB`, `MB`, `GB` and `TB` are powers of 1000, `KiB`, `MiB`, `GiB`, `TiB` and the short `K`, `M`, `G`, `T` are powers of 1024.
+
+If the field can't be converted, the `on_failure` policy of the field is applied
+and the `convert_errors_total` metric is increased. The absent fields are skipped.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: parse_re2
+      field: message
+      re2: "^(?P<status>\\d+) (?P<took>\\S+) (?P<size>\\S+)$"
+    - type: convert
+      fields:
+        - field: status
+          type: int
+          on_failure: remove
+        - field: took
+          type: duration
+          on_failure: default
+          default: "0"
+        - field: size
+          type: bytes
+    ...
+```
+}*/
+
+const (
+	typeInt      = "int"
+	typeFloat    = "float"
+	typeBool     = "bool"
+	typeString   = "string"
+	typeDuration = "duration"
+	typeBytes    = "bytes"
+
+	onFailureKeep    = "keep"
+	onFailureRemove  = "remove"
+	onFailureDefault = "default"
+)
+
+type Plugin struct {
+	config *Config
+	logger *zap.Logger
+
+	fields []*field
+	buf    []byte
+
+	// plugin metrics
+	errorsMetric *prometheus.CounterVec
+}
+
+type field struct {
+	path      []string
+	name      string
+	kind      string
+	onFailure string
+
+	// defaultValue is the converted default, it's set if on_failure is default
+	defaultValue *value
+}
+
+// value is the result of the conversion.
+type value struct {
+	kind string
+	i    int64
+	f    float64
+	b    bool
+	s    string
+}
+
+// ! config-params
+// ^ config-params
+type Config struct {
+	// > @3@4@5@6
+	// >
+	// > The fields to convert. It's a list of objects with the following fields:
+	// > * `field` – the event field selector.
+	// > * `type` – one of `int`, `float`, `bool`, `string`, `duration` or `bytes`.
+	// > * `on_failure` – what to do if the value can't be converted:
+	// > `keep` the value as is (default), `remove` the field or set the `default`.
+	// > * `default` – the value to set on failure, it's converted to the `type` on start.
+	Fields []FieldConfig `json:"fields" slice:"true" required:"true"` // *
+}
+
+type FieldConfig struct {
+	Field     cfg.FieldSelector `json:"field"`
+	Type      string            `json:"type"`
+	OnFailure string            `json:"on_failure"`
+	Default   string            `json:"default"`
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "convert",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger.Desugar()
+	p.registerMetrics(params.MetricCtl)
+
+	if len(p.config.Fields) == 0 {
+		p.logger.Fatal("no fields are set")
+	}
+
+	for _, fc := range p.config.Fields {
+		f, err := newField(fc)
+		if err != nil {
+			p.logger.Fatal("wrong field config", zap.String("field", string(fc.Field)), zap.Error(err))
+		}
+		p.fields = append(p.fields, f)
+	}
+}
+
+func newField(fc FieldConfig) (*field, error) {
+	if fc.Field == "" {
+		return nil, fmt.Errorf("field isn't set")
+	}
+
+	f := &field{
+		path:      cfg.ParseFieldSelector(string(fc.Field)),
+		name:      string(fc.Field),
+		kind:      fc.Type,
+		onFailure: fc.OnFailure,
+	}
+
+	switch f.kind {
+	case typeInt, typeFloat, typeBool, typeString, typeDuration, typeBytes:
+	default:
+		return nil, fmt.Errorf("unknown type %q", f.kind)
+	}
+
+	switch f.onFailure {
+	case "":
+		f.onFailure = onFailureKeep
+	case onFailureKeep, onFailureRemove:
+	case onFailureDefault:
+		v, ok := convertString(f.kind, fc.Default)
+		if !ok {
+			return nil, fmt.Errorf("can't convert default %q to %s", fc.Default, f.kind)
+		}
+		f.defaultValue = &v
+	default:
+		return nil, fmt.Errorf("unknown on_failure policy %q", f.onFailure)
+	}
+
+	return f, nil
+}
+
+func (p *Plugin) registerMetrics(ctl *metric.Ctl) {
+	p.errorsMetric = ctl.RegisterCounterVec(
+		"convert_errors_total",
+		"Number of values which the convert plugin can't convert",
+		"field", "type",
+	)
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	for _, f := range p.fields {
+		node := event.Root.Dig(f.path...)
+		if node == nil {
+			continue
+		}
+
+		v, ok := p.convert(f.kind, node)
+		if ok {
+			v.set(node)
+			continue
+		}
+
+		p.errorsMetric.WithLabelValues(f.name, f.kind).Inc()
+		switch f.onFailure {
+		case onFailureRemove:
+			node.Suicide()
+		case onFailureDefault:
+			f.defaultValue.set(node)
+		}
+	}
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) convert(kind string, node *insaneJSON.Node) (value, bool) {
+	switch {
+	case node.IsString():
+		return convertString(kind, node.AsString())
+	case node.IsNumber():
+		return convertNumber(kind, node.AsString())
+	case node.IsTrue(), node.IsFalse():
+		return convertBool(kind, node.IsTrue())
+	case kind == typeString && (node.IsObject() || node.IsArray()):
+		p.buf = node.Encode(p.buf[:0])
+		return value{kind: typeString, s: string(p.buf)}, true
+	default:
+		// null can't be converted to anything
+		return value{}, false
+	}
+}
+
+func convertString(kind, s string) (value, bool) {
+	switch kind {
+	case typeString:
+		return value{kind: typeString, s: s}, true
+	case typeInt, typeFloat:
+		return convertNumber(kind, strings.TrimSpace(s))
+	case typeBool:
+		b, err := strconv.ParseBool(strings.ToLower(strings.TrimSpace(s)))
+		return value{kind: typeBool, b: b}, err == nil
+	case typeDuration:
+		s = strings.TrimSpace(s)
+		if d, err := time.ParseDuration(s); err == nil {
+			return value{kind: typeFloat, f: float64(d) / float64(time.Millisecond)}, true
+		}
+		// numbers are considered as milliseconds
+		return convertNumber(typeFloat, s)
+	case typeBytes:
+		size, ok := parseBytes(s)
+		return value{kind: typeInt, i: size}, ok
+	}
+	return value{}, false
+}
+
+func convertNumber(kind, s string) (value, bool) {
+	switch kind {
+	case typeString:
+		return value{kind: typeString, s: s}, true
+	case typeInt:
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return value{kind: typeInt, i: i}, true
+		}
+		f, ok := parseFloat(s)
+		if !ok || f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return value{}, false
+		}
+		return value{kind: typeInt, i: int64(f)}, true
+	case typeFloat, typeDuration:
+		f, ok := parseFloat(s)
+		return value{kind: typeFloat, f: f}, ok
+	case typeBytes:
+		f, ok := parseFloat(s)
+		if !ok || f < 0 || f >= math.MaxInt64 {
+			return value{}, false
+		}
+		return value{kind: typeInt, i: int64(f)}, true
+	case typeBool:
+		switch s {
+		case "0":
+			return value{kind: typeBool, b: false}, true
+		case "1":
+			return value{kind: typeBool, b: true}, true
+		}
+	}
+	return value{}, false
+}
+
+// parseFloat parses only the finite numbers, since NaN and infinity aren't valid JSON numbers.
+func parseFloat(s string) (float64, bool) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
+func convertBool(kind string, b bool) (value, bool) {
+	switch kind {
+	case typeBool:
+		return value{kind: typeBool, b: b}, true
+	case typeString:
+		return value{kind: typeString, s: strconv.FormatBool(b)}, true
+	case typeInt:
+		if b {
+			return value{kind: typeInt, i: 1}, true
+		}
+		return value{kind: typeInt, i: 0}, true
+	}
+	return value{}, false
+}
+
+var byteUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"k":   1 << 10,
+	"m":   1 << 20,
+	"g":   1 << 30,
+	"t":   1 << 40,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+// parseBytes parses the size with the optional unit, e.g. "1.5 MiB".
+func parseBytes(s string) (int64, bool) {
+	s = strings.TrimSpace(s)
+
+	i := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+		i++
+	}
+	number, ok := parseFloat(s[:i])
+	if !ok {
+		return 0, false
+	}
+
+	unit, ok := byteUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, false
+	}
+
+	size := math.Round(number * unit)
+	if size >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(size), true
+}
+
+func (v *value) set(node *insaneJSON.Node) {
+	switch v.kind {
+	case typeInt:
+		node.MutateToInt64(v.i)
+	case typeFloat:
+		node.MutateToFloat(v.f)
+	case typeBool:
+		node.MutateToBool(v.b)
+	case typeString:
+		node.MutateToString(v.s)
+	}
+}
@@ -0,0 +1,186 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/test"
+	"github.com/stretchr/testify/require"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+func TestConvert(t *testing.T) {
+	suits := []struct {
+		name     string
+		field    FieldConfig
+		input    string
+		expected string
+	}{
+		{
+			name:     "int from string",
+			field:    FieldConfig{Field: "v", Type: "int"},
+			input:    `{"v":" 42 "}`,
+			expected: `{"v":42}`,
+		},
+		{
+			name:     "int from float",
+			field:    FieldConfig{Field: "v", Type: "int"},
+			input:    `{"v":"1e3"}`,
+			expected: `{"v":1000}`,
+		},
+		{
+			name:     "int from bool",
+			field:    FieldConfig{Field: "v", Type: "int"},
+			input:    `{"v":true}`,
+			expected: `{"v":1}`,
+		},
+		{
+			name:     "float",
+			field:    FieldConfig{Field: "a.b", Type: "float"},
+			input:    `{"a":{"b":"1.25"}}`,
+			expected: `{"a":{"b":1.25}}`,
+		},
+		{
+			name:     "bool",
+			field:    FieldConfig{Field: "v", Type: "bool"},
+			input:    `{"v":"TRUE"}`,
+			expected: `{"v":true}`,
+		},
+		{
+			name:     "bool from number",
+			field:    FieldConfig{Field: "v", Type: "bool"},
+			input:    `{"v":0}`,
+			expected: `{"v":false}`,
+		},
+		{
+			name:     "string from number",
+			field:    FieldConfig{Field: "v", Type: "string"},
+			input:    `{"v":12.5}`,
+			expected: `{"v":"12.5"}`,
+		},
+		{
+			name:     "string from object",
+			field:    FieldConfig{Field: "v", Type: "string"},
+			input:    `{"v":{"a":[1,"2"]}}`,
+			expected: `{"v":"{\"a\":[1,\"2\"]}"}`,
+		},
+		{
+			name:     "duration",
+			field:    FieldConfig{Field: "v", Type: "duration"},
+			input:    `{"v":"1m1.5s"}`,
+			expected: `{"v":61500}`,
+		},
+		{
+			name:     "duration in ms",
+			field:    FieldConfig{Field: "v", Type: "duration"},
+			input:    `{"v":"250"}`,
+			expected: `{"v":250}`,
+		},
+		{
+			name:     "bytes",
+			field:    FieldConfig{Field: "v", Type: "bytes"},
+			input:    `{"v":"1.5 MiB"}`,
+			expected: `{"v":1572864}`,
+		},
+		{
+			name:     "absent field",
+			field:    FieldConfig{Field: "v", Type: "int", OnFailure: "remove"},
+			input:    `{"a":"1"}`,
+			expected: `{"a":"1"}`,
+		},
+		{
+			name:     "keep on failure",
+			field:    FieldConfig{Field: "v", Type: "int"},
+			input:    `{"v":"1.5"}`,
+			expected: `{"v":"1.5"}`,
+		},
+		{
+			name:     "remove on failure",
+			field:    FieldConfig{Field: "v", Type: "bool", OnFailure: "remove"},
+			input:    `{"v":"yes","a":1}`,
+			expected: `{"a":1}`,
+		},
+		{
+			name:     "float from NaN",
+			field:    FieldConfig{Field: "v", Type: "float"},
+			input:    `{"v":"NaN"}`,
+			expected: `{"v":"NaN"}`,
+		},
+		{
+			name:     "float from infinity",
+			field:    FieldConfig{Field: "v", Type: "float", OnFailure: "remove"},
+			input:    `{"v":"-infinity","a":1}`,
+			expected: `{"a":1}`,
+		},
+		{
+			name:     "float out of range",
+			field:    FieldConfig{Field: "v", Type: "float", OnFailure: "default", Default: "0"},
+			input:    `{"v":"1e400"}`,
+			expected: `{"v":0}`,
+		},
+		{
+			name:     "duration from infinity",
+			field:    FieldConfig{Field: "v", Type: "duration", OnFailure: "remove"},
+			input:    `{"v":"+Inf","a":1}`,
+			expected: `{"a":1}`,
+		},
+		{
+			name:     "int from NaN",
+			field:    FieldConfig{Field: "v", Type: "int", OnFailure: "remove"},
+			input:    `{"v":"nan","a":1}`,
+			expected: `{"a":1}`,
+		},
+		{
+			name:     "bytes from NaN",
+			field:    FieldConfig{Field: "v", Type: "bytes", OnFailure: "remove"},
+			input:    `{"v":"NaN","a":1}`,
+			expected: `{"a":1}`,
+		},
+		{
+			name:     "default on failure",
+			field:    FieldConfig{Field: "v", Type: "duration", OnFailure: "default", Default: "1s"},
+			input:    `{"v":null}`,
+			expected: `{"v":1000}`,
+		},
+	}
+
+	for _, tCase := range suits {
+		t.Run(tCase.name, func(t *testing.T) {
+			config := test.NewConfig(&Config{Fields: []FieldConfig{tCase.field}}, nil)
+			plugin := &Plugin{}
+			plugin.Start(config, test.NewEmptyActionPluginParams())
+
+			root, err := insaneJSON.DecodeString(tCase.input)
+			require.NoError(t, err)
+			defer insaneJSON.Release(root)
+
+			require.Equal(t, pipeline.ActionPass, plugin.Do(&pipeline.Event{Root: root}))
+			require.Equal(t, tCase.expected, root.EncodeToString())
+		})
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	suits := []struct {
+		in       string
+		expected int64
+		ok       bool
+	}{
+		{in: "512", expected: 512, ok: true},
+		{in: "10KB", expected: 10_000, ok: true},
+		{in: "10kb", expected: 10_000, ok: true},
+		{in: "10K", expected: 10_240, ok: true},
+		{in: "2 GiB", expected: 2 << 30, ok: true},
+		{in: "0.5B", expected: 1, ok: true},
+		{in: "", ok: false},
+		{in: "MB", ok: false},
+		{in: "10 XB", ok: false},
+		{in: "-1KB", ok: false},
+	}
+
+	for _, tCase := range suits {
+		size, ok := parseBytes(tCase.in)
+		require.Equal(t, tCase.ok, ok, tCase.in)
+		require.Equal(t, tCase.expected, size, tCase.in)
+	}
+}
@@ -0,0 +1,448 @@
+package correlate
+
+import (
+	"time"
+
+	"github.com/ozontech/file.d/cfg"
+	"github.com/ozontech/file.d/fd"
+	"github.com/ozontech/file.d/metric"
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/pipeline/doif"
+	"github.com/prometheus/client_golang/prometheus"
+	insaneJSON "github.com/vitkovskii/insane-json"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It merges the events which have the same value of the `key` field into one event,
+e.g. the request and the response logs with the same `request_id`, or the start and the end of the transaction.
+Unlike `join`, the merged events may come from the different streams and may be interleaved with the other events.
+
+The events are buffered until the group is completed: the event which meets the `complete_if` conditions comes,
+or the group has `max_events` events. The completing event is replaced with the merged one.
+If the group isn't completed in `timeout`, it's handled by `on_timeout`.
+The timed out groups are emitted together with the next event processed by the plugin,
+or when the stream of the buffered events gets no events for the pipeline `event_timeout`.
+
+The buffered events are held until their group is done, so they are committed after the merged event is emitted.
+The next events of the stream are held too, since the events of the stream are committed in order.
+The held events are released without waiting for the groups if the stream gets no events for the pipeline `event_timeout`,
+if the next event of the stream doesn't match the plugin conditions or another action spawns the events from it,
+or if the number of the held events reaches `max_held_events`.
+The buffered events which are released this way, and the buffered events spawned by the previous actions,
+are lost on file.d restart.
+
+The events without the `key` field pass the plugin as is.
+
+**Example of merging the request and the response:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: correlate
+      key: request_id
+      timeout: 30s
+      complete_if:
+        op: equal
+        field: type
+        values: [response]
+    ...
+```
+
+The events:
+```json
+{"request_id": "42", "type": "request", "path": "/api/orders"}
+{"request_id": "42", "type": "response", "status": 200}
+```
+
+The merged event:
+```json
+{"request_id": "42", "type": "response", "path": "/api/orders", "status": 200}
+```
+}*/
+
+const (
+	modeMerge   = "merge"
+	modeCollect = "collect"
+
+	onTimeoutEmit    = "emit"
+	onTimeoutDiscard = "discard"
+)
+
+type Plugin struct {
+	config     *Config
+	logger     *zap.Logger
+	controller pipeline.ActionPluginController
+
+	correlator *correlator
+	completeIf *doif.Checker
+	nowFn      func() time.Time
+	mergeRoot  *insaneJSON.Root
+	buf        []byte
+
+	// held are the events of the stream held by the plugin in the order of the stream
+	held     []heldEvent
+	spawning bool
+
+	// plugin metrics
+	groupsOverflowMetric prometheus.Counter
+	timedOutMetric       prometheus.Counter
+	heldOverflowMetric   prometheus.Counter
+}
+
+type heldEvent struct {
+	event *pipeline.Event
+	// group is the group of the buffered event, nil for the event which passes the plugin
+	group *group
+}
+
+// ! config-params
+// ^ config-params
+type Config struct {
+	// > @3@4@5@6
+	// >
+	// > The event field to correlate the events by. The string and the number values are supported.
+	Key  cfg.FieldSelector `json:"key" parse:"selector" required:"true"` // *
+	Key_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The conditions in the same format as the action `do_if`.
+	// > The event which meets them completes the group.
+	CompleteIf map[string]any `json:"complete_if"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The number of the events which completes the group. `0` means no limit.
+	// > Either `complete_if` or `max_events` should be set.
+	MaxEvents int `json:"max_events"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The maximum time to wait for the group to be completed since the first event of the group.
+	Timeout  cfg.Duration `json:"timeout" parse:"duration" default:"30s"` // *
+	Timeout_ time.Duration
+
+	// > @3@4@5@6
+	// >
+	// > What to do with the group which isn't completed in `timeout`:
+	// > `emit` the merged event or `discard` the events of the group.
+	OnTimeout string `json:"on_timeout" default:"emit" options:"emit|discard"` // *
+
+	// > @3@4@5@6
+	// >
+	// > How to merge the events:
+	// > * `merge` – the fields of the events are merged recursively, the later events override the values of the earlier ones.
+	// > * `collect` – the merged event has the `key` field and the events in the `events_field` array.
+	Mode string `json:"mode" default:"merge" options:"merge|collect"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The field of the merged event to put the events into in the `collect` mode.
+	EventsField  cfg.FieldSelector `json:"events_field" parse:"selector" default:"events"` // *
+	EventsField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The maximum number of the groups which are waiting for the completion.
+	// > The events of the new groups above the limit pass the plugin as is.
+	MaxGroups int `json:"max_groups" default:"10000"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The maximum number of the events of the stream held by the plugin.
+	// > Above the limit, the held events are released without waiting for the groups of the buffered ones.
+	// > It should be less than the pipeline `capacity`.
+	MaxHeldEvents int `json:"max_held_events" default:"256"` // *
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "correlate",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger.Desugar()
+	p.controller = params.Controller
+	p.nowFn = time.Now
+	p.registerMetrics(params.MetricCtl)
+
+	if len(p.config.Key_) == 0 {
+		p.logger.Fatal("key isn't set")
+	}
+	if p.config.Timeout_ <= 0 {
+		p.logger.Fatal("timeout must be > 0")
+	}
+	if p.config.MaxGroups <= 0 {
+		p.logger.Fatal("max_groups must be > 0", zap.Int("max_groups", p.config.MaxGroups))
+	}
+	if p.config.MaxHeldEvents <= 0 {
+		p.logger.Fatal("max_held_events must be > 0", zap.Int("max_held_events", p.config.MaxHeldEvents))
+	}
+	if p.config.MaxEvents < 0 {
+		p.logger.Fatal("max_events must be >= 0", zap.Int("max_events", p.config.MaxEvents))
+	}
+	if len(p.config.CompleteIf) == 0 && p.config.MaxEvents == 0 {
+		p.logger.Fatal("either complete_if or max_events must be set")
+	}
+
+	if len(p.config.CompleteIf) > 0 {
+		checker, err := fd.ExtractDoIfChecker(p.config.CompleteIf)
+		if err != nil {
+			p.logger.Fatal("can't extract complete_if conditions", zap.Error(err))
+		}
+		p.completeIf = checker
+	}
+
+	p.mergeRoot = insaneJSON.Spawn()
+	p.correlator = acquireCorrelator(p.config)
+}
+
+func (p *Plugin) registerMetrics(ctl *metric.Ctl) {
+	p.groupsOverflowMetric = ctl.RegisterCounter(
+		"correlate_groups_overflow_total",
+		"Number of events passed by the correlate plugin as is because the max_groups limit is reached",
+	)
+	p.timedOutMetric = ctl.RegisterCounter(
+		"correlate_timed_out_groups_total",
+		"Number of groups which aren't completed in timeout by the correlate plugin",
+	)
+	p.heldOverflowMetric = ctl.RegisterCounter(
+		"correlate_held_overflow_total",
+		"Number of times the held events are released by the correlate plugin because the max_held_events limit is reached",
+	)
+}
+
+func (p *Plugin) Stop() {
+	releaseCorrelator(p.config)
+	insaneJSON.Release(p.mergeRoot)
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	if event.IsTimeoutKind() {
+		return p.flushTimeout()
+	}
+
+	now := p.nowFn()
+
+	// spawned events aren't committed, so they aren't held and can't be the parents of the timed out groups
+	if event.IsChildKind() {
+		if p.correlate(event, now) != nil {
+			// the copy of the event is buffered
+			return pipeline.ActionDiscard
+		}
+		return pipeline.ActionPass
+	}
+
+	expired := p.correlator.expire(now)
+	p.timedOutMetric.Add(float64(len(expired)))
+
+	buffered := p.correlate(event, now)
+	p.releaseDone()
+
+	// the event root is replaced by the merged event, so the timed out events are made after
+	p.spawnTimedOut(event, buffered == nil, expired)
+
+	if len(p.held) >= p.config.MaxHeldEvents {
+		p.heldOverflowMetric.Inc()
+		p.releaseAll()
+	}
+
+	if buffered == nil && len(p.held) == 0 {
+		if event.IsChildParentKind() {
+			return pipeline.ActionBreak
+		}
+		return pipeline.ActionPass
+	}
+
+	p.held = append(p.held, heldEvent{event: event, group: buffered})
+	return pipeline.ActionHold
+}
+
+// flushTimeout releases the held events on the timeout event.
+func (p *Plugin) flushTimeout() pipeline.ActionResult {
+	// the parent of the spawned events is held, so there is nothing to flush
+	if p.spawning {
+		return pipeline.ActionCollapse
+	}
+
+	if len(p.held) > 0 {
+		expired := p.correlator.expire(p.nowFn())
+		p.timedOutMetric.Add(float64(len(expired)))
+
+		// the first held event is always buffered, so it isn't written anyway
+		p.spawnTimedOut(p.held[0].event, false, expired)
+	}
+
+	p.releaseAll()
+	return pipeline.ActionDiscard
+}
+
+// spawnTimedOut spawns the merged events of the timed out groups from the parent.
+// The parent of the spawned events isn't written by the output,
+// so the parent which passes the plugin is spawned as well.
+func (p *Plugin) spawnTimedOut(parent *pipeline.Event, passes bool, expired []*group) {
+	nodes := make([]*insaneJSON.Node, 0, len(expired)+1)
+	if passes && len(expired) > 0 && p.config.OnTimeout == onTimeoutEmit {
+		node, err := p.copyRoot(parent.Root, parent.Root)
+		if err != nil {
+			p.logger.Error("can't copy event", zap.Error(err))
+			return
+		}
+		nodes = append(nodes, node)
+	}
+	nodes = append(nodes, p.makeTimedOut(parent.Root, expired)...)
+	if len(nodes) == 0 || (passes && len(nodes) == 1) {
+		return
+	}
+
+	p.spawning = true
+	p.controller.Spawn(parent, nodes)
+	p.spawning = false
+}
+
+// releaseDone releases the held events up to the first buffered event which group isn't done.
+func (p *Plugin) releaseDone() {
+	for len(p.held) > 0 {
+		h := p.held[0]
+		if h.group != nil && !p.correlator.isDone(h.group) {
+			return
+		}
+		p.held = p.held[1:]
+		p.propagate(h)
+	}
+}
+
+func (p *Plugin) releaseAll() {
+	// the held events are taken one by one, since the timeout may come while the event is propagated
+	for len(p.held) > 0 {
+		h := p.held[0]
+		p.held = p.held[1:]
+		p.propagate(h)
+	}
+}
+
+func (p *Plugin) propagate(h heldEvent) {
+	// the buffered event is committed by the output, but isn't written
+	if h.group != nil {
+		h.event.SetChildParentKind()
+	}
+	p.controller.Propagate(h.event)
+}
+
+// correlate returns the group of the event if the event is buffered, otherwise the event passes the plugin.
+func (p *Plugin) correlate(event *pipeline.Event, now time.Time) *group {
+	keyNode := event.Root.Dig(p.config.Key_...)
+	if keyNode == nil || !(keyNode.IsString() || keyNode.IsNumber()) {
+		return nil
+	}
+	key := keyNode.EncodeToString()
+
+	complete := p.completeIf != nil && p.completeIf.Check(event.Root)
+	part := event.Root.EncodeToString()
+
+	g, completed := p.correlator.add(now, key, part, complete)
+	if g == nil {
+		p.groupsOverflowMetric.Inc()
+		return nil
+	}
+	if !completed {
+		return g
+	}
+
+	merged, err := p.merge(g)
+	if err != nil {
+		p.logger.Error("can't merge events", zap.Error(err))
+		return nil
+	}
+
+	// the merged event is in the reused buffer, so it's copied
+	if err := event.Root.DecodeString(string(merged)); err != nil {
+		p.logger.Error("can't decode merged event", zap.Error(err))
+	}
+	return nil
+}
+
+// makeTimedOut makes the merged events of the timed out groups within the root of the parent event,
+// so the nodes live as long as the parent.
+func (p *Plugin) makeTimedOut(root *insaneJSON.Root, expired []*group) []*insaneJSON.Node {
+	if len(expired) == 0 || p.config.OnTimeout == onTimeoutDiscard {
+		return nil
+	}
+
+	nodes := make([]*insaneJSON.Node, 0, len(expired))
+	for _, g := range expired {
+		merged, err := p.merge(g)
+		if err != nil {
+			p.logger.Error("can't merge events", zap.Error(err))
+			continue
+		}
+		node, err := root.DecodeStringAdditional(string(merged))
+		if err != nil {
+			p.logger.Error("can't decode merged event", zap.Error(err))
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// merge merges the parts of the group and returns the encoded merged event.
+// The returned buffer is reused by the next call.
+func (p *Plugin) merge(g *group) ([]byte, error) {
+	root := p.mergeRoot
+	if err := root.DecodeString("{}"); err != nil {
+		return nil, err
+	}
+
+	var events *insaneJSON.Node
+	if p.config.Mode == modeCollect {
+		pipeline.CreateNestedField(root, p.config.Key_).MutateToJSON(root, g.key)
+		events = pipeline.CreateNestedField(root, p.config.EventsField_).MutateToArray()
+	}
+
+	for _, part := range g.parts {
+		node, err := root.DecodeStringAdditional(part)
+		if err != nil {
+			return nil, err
+		}
+
+		if events != nil {
+			events.AddElementNoAlloc(root).MutateToNode(node)
+			continue
+		}
+		mergeObjects(root, root.Node, node)
+	}
+
+	p.buf = root.Encode(p.buf[:0])
+	return p.buf, nil
+}
+
+// copyRoot decodes the copy of src within dst, so the copy lives as long as dst.
+func (p *Plugin) copyRoot(dst, src *insaneJSON.Root) (*insaneJSON.Node, error) {
+	p.buf = src.Encode(p.buf[:0])
+	return dst.DecodeStringAdditional(string(p.buf))
+}
+
+// mergeObjects merges the fields of src into dst recursively, the values of src override the values of dst.
+func mergeObjects(root *insaneJSON.Root, dst, src *insaneJSON.Node) {
+	for _, field := range src.AsFields() {
+		value := field.AsFieldValue()
+		name := field.AsString()
+
+		current := dst.Dig(name)
+		if current != nil && current.IsObject() && value.IsObject() {
+			mergeObjects(root, current, value)
+			continue
+		}
+		dst.AddFieldNoAlloc(root, name).MutateToNode(value)
+	}
+}
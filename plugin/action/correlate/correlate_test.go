@@ -0,0 +1,311 @@
+package correlate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/test"
+	"github.com/stretchr/testify/require"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+type fakeController struct {
+	spawned    []string
+	propagated []*pipeline.Event
+}
+
+func (c *fakeController) Propagate(event *pipeline.Event) {
+	c.propagated = append(c.propagated, event)
+}
+
+func (c *fakeController) Spawn(parent *pipeline.Event, nodes []*insaneJSON.Node) {
+	parent.SetChildParentKind()
+	for _, node := range nodes {
+		c.spawned = append(c.spawned, node.EncodeToString())
+	}
+}
+
+func (c *fakeController) IncMaxEventSizeExceeded() {}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func startCorrelate(t *testing.T, config *Config, clock *fakeClock) (*Plugin, *fakeController) {
+	t.Helper()
+
+	ctl := &fakeController{}
+	params := test.NewEmptyActionPluginParams()
+	params.Controller = ctl
+
+	plugin := &Plugin{}
+	plugin.Start(test.NewConfig(config, nil), params)
+	plugin.nowFn = clock.Now
+	t.Cleanup(plugin.Stop)
+
+	return plugin, ctl
+}
+
+// doCorrelate returns the result of the plugin and the event after the plugin.
+func doCorrelate(t *testing.T, plugin *Plugin, e string) (pipeline.ActionResult, string) {
+	t.Helper()
+
+	result, event := doCorrelateEvent(t, plugin, e)
+	return result, event.Root.EncodeToString()
+}
+
+func doCorrelateEvent(t *testing.T, plugin *Plugin, e string) (pipeline.ActionResult, *pipeline.Event) {
+	t.Helper()
+
+	root, err := insaneJSON.DecodeString(e)
+	require.NoError(t, err)
+	// the event may be held by the plugin
+	t.Cleanup(func() { insaneJSON.Release(root) })
+
+	event := &pipeline.Event{Root: root}
+	return plugin.Do(event), event
+}
+
+func doTimeout(plugin *Plugin) pipeline.ActionResult {
+	event := &pipeline.Event{}
+	event.SetTimeoutKind()
+	return plugin.Do(event)
+}
+
+// encodePropagated returns the propagated events and whether they are written by the output.
+func encodePropagated(ctl *fakeController) []string {
+	out := make([]string, 0, len(ctl.propagated))
+	for _, event := range ctl.propagated {
+		prefix := "written "
+		if event.IsChildParentKind() {
+			prefix = "committed "
+		}
+		out = append(out, prefix+event.Root.EncodeToString())
+	}
+	return out
+}
+
+var completeOnResponse = map[string]any{
+	"op":     "equal",
+	"field":  "type",
+	"values": []any{"response"},
+}
+
+func TestCorrelateMerge(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	plugin, ctl := startCorrelate(t, &Config{
+		Key:        "request.id",
+		CompleteIf: completeOnResponse,
+	}, clock)
+
+	// the buffered events are held, and the next events of the stream are held behind them
+	in := []string{
+		`{"request":{"id":"42"},"type":"request","path":"/api","meta":{"host":"a","tags":[1]}}`,
+		`{"request":{"id":43},"type":"request"}`,
+		`{"message":"no key"}`,
+		`{"request":{"id":"42"},"type":"progress","meta":{"tags":[2],"step":1}}`,
+	}
+	for _, e := range in {
+		result, _ := doCorrelate(t, plugin, e)
+		require.Equal(t, pipeline.ActionHold, result, "wrong result for event %q", e)
+	}
+
+	// the group is done, but the event is held behind the buffered event of the other group
+	result, out := doCorrelate(t, plugin, `{"request":{"id":"42"},"type":"response","status":200}`)
+	require.Equal(t, pipeline.ActionHold, result)
+	require.Equal(t,
+		`{"request":{"id":"42"},"type":"response","path":"/api","meta":{"host":"a","tags":[2],"step":1},"status":200}`,
+		out,
+	)
+	require.Equal(t, []string{"committed " + in[0]}, encodePropagated(ctl))
+
+	// the key of the other type doesn't match
+	result, out = doCorrelate(t, plugin, `{"request":{"id":"43"},"type":"response"}`)
+	require.Equal(t, pipeline.ActionHold, result)
+	require.Equal(t, `{"request":{"id":"43"},"type":"response"}`, out)
+
+	// all the held events are released in the order of the stream
+	result, out = doCorrelate(t, plugin, `{"request":{"id":43},"type":"response"}`)
+	require.Equal(t, pipeline.ActionPass, result)
+	require.Equal(t, `{"request":{"id":43},"type":"response"}`, out)
+	require.Equal(t, []string{
+		"committed " + in[0],
+		"committed " + in[1],
+		"written " + in[2],
+		"committed " + in[3],
+		`written {"request":{"id":"42"},"type":"response","path":"/api","meta":{"host":"a","tags":[2],"step":1},"status":200}`,
+		`written {"request":{"id":"43"},"type":"response"}`,
+	}, encodePropagated(ctl))
+	require.Empty(t, plugin.held)
+	require.Empty(t, ctl.spawned)
+}
+
+func TestCorrelateCollectMaxEvents(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	plugin, _ := startCorrelate(t, &Config{
+		Key:         "tx",
+		MaxEvents:   3,
+		Mode:        modeCollect,
+		EventsField: "parts",
+	}, clock)
+
+	for _, e := range []string{`{"tx":1,"step":"begin"}`, `{"tx":1,"step":"update"}`} {
+		result, _ := doCorrelate(t, plugin, e)
+		require.Equal(t, pipeline.ActionHold, result)
+	}
+
+	result, out := doCorrelate(t, plugin, `{"tx":1,"step":"commit"}`)
+	require.Equal(t, pipeline.ActionPass, result)
+	require.Equal(t,
+		`{"tx":1,"parts":[{"tx":1,"step":"begin"},{"tx":1,"step":"update"},{"tx":1,"step":"commit"}]}`,
+		out,
+	)
+}
+
+func TestCorrelateTimeout(t *testing.T) {
+	start := time.Now()
+	clock := &fakeClock{now: start}
+	plugin, ctl := startCorrelate(t, &Config{
+		Key:        "id",
+		CompleteIf: completeOnResponse,
+		Timeout:    "10s",
+	}, clock)
+
+	result, _ := doCorrelate(t, plugin, `{"id":"1","a":1}`)
+	require.Equal(t, pipeline.ActionHold, result)
+	clock.now = start.Add(5 * time.Second)
+	result, _ = doCorrelate(t, plugin, `{"id":"2","b":2}`)
+	require.Equal(t, pipeline.ActionHold, result)
+	result, _ = doCorrelate(t, plugin, `{"id":"1","c":3}`)
+	require.Equal(t, pipeline.ActionHold, result)
+
+	// the passed event is spawned together with the timed out group
+	clock.now = start.Add(11 * time.Second)
+	result, _ = doCorrelate(t, plugin, `{"message":"no key"}`)
+	require.Equal(t, pipeline.ActionHold, result)
+	require.Equal(t, []string{`{"message":"no key"}`, `{"id":"1","a":1,"c":3}`}, ctl.spawned)
+	require.Equal(t, []string{`committed {"id":"1","a":1}`}, encodePropagated(ctl))
+
+	// the buffered event isn't spawned
+	ctl.spawned = nil
+	clock.now = start.Add(16 * time.Second)
+	result, _ = doCorrelate(t, plugin, `{"id":"3"}`)
+	require.Equal(t, pipeline.ActionHold, result)
+	require.Equal(t, []string{`{"id":"2","b":2}`}, ctl.spawned)
+	require.Equal(t, []string{
+		`committed {"id":"1","a":1}`,
+		`committed {"id":"2","b":2}`,
+		`committed {"id":"1","c":3}`,
+		`committed {"message":"no key"}`,
+	}, encodePropagated(ctl))
+
+	// the timeout event flushes the timed out groups and releases the held events
+	ctl.spawned = nil
+	clock.now = start.Add(30 * time.Second)
+	require.Equal(t, pipeline.ActionDiscard, doTimeout(plugin))
+	require.Equal(t, []string{`{"id":"3"}`}, ctl.spawned)
+	require.Len(t, ctl.propagated, 5)
+	require.Empty(t, plugin.held)
+}
+
+func TestCorrelateTimeoutDiscard(t *testing.T) {
+	start := time.Now()
+	clock := &fakeClock{now: start}
+	plugin, ctl := startCorrelate(t, &Config{
+		Key:       "id",
+		MaxEvents: 2,
+		Timeout:   "10s",
+		OnTimeout: onTimeoutDiscard,
+	}, clock)
+
+	result, _ := doCorrelate(t, plugin, `{"id":"1"}`)
+	require.Equal(t, pipeline.ActionHold, result)
+
+	clock.now = start.Add(time.Minute)
+	result, _ = doCorrelate(t, plugin, `{"id":"1","last":true}`)
+	require.Equal(t, pipeline.ActionHold, result)
+	require.Equal(t, []string{`committed {"id":"1"}`}, encodePropagated(ctl))
+	require.Empty(t, ctl.spawned)
+}
+
+func TestCorrelateMaxGroups(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	plugin, ctl := startCorrelate(t, &Config{
+		Key:       "id",
+		MaxEvents: 2,
+		MaxGroups: 2,
+	}, clock)
+
+	for _, e := range []string{`{"id":1}`, `{"id":2}`} {
+		result, _ := doCorrelate(t, plugin, e)
+		require.Equal(t, pipeline.ActionHold, result)
+	}
+	require.Len(t, plugin.correlator.groups, 2)
+
+	// the event of the new group isn't buffered
+	result, _ := doCorrelate(t, plugin, `{"id":3}`)
+	require.Equal(t, pipeline.ActionHold, result)
+	require.Len(t, plugin.correlator.groups, 2)
+
+	result, _ = doCorrelate(t, plugin, `{"id":1}`)
+	require.Equal(t, pipeline.ActionHold, result)
+	result, _ = doCorrelate(t, plugin, `{"id":3}`)
+	require.Equal(t, pipeline.ActionHold, result)
+	require.Len(t, plugin.correlator.groups, 2)
+	require.Equal(t, []string{`committed {"id":1}`}, encodePropagated(ctl))
+}
+
+func TestCorrelateMaxHeldEvents(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	plugin, ctl := startCorrelate(t, &Config{
+		Key:           "id",
+		MaxEvents:     2,
+		MaxHeldEvents: 2,
+	}, clock)
+
+	result, _ := doCorrelate(t, plugin, `{"id":1}`)
+	require.Equal(t, pipeline.ActionHold, result)
+	result, _ = doCorrelate(t, plugin, `{}`)
+	require.Equal(t, pipeline.ActionHold, result)
+
+	// the held events are released without waiting for the group
+	result, _ = doCorrelate(t, plugin, `{}`)
+	require.Equal(t, pipeline.ActionPass, result)
+	require.Equal(t, []string{`committed {"id":1}`, `written {}`}, encodePropagated(ctl))
+
+	// the group is still buffered
+	result, out := doCorrelate(t, plugin, `{"id":1,"last":true}`)
+	require.Equal(t, pipeline.ActionPass, result)
+	require.Equal(t, `{"id":1,"last":true}`, out)
+}
+
+func TestCorrelateChild(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	plugin, _ := startCorrelate(t, &Config{
+		Key:       "id",
+		MaxEvents: 2,
+	}, clock)
+
+	doChild := func(e string) (pipeline.ActionResult, string) {
+		root, err := insaneJSON.DecodeString(e)
+		require.NoError(t, err)
+		defer insaneJSON.Release(root)
+
+		event := &pipeline.Event{Root: root}
+		event.SetChildKind()
+		return plugin.Do(event), root.EncodeToString()
+	}
+
+	// the spawned events aren't held
+	result, _ := doChild(`{"id":1,"a":1}`)
+	require.Equal(t, pipeline.ActionDiscard, result)
+	result, out := doChild(`{"id":1,"b":2}`)
+	require.Equal(t, pipeline.ActionPass, result)
+	require.Equal(t, `{"id":1,"a":1,"b":2}`, out)
+	require.Empty(t, plugin.held)
+}
@@ -0,0 +1,130 @@
+package correlate
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+var (
+	correlatorsMu = &sync.Mutex{}
+	// correlators are shared by the plugin instances of the pipeline processors,
+	// since the parts of the group can come from the different streams
+	correlators = make(map[*Config]*correlator)
+)
+
+type group struct {
+	key      string
+	parts    []string
+	deadline time.Time
+	// done is true if the group is completed or timed out
+	done bool
+}
+
+// correlator holds the groups in the order of the creation,
+// so the groups are expired from the front since the timeout is the same for all the groups.
+type correlator struct {
+	timeout   time.Duration
+	maxGroups int
+	maxEvents int
+
+	mu     sync.Mutex
+	groups map[string]*list.Element
+	order  *list.List
+
+	refs int
+}
+
+func acquireCorrelator(config *Config) *correlator {
+	correlatorsMu.Lock()
+	defer correlatorsMu.Unlock()
+
+	if c, has := correlators[config]; has {
+		c.refs++
+		return c
+	}
+
+	c := &correlator{
+		timeout:   config.Timeout_,
+		maxGroups: config.MaxGroups,
+		maxEvents: config.MaxEvents,
+		groups:    make(map[string]*list.Element),
+		order:     list.New(),
+		refs:      1,
+	}
+	correlators[config] = c
+	return c
+}
+
+func releaseCorrelator(config *Config) {
+	correlatorsMu.Lock()
+	defer correlatorsMu.Unlock()
+
+	c, has := correlators[config]
+	if !has {
+		return
+	}
+	c.refs--
+	if c.refs == 0 {
+		delete(correlators, config)
+	}
+}
+
+// add adds the part to the group of the key and returns the group.
+// It returns true if the group is completed by the part, the group is removed then.
+// It returns nil if the group can't be created because of the max_groups limit.
+func (c *correlator) add(now time.Time, key string, part string, complete bool) (*group, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var g *group
+	if e, has := c.groups[key]; has {
+		g = e.Value.(*group)
+	} else {
+		if complete {
+			// the single part group isn't stored
+			return &group{key: key, parts: []string{part}, done: true}, true
+		}
+		if len(c.groups) >= c.maxGroups {
+			return nil, false
+		}
+		g = &group{key: key, deadline: now.Add(c.timeout)}
+		c.groups[key] = c.order.PushBack(g)
+	}
+
+	g.parts = append(g.parts, part)
+	if complete || (c.maxEvents > 0 && len(g.parts) >= c.maxEvents) {
+		c.order.Remove(c.groups[key])
+		delete(c.groups, key)
+		g.done = true
+		return g, true
+	}
+
+	return g, false
+}
+
+// expire removes and returns the groups which timeout is reached.
+func (c *correlator) expire(now time.Time) []*group {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expired []*group
+	for e := c.order.Front(); e != nil; e = c.order.Front() {
+		g := e.Value.(*group)
+		if now.Before(g.deadline) {
+			break
+		}
+		c.order.Remove(e)
+		delete(c.groups, g.key)
+		g.done = true
+		expired = append(expired, g)
+	}
+	return expired
+}
+
+func (c *correlator) isDone(g *group) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return g.done
+}
@@ -0,0 +1,241 @@
+package dedup
+
+import (
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/go-redis/redis"
+	"github.com/ozontech/file.d/cfg"
+	"github.com/ozontech/file.d/fd"
+	"github.com/ozontech/file.d/metric"
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/plugin/action/throttle"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It discards the events which have already been seen within the time window.
+It is useful to get rid of the duplicates produced by at-least-once delivery, e.g. after Kafka consumer rebalancing
+or re-reading files after a restart.
+
+The key of the event is computed from the values of `fields` or from the whole event if `fields` aren't set.
+Only the 64-bit hashes of the keys are stored, and the number of stored keys is bounded by `max_keys`:
+the least recently seen keys are evicted first.
+
+The redis backend allows to deduplicate the events across several file.d instances.
+If redis isn't available, the in-memory store is used until redis is back.
+
+**Example of deduplicating events by the request ID and the message:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: dedup
+      fields:
+        - request_id
+        - message
+      window: 10m
+    ...
+```
+}*/
+
+const (
+	redisBackend    = "redis"
+	inMemoryBackend = "memory"
+
+	// keySeparator separates the values of the key fields to avoid collisions like ("ab", "c") and ("a", "bc")
+	keySeparator = 0xff
+)
+
+type Plugin struct {
+	config *Config
+	logger *zap.Logger
+
+	fields [][]string
+	store  *sharedStore
+
+	keyBuf []byte
+	nowFn  func() time.Time
+
+	// plugin metrics
+	duplicatesMetric  prometheus.Counter
+	redisErrorsMetric prometheus.Counter
+}
+
+// ! config-params
+// ^ config-params
+type Config struct {
+	// > @3@4@5@6
+	// >
+	// > The event fields which values form the key of the event.
+	// > If not set, the whole event is used as a key.
+	// > Events which have none of the fields pass the plugin as is.
+	Fields []cfg.FieldSelector `json:"fields" slice:"true"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The time window to remember the seen keys.
+	// > The event is discarded if the same key has been seen less than `window` ago.
+	Window  cfg.Duration `json:"window" parse:"duration" default:"1m"` // *
+	Window_ time.Duration
+
+	// > @3@4@5@6
+	// >
+	// > The maximum number of keys kept in the memory.
+	MaxKeys int `json:"max_keys" default:"100000"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Defines kind of backend.
+	Backend string `json:"backend" default:"memory" options:"memory|redis"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Redis settings in the same format as the `throttle` plugin uses.
+	// > Only the connection settings are used: `endpoint`, `password`, `timeout`, `max_retries`,
+	// > `min_retry_backoff` and `max_retry_backoff`.
+	RedisBackendCfg throttle.RedisBackendConfig `json:"redis_backend_config" child:"true"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The prefix of the redis keys. If not set, the pipeline name is used.
+	RedisKeyPrefix string `json:"redis_key_prefix"` // *
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "dedup",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger.Desugar()
+	p.nowFn = time.Now
+	p.keyBuf = make([]byte, 0, params.PipelineSettings.AvgEventSize)
+	p.registerMetrics(params.MetricCtl)
+
+	if p.config.MaxKeys <= 0 {
+		p.logger.Fatal("max_keys must be > 0", zap.Int("max_keys", p.config.MaxKeys))
+	}
+
+	p.fields = make([][]string, 0, len(p.config.Fields))
+	for _, field := range p.config.Fields {
+		p.fields = append(p.fields, cfg.ParseFieldSelector(string(field)))
+	}
+
+	p.store = acquireStore(p.config, func() *sharedStore {
+		return p.newStore(params.PipelineName)
+	})
+}
+
+func (p *Plugin) newStore(pipelineName string) *sharedStore {
+	s := &sharedStore{
+		memory: newLRUStore(p.config.Window_, p.config.MaxKeys),
+	}
+	if p.config.Backend != redisBackend {
+		return s
+	}
+
+	keyPrefix := p.config.RedisKeyPrefix
+	if keyPrefix == "" {
+		keyPrefix = pipelineName
+	}
+
+	redisCfg := &p.config.RedisBackendCfg
+	client := redis.NewClient(&redis.Options{
+		Network:         "tcp",
+		Addr:            redisCfg.Endpoint,
+		Password:        redisCfg.Password,
+		ReadTimeout:     redisCfg.Timeout_,
+		WriteTimeout:    redisCfg.Timeout_,
+		MaxRetries:      redisCfg.MaxRetries,
+		MinRetryBackoff: redisCfg.MinRetryBackoff_,
+		MaxRetryBackoff: redisCfg.MaxRetryBackoff_,
+	})
+	s.redis = newRedisStore(client, keyPrefix+"_dedup_", p.config.Window_)
+	s.redisClient = client
+
+	return s
+}
+
+func (p *Plugin) registerMetrics(ctl *metric.Ctl) {
+	p.duplicatesMetric = ctl.RegisterCounter(
+		"dedup_duplicates_total",
+		"Number of events discarded by the dedup plugin as duplicates",
+	)
+	p.redisErrorsMetric = ctl.RegisterCounter(
+		"dedup_redis_errors_total",
+		"Number of redis errors in the dedup plugin",
+	)
+}
+
+func (p *Plugin) Stop() {
+	if err := releaseStore(p.config); err != nil {
+		p.logger.Error("can't close redis client", zap.Error(err))
+	}
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	var ok bool
+	p.keyBuf, ok = p.appendKey(p.keyBuf[:0], event)
+	if !ok {
+		return pipeline.ActionPass
+	}
+
+	if p.isDuplicate(xxhash.Sum64(p.keyBuf)) {
+		p.duplicatesMetric.Inc()
+		return pipeline.ActionDiscard
+	}
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) isDuplicate(key uint64) bool {
+	now := p.nowFn()
+
+	if p.store.redis != nil {
+		isDuplicate, err := p.store.redis.isDuplicate(key, now)
+		if err == nil {
+			if p.store.redisDown.CompareAndSwap(true, false) {
+				p.logger.Info("redis is available again, redis store is used")
+			}
+			return isDuplicate
+		}
+
+		// the errors are counted by the metric, and only the first one is logged until redis is back
+		p.redisErrorsMetric.Inc()
+		if p.store.redisDown.CompareAndSwap(false, true) {
+			p.logger.Warn("can't check the key in redis, in-memory store is used", zap.Error(err))
+		}
+	}
+
+	isDuplicate, _ := p.store.memory.isDuplicate(key, now)
+	return isDuplicate
+}
+
+// appendKey returns false if the event has none of the key fields.
+func (p *Plugin) appendKey(dst []byte, event *pipeline.Event) ([]byte, bool) {
+	if len(p.fields) == 0 {
+		return event.Root.Encode(dst), true
+	}
+
+	found := false
+	for _, field := range p.fields {
+		node := event.Root.Dig(field...)
+		if node != nil {
+			found = true
+			dst = node.Encode(dst)
+		}
+		dst = append(dst, keySeparator)
+	}
+
+	return dst, found
+}
@@ -0,0 +1,210 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ozontech/file.d/cfg"
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/plugin/action/throttle"
+	"github.com/ozontech/file.d/test"
+	"github.com/stretchr/testify/require"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func startDedup(t *testing.T, config *Config, clock *fakeClock) *Plugin {
+	t.Helper()
+
+	plugin := &Plugin{}
+	plugin.Start(test.NewConfig(config, nil), test.NewEmptyActionPluginParams())
+	plugin.nowFn = clock.Now
+	t.Cleanup(plugin.Stop)
+
+	return plugin
+}
+
+func doDedup(t *testing.T, plugin *Plugin, in []string) []string {
+	t.Helper()
+
+	out := make([]string, 0)
+	for _, e := range in {
+		root, err := insaneJSON.DecodeString(e)
+		require.NoError(t, err)
+
+		if plugin.Do(&pipeline.Event{Root: root}) == pipeline.ActionPass {
+			out = append(out, root.EncodeToString())
+		}
+		insaneJSON.Release(root)
+	}
+
+	return out
+}
+
+func TestDedup(t *testing.T) {
+	cases := []struct {
+		name   string
+		config *Config
+		in     []string
+		out    []string
+	}{
+		{
+			name:   "whole_event",
+			config: &Config{},
+			in: []string{
+				`{"id":1,"message":"a"}`,
+				`{"id":1,"message":"a"}`,
+				`{"id":1,"message":"b"}`,
+			},
+			out: []string{
+				`{"id":1,"message":"a"}`,
+				`{"id":1,"message":"b"}`,
+			},
+		},
+		{
+			name:   "fields",
+			config: &Config{Fields: []cfg.FieldSelector{"id", "meta.source"}},
+			in: []string{
+				`{"id":1,"meta":{"source":"a"},"ts":1}`,
+				`{"id":1,"meta":{"source":"a"},"ts":2}`,
+				`{"id":1,"meta":{"source":"b"},"ts":3}`,
+				`{"id":1,"ts":4}`,
+				`{"id":1,"ts":5}`,
+				`{"message":"no key"}`,
+				`{"message":"no key"}`,
+			},
+			out: []string{
+				`{"id":1,"meta":{"source":"a"},"ts":1}`,
+				`{"id":1,"meta":{"source":"b"},"ts":3}`,
+				`{"id":1,"ts":4}`,
+				`{"message":"no key"}`,
+				`{"message":"no key"}`,
+			},
+		},
+		{
+			name:   "no_collisions",
+			config: &Config{Fields: []cfg.FieldSelector{"a", "b"}},
+			in: []string{
+				`{"a":"ab","b":"c"}`,
+				`{"a":"a","b":"bc"}`,
+			},
+			out: []string{
+				`{"a":"ab","b":"c"}`,
+				`{"a":"a","b":"bc"}`,
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := startDedup(t, tt.config, &fakeClock{now: time.Now()})
+			require.Equal(t, tt.out, doDedup(t, plugin, tt.in))
+		})
+	}
+}
+
+func TestDedupWindow(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	plugin := startDedup(t, &Config{Fields: []cfg.FieldSelector{"id"}, Window: "1m"}, clock)
+
+	in := []string{`{"id":1}`}
+	require.Len(t, doDedup(t, plugin, in), 1)
+
+	clock.now = clock.now.Add(30 * time.Second)
+	require.Len(t, doDedup(t, plugin, in), 0)
+
+	clock.now = clock.now.Add(time.Minute)
+	require.Len(t, doDedup(t, plugin, in), 1)
+	require.Len(t, doDedup(t, plugin, in), 0)
+}
+
+func TestLRUStoreEviction(t *testing.T) {
+	s := newLRUStore(time.Minute, 2)
+	now := time.Now()
+
+	isDuplicate := func(key uint64) bool {
+		t.Helper()
+		dup, err := s.isDuplicate(key, now)
+		require.NoError(t, err)
+		return dup
+	}
+
+	require.False(t, isDuplicate(1))
+	require.False(t, isDuplicate(2))
+	require.True(t, isDuplicate(1))
+
+	// key 2 is the least recently seen one, so it's evicted
+	require.False(t, isDuplicate(3))
+	require.True(t, isDuplicate(1))
+	require.False(t, isDuplicate(2))
+}
+
+func TestDedupRedis(t *testing.T) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+	defer s.Close()
+
+	newConfig := func() *Config {
+		return &Config{
+			Fields:  []cfg.FieldSelector{"id"},
+			Window:  "1m",
+			Backend: redisBackend,
+			RedisBackendCfg: throttle.RedisBackendConfig{
+				Endpoint: s.Addr(),
+			},
+			RedisKeyPrefix: "test",
+		}
+	}
+
+	// different configs emulate different file.d instances
+	clock := &fakeClock{now: time.Now()}
+	first := startDedup(t, newConfig(), clock)
+	second := startDedup(t, newConfig(), clock)
+
+	require.Len(t, doDedup(t, first, []string{`{"id":1}`}), 1)
+	require.Len(t, doDedup(t, second, []string{`{"id":1}`}), 0)
+	require.Len(t, doDedup(t, second, []string{`{"id":2}`}), 1)
+
+	s.FastForward(time.Minute)
+	require.Len(t, doDedup(t, second, []string{`{"id":1}`}), 1)
+
+	// the in-memory store is used when redis isn't available
+	s.Close()
+	require.Len(t, doDedup(t, first, []string{`{"id":3}`}), 1)
+	require.Len(t, doDedup(t, first, []string{`{"id":3}`}), 0)
+	require.True(t, first.store.redisDown.Load())
+}
+
+type fakeCloser struct {
+	closed bool
+}
+
+func (c *fakeCloser) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestReleaseStore(t *testing.T) {
+	config := &Config{}
+	closer := &fakeCloser{}
+	newStore := func() *sharedStore {
+		return &sharedStore{redisClient: closer}
+	}
+
+	first := acquireStore(config, newStore)
+	require.Same(t, first, acquireStore(config, newStore))
+
+	// the redis client is closed when the last processor releases the store
+	require.NoError(t, releaseStore(config))
+	require.False(t, closer.closed)
+	require.NoError(t, releaseStore(config))
+	require.True(t, closer.closed)
+}
@@ -0,0 +1,151 @@
+package dedup
+
+import (
+	"container/list"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+var (
+	// stores should be shared across the processors, so let's have a map by the action config
+	stores   = map[*Config]*sharedStore{}
+	storesMu = &sync.Mutex{}
+)
+
+type store interface {
+	// isDuplicate returns true if the key has been seen within the window,
+	// otherwise it remembers the key.
+	isDuplicate(key uint64, now time.Time) (bool, error)
+}
+
+// sharedStore is used by all processors of the action.
+type sharedStore struct {
+	memory store
+	redis  store // nil if the redis backend isn't used
+	// redisClient is closed when the store isn't used anymore
+	redisClient io.Closer
+	// redisDown is true after the redis error until the next successful request
+	redisDown atomic.Bool
+
+	refs int
+}
+
+func acquireStore(config *Config, newStore func() *sharedStore) *sharedStore {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	if s, has := stores[config]; has {
+		s.refs++
+		return s
+	}
+
+	s := newStore()
+	s.refs = 1
+	stores[config] = s
+	return s
+}
+
+// releaseStore closes the redis client when the store isn't used anymore.
+func releaseStore(config *Config) error {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	s, has := stores[config]
+	if !has {
+		return nil
+	}
+	s.refs--
+	if s.refs > 0 {
+		return nil
+	}
+	delete(stores, config)
+
+	if s.redisClient == nil {
+		return nil
+	}
+	return s.redisClient.Close()
+}
+
+type lruEntry struct {
+	key  uint64
+	seen time.Time
+}
+
+// lruStore keeps at most maxKeys keys, the least recently seen keys are evicted first.
+type lruStore struct {
+	window  time.Duration
+	maxKeys int
+
+	mu    sync.Mutex
+	keys  map[uint64]*list.Element
+	order *list.List
+}
+
+func newLRUStore(window time.Duration, maxKeys int) *lruStore {
+	return &lruStore{
+		window:  window,
+		maxKeys: maxKeys,
+		keys:    make(map[uint64]*list.Element, maxKeys),
+		order:   list.New(),
+	}
+}
+
+func (s *lruStore) isDuplicate(key uint64, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, has := s.keys[key]; has {
+		entry := el.Value.(*lruEntry)
+		if now.Sub(entry.seen) < s.window {
+			s.order.MoveToFront(el)
+			return true, nil
+		}
+
+		// the window is over, so the event starts a new one
+		entry.seen = now
+		s.order.MoveToFront(el)
+		return false, nil
+	}
+
+	s.keys[key] = s.order.PushFront(&lruEntry{key: key, seen: now})
+	if s.order.Len() > s.maxKeys {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.keys, oldest.Value.(*lruEntry).key)
+	}
+
+	return false, nil
+}
+
+// interface with only necessary functions of the original redis.Client
+type redisClient interface {
+	SetNX(key string, value any, expiration time.Duration) *redis.BoolCmd
+}
+
+// redisStore shares the seen keys between file.d instances.
+type redisStore struct {
+	client    redisClient
+	keyPrefix string
+	window    time.Duration
+}
+
+func newRedisStore(client redisClient, keyPrefix string, window time.Duration) *redisStore {
+	return &redisStore{
+		client:    client,
+		keyPrefix: keyPrefix,
+		window:    window,
+	}
+}
+
+func (s *redisStore) isDuplicate(key uint64, _ time.Time) (bool, error) {
+	isNew, err := s.client.SetNX(s.keyPrefix+strconv.FormatUint(key, 16), 1, s.window).Result()
+	if err != nil {
+		return false, err
+	}
+	return !isNew, nil
+}
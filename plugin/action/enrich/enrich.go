@@ -0,0 +1,208 @@
+package enrich
+
+import (
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ozontech/file.d/cfg"
+	"github.com/ozontech/file.d/fd"
+	"github.com/ozontech/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It joins events with a local lookup table loaded from a CSV or JSON file.
+The value of the `field` is used as a key to find the table row, then the row columns are written into the event.
+The file is reloaded when it changes, so the table can be updated without restarting file.d.
+
+Keys can be matched exactly, by the longest prefix or by the most specific CIDR containing an IP address.
+
+**Example of mapping `service_id` to the service owners:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: enrich
+      field: service_id
+      file: /etc/file.d/services.csv
+      columns: [team, owner, tier]
+      defaults:
+        team: unknown
+    ...
+```
+
+`/etc/file.d/services.csv`:
+```
+service_id,team,owner,tier
+checkout,payments,alice,1
+search,discovery,bob,2
+```
+
+The event:
+```json
+{"service_id":"checkout","message":"order is created"}
+```
+
+Will be transformed to:
+```json
+{"service_id":"checkout","message":"order is created","team":"payments","owner":"alice","tier":"1"}
+```
+}*/
+
+type Plugin struct {
+	config *Config
+	logger *zap.Logger
+
+	table          *table
+	defaultColumns []string
+}
+
+// ! config-params
+// ^ config-params
+type Config struct {
+	// > @3@4@5@6
+	// >
+	// > The event field which value is used as a lookup key.
+	Field  cfg.FieldSelector `json:"field" parse:"selector" required:"true"` // *
+	Field_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The path to the lookup table file.
+	File string `json:"file" required:"true"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The format of the lookup table file:
+	// > * `csv` – the first line is a header with column names
+	// > * `json` – an object which maps keys to objects of columns, e.g. `{"checkout":{"team":"payments"}}`
+	Format string `json:"format" default:"csv" options:"csv|json"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The CSV column which holds the keys. If not set, the first column is used.
+	KeyColumn string `json:"key_column"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Defines how the event value is matched with the table keys:
+	// > * `exact` – the value must be equal to the key
+	// > * `prefix` – the longest key which is a prefix of the value is used
+	// > * `cidr` – the keys are CIDR blocks, the most specific block containing the IP address from the value is used
+	KeyType string `json:"key_type" default:"exact" options:"exact|prefix|cidr"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The list of the table columns to write into the event. If empty, all columns are written.
+	Columns []string `json:"columns"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The event object to write the columns into. If not set, the columns are written into the event root.
+	TargetField  cfg.FieldSelector `json:"target_field" parse:"selector"` // *
+	TargetField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The map of `column => value` which is written into the event if the key isn't found in the table.
+	// > Columns without defaults aren't written on a miss.
+	Defaults map[string]string `json:"defaults"` // *
+
+	// > @3@4@5@6
+	// >
+	// > How often to check the file for changes. Zero disables reloading.
+	ReloadInterval  cfg.Duration `json:"reload_interval" parse:"duration" default:"10s"` // *
+	ReloadInterval_ time.Duration
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "enrich",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger.Desugar()
+
+	t, err := acquireTable(&tableConfig{
+		file:           filepath.Clean(p.config.File),
+		format:         p.config.Format,
+		keyColumn:      p.config.KeyColumn,
+		keyType:        p.config.KeyType,
+		reloadInterval: p.config.ReloadInterval_,
+	})
+	if err != nil {
+		p.logger.Fatal("can't load lookup table", zap.String("file", p.config.File), zap.Error(err))
+	}
+	p.table = t
+
+	p.defaultColumns = make([]string, 0, len(p.config.Defaults))
+	for column := range p.config.Defaults {
+		p.defaultColumns = append(p.defaultColumns, column)
+	}
+	sort.Strings(p.defaultColumns)
+}
+
+func (p *Plugin) Stop() {
+	p.table.release()
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.Field_...)
+	if node == nil {
+		p.writeDefaults(event)
+		return pipeline.ActionPass
+	}
+
+	r, ok := p.table.find(node.AsString())
+	if !ok {
+		p.writeDefaults(event)
+		return pipeline.ActionPass
+	}
+
+	columns := p.config.Columns
+	if len(columns) == 0 {
+		columns = p.table.columns()
+	}
+
+	target := p.target(event)
+	for _, column := range columns {
+		value, has := r[column]
+		if !has {
+			value, has = p.config.Defaults[column]
+		}
+		if !has {
+			continue
+		}
+		target.AddFieldNoAlloc(event.Root, column).MutateToString(value)
+	}
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) writeDefaults(event *pipeline.Event) {
+	if len(p.defaultColumns) == 0 {
+		return
+	}
+
+	target := p.target(event)
+	for _, column := range p.defaultColumns {
+		target.AddFieldNoAlloc(event.Root, column).MutateToString(p.config.Defaults[column])
+	}
+}
+
+func (p *Plugin) target(event *pipeline.Event) *insaneJSON.Node {
+	if len(p.config.TargetField_) == 0 {
+		return event.Root.Node
+	}
+	return pipeline.CreateNestedField(event.Root, p.config.TargetField_)
+}
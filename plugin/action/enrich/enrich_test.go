@@ -0,0 +1,217 @@
+package enrich
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/test"
+	"github.com/stretchr/testify/require"
+)
+
+const servicesCSV = `service_id,team,owner,tier
+checkout,payments,alice,1
+search,discovery,bob,2
+`
+
+func writeTable(t *testing.T, name, content string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+	return file
+}
+
+func TestEnrich(t *testing.T) {
+	cases := []struct {
+		name   string
+		file   string
+		table  string
+		config *Config
+		in     []string
+		out    []string
+	}{
+		{
+			name:  "csv_exact",
+			file:  "services.csv",
+			table: servicesCSV,
+			config: &Config{
+				Field:    "service_id",
+				Defaults: map[string]string{"team": "unknown"},
+			},
+			in: []string{
+				`{"service_id":"checkout"}`,
+				`{"service_id":"billing"}`,
+				`{"message":"no key"}`,
+			},
+			out: []string{
+				`{"service_id":"checkout","team":"payments","owner":"alice","tier":"1"}`,
+				`{"service_id":"billing","team":"unknown"}`,
+				`{"message":"no key","team":"unknown"}`,
+			},
+		},
+		{
+			name:  "csv_columns_and_target",
+			file:  "services.csv",
+			table: servicesCSV,
+			config: &Config{
+				Field:       "service_id",
+				Columns:     []string{"team", "tier"},
+				TargetField: "meta.service",
+			},
+			in: []string{
+				`{"service_id":"search"}`,
+			},
+			out: []string{
+				`{"service_id":"search","meta":{"service":{"team":"discovery","tier":"2"}}}`,
+			},
+		},
+		{
+			name:  "csv_key_column",
+			file:  "owners.csv",
+			table: "team,owner\npayments,alice\n",
+			config: &Config{
+				Field:     "owner",
+				KeyColumn: "owner",
+			},
+			in: []string{
+				`{"owner":"alice"}`,
+			},
+			out: []string{
+				`{"owner":"alice","team":"payments"}`,
+			},
+		},
+		{
+			name:  "json_prefix",
+			file:  "pods.json",
+			table: `{"payment-":{"team":"payments"},"payment-api-":{"team":"payments-api","tier":1}}`,
+			config: &Config{
+				Field:   "k8s_pod",
+				Format:  "json",
+				KeyType: "prefix",
+			},
+			in: []string{
+				`{"k8s_pod":"payment-api-abcd"}`,
+				`{"k8s_pod":"payment-worker-abcd"}`,
+				`{"k8s_pod":"search-abcd"}`,
+			},
+			out: []string{
+				`{"k8s_pod":"payment-api-abcd","team":"payments-api","tier":"1"}`,
+				`{"k8s_pod":"payment-worker-abcd","team":"payments"}`,
+				`{"k8s_pod":"search-abcd"}`,
+			},
+		},
+		{
+			name:  "csv_cidr",
+			file:  "networks.csv",
+			table: "network,zone\n10.0.0.0/8,internal\n10.1.0.0/16,office\n2001:db8::/32,ipv6\n",
+			config: &Config{
+				Field:   "ip",
+				KeyType: "cidr",
+			},
+			in: []string{
+				`{"ip":"10.2.3.4"}`,
+				`{"ip":"10.1.3.4"}`,
+				`{"ip":"2001:db8::1"}`,
+				`{"ip":"8.8.8.8"}`,
+				`{"ip":"not an ip"}`,
+			},
+			out: []string{
+				`{"ip":"10.2.3.4","zone":"internal"}`,
+				`{"ip":"10.1.3.4","zone":"office"}`,
+				`{"ip":"2001:db8::1","zone":"ipv6"}`,
+				`{"ip":"8.8.8.8"}`,
+				`{"ip":"not an ip"}`,
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.File = writeTable(t, tt.file, tt.table)
+			config := test.NewConfig(tt.config, nil)
+			p, input, output := test.NewPipelineMock(test.NewActionPluginStaticInfo(factory, config, pipeline.MatchModeAnd, nil, false))
+
+			wg := &sync.WaitGroup{}
+			wg.Add(len(tt.in))
+
+			outEvents := make([]string, 0, len(tt.out))
+			output.SetOutFn(func(e *pipeline.Event) {
+				outEvents = append(outEvents, e.Root.EncodeToString())
+				wg.Done()
+			})
+
+			for _, e := range tt.in {
+				input.In(0, "test.log", 0, []byte(e))
+			}
+
+			wg.Wait()
+			p.Stop()
+
+			require.Equal(t, tt.out, outEvents)
+		})
+	}
+}
+
+func TestTableReload(t *testing.T) {
+	file := writeTable(t, "services.csv", servicesCSV)
+
+	tbl, err := acquireTable(&tableConfig{
+		file:           file,
+		format:         formatCSV,
+		keyType:        keyTypeExact,
+		reloadInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer tbl.release()
+
+	r, ok := tbl.find("checkout")
+	require.True(t, ok)
+	require.Equal(t, "payments", r["team"])
+
+	updated := strings.Replace(servicesCSV, "payments", "billing", 1)
+	require.NoError(t, os.WriteFile(file, []byte(updated), 0o644))
+	// make sure the modification time is changed even on coarse-grained file systems
+	require.NoError(t, os.Chtimes(file, time.Now(), time.Now().Add(time.Second)))
+
+	require.Eventually(t, func() bool {
+		r, _ := tbl.find("checkout")
+		return r["team"] == "billing"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// broken file must not replace the loaded table
+	require.NoError(t, os.WriteFile(file, []byte(`"unterminated`), 0o644))
+	require.NoError(t, os.Chtimes(file, time.Now(), time.Now().Add(2*time.Second)))
+	time.Sleep(50 * time.Millisecond)
+
+	r, ok = tbl.find("checkout")
+	require.True(t, ok)
+	require.Equal(t, "billing", r["team"])
+}
+
+func TestTableShared(t *testing.T) {
+	file := writeTable(t, "services.csv", servicesCSV)
+
+	config := tableConfig{file: file, format: formatCSV, keyType: keyTypeExact}
+	first, err := acquireTable(&config)
+	require.NoError(t, err)
+	defer first.release()
+
+	same := config
+	second, err := acquireTable(&same)
+	require.NoError(t, err)
+	defer second.release()
+	require.Same(t, first, second)
+
+	// the table which is reloaded isn't shared with the one which isn't
+	reloaded := config
+	reloaded.reloadInterval = time.Minute
+	third, err := acquireTable(&reloaded)
+	require.NoError(t, err)
+	defer third.release()
+	require.NotSame(t, first, third)
+}
@@ -0,0 +1,341 @@
+package enrich
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ozontech/file.d/logger"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+)
+
+const (
+	formatCSV  = "csv"
+	formatJSON = "json"
+
+	keyTypeExact  = "exact"
+	keyTypePrefix = "prefix"
+	keyTypeCIDR   = "cidr"
+)
+
+var (
+	// tables are shared across the processors of all pipelines, so let's have a map by table identity
+	tables   = map[string]*table{}
+	tablesMu = &sync.Mutex{}
+)
+
+type row map[string]string
+
+type tableConfig struct {
+	file           string
+	format         string
+	keyColumn      string
+	keyType        string
+	reloadInterval time.Duration
+}
+
+func (c *tableConfig) id() string {
+	return strings.Join([]string{c.file, c.format, c.keyColumn, c.keyType, c.reloadInterval.String()}, "|")
+}
+
+// lookup is an immutable snapshot of the table content.
+type lookup struct {
+	columns []string
+
+	exact    map[string]row
+	prefixes []prefixRow // sorted by prefix length in descending order
+	nets     []netRow    // sorted by prefix bits in descending order
+}
+
+type prefixRow struct {
+	prefix string
+	row    row
+}
+
+type netRow struct {
+	net netip.Prefix
+	row row
+}
+
+func (l *lookup) find(key string) (row, bool) {
+	switch {
+	case l.exact != nil:
+		r, ok := l.exact[key]
+		return r, ok
+	case l.prefixes != nil:
+		for _, p := range l.prefixes {
+			if strings.HasPrefix(key, p.prefix) {
+				return p.row, true
+			}
+		}
+	case l.nets != nil:
+		addr, err := netip.ParseAddr(key)
+		if err != nil {
+			return nil, false
+		}
+		for _, n := range l.nets {
+			if n.net.Contains(addr) {
+				return n.row, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// table holds the lookup loaded from the file and reloads it when the file changes.
+type table struct {
+	config *tableConfig
+	logger *zap.Logger
+
+	data    atomic.Pointer[lookup]
+	modTime time.Time
+
+	refs   int
+	stopCh chan struct{}
+}
+
+// acquireTable returns the shared table for the config, loading it on the first call.
+// The table may outlive the pipeline which loaded it, so it doesn't use the logger of the pipeline.
+func acquireTable(config *tableConfig) (*table, error) {
+	tablesMu.Lock()
+	defer tablesMu.Unlock()
+
+	id := config.id()
+	if t, has := tables[id]; has {
+		t.refs++
+		return t, nil
+	}
+
+	t := &table{
+		config: config,
+		logger: logger.Instance.Named("enrich").Desugar(),
+		stopCh: make(chan struct{}),
+	}
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	if config.reloadInterval > 0 {
+		go t.watch()
+	}
+
+	t.refs = 1
+	tables[id] = t
+	return t, nil
+}
+
+// release stops the watcher when the table isn't used anymore.
+func (t *table) release() {
+	tablesMu.Lock()
+	defer tablesMu.Unlock()
+
+	t.refs--
+	if t.refs > 0 {
+		return
+	}
+	close(t.stopCh)
+	delete(tables, t.config.id())
+}
+
+func (t *table) find(key string) (row, bool) {
+	return t.data.Load().find(key)
+}
+
+func (t *table) columns() []string {
+	return t.data.Load().columns
+}
+
+func (t *table) watch() {
+	ticker := time.NewTicker(t.config.reloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stopCh:
+			return
+		case <-ticker.C:
+			stat, err := os.Stat(t.config.file)
+			if err != nil {
+				t.logger.Error("can't stat lookup table file", zap.String("file", t.config.file), zap.Error(err))
+				continue
+			}
+			if stat.ModTime().Equal(t.modTime) {
+				continue
+			}
+
+			// keep serving the previous content if the new one is broken
+			if err := t.load(); err != nil {
+				t.logger.Error("can't reload lookup table", zap.String("file", t.config.file), zap.Error(err))
+				continue
+			}
+			t.logger.Info("lookup table is reloaded", zap.String("file", t.config.file))
+		}
+	}
+}
+
+func (t *table) load() error {
+	f, err := os.Open(t.config.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	var (
+		columns []string
+		rows    map[string]row
+	)
+	switch t.config.format {
+	case formatJSON:
+		columns, rows, err = readJSON(f)
+	default:
+		columns, rows, err = readCSV(f, t.config.keyColumn)
+	}
+	if err != nil {
+		return err
+	}
+
+	l, err := newLookup(t.config.keyType, columns, rows)
+	if err != nil {
+		return err
+	}
+
+	t.data.Store(l)
+	t.modTime = stat.ModTime()
+	return nil
+}
+
+func newLookup(keyType string, columns []string, rows map[string]row) (*lookup, error) {
+	l := &lookup{columns: columns}
+
+	switch keyType {
+	case keyTypePrefix:
+		l.prefixes = make([]prefixRow, 0, len(rows))
+		for k, r := range rows {
+			l.prefixes = append(l.prefixes, prefixRow{prefix: k, row: r})
+		}
+		sort.Slice(l.prefixes, func(i, j int) bool {
+			return len(l.prefixes[i].prefix) > len(l.prefixes[j].prefix)
+		})
+	case keyTypeCIDR:
+		l.nets = make([]netRow, 0, len(rows))
+		for k, r := range rows {
+			n, err := netip.ParsePrefix(k)
+			if err != nil {
+				return nil, fmt.Errorf("can't parse CIDR key %q: %w", k, err)
+			}
+			l.nets = append(l.nets, netRow{net: n.Masked(), row: r})
+		}
+		sort.Slice(l.nets, func(i, j int) bool {
+			return l.nets[i].net.Bits() > l.nets[j].net.Bits()
+		})
+	default:
+		l.exact = rows
+	}
+
+	return l, nil
+}
+
+// readCSV reads the table with a header. The first column is used as a key if keyColumn is empty.
+func readCSV(r io.Reader, keyColumn string) ([]string, map[string]row, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't read csv header: %w", err)
+	}
+
+	keyIdx := 0
+	if keyColumn != "" {
+		keyIdx = -1
+		for i, name := range header {
+			if name == keyColumn {
+				keyIdx = i
+				break
+			}
+		}
+		if keyIdx == -1 {
+			return nil, nil, fmt.Errorf("key column %q isn't found in csv header", keyColumn)
+		}
+	}
+
+	columns := make([]string, 0, len(header)-1)
+	for i, name := range header {
+		if i != keyIdx {
+			columns = append(columns, name)
+		}
+	}
+
+	rows := make(map[string]row)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("can't read csv record: %w", err)
+		}
+
+		r := make(row, len(record)-1)
+		for i, value := range record {
+			if i != keyIdx {
+				r[header[i]] = value
+			}
+		}
+		rows[record[keyIdx]] = r
+	}
+
+	return columns, rows, nil
+}
+
+// readJSON reads the table from an object which maps keys to objects of columns.
+func readJSON(r io.Reader) ([]string, map[string]row, error) {
+	var raw map[string]map[string]any
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, nil, fmt.Errorf("can't decode json table: %w", err)
+	}
+
+	uniq := make(map[string]struct{})
+	rows := make(map[string]row, len(raw))
+	for key, values := range raw {
+		r := make(row, len(values))
+		for name, value := range values {
+			uniq[name] = struct{}{}
+			switch v := value.(type) {
+			case string:
+				r[name] = v
+			case nil:
+				r[name] = ""
+			default:
+				b, err := json.Marshal(v)
+				if err != nil {
+					return nil, nil, err
+				}
+				r[name] = string(b)
+			}
+		}
+		rows[key] = r
+	}
+
+	columns := make([]string, 0, len(uniq))
+	for name := range uniq {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+
+	return columns, rows, nil
+}
@@ -3,6 +3,7 @@ package join
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -295,3 +296,59 @@ func TestJoinAfterNilNode(t *testing.T) {
 		})
 	}
 }
+
+// the busy join receives the events which don't match its conditions,
+// since the continuation lines usually don't have the fields of the first line
+func TestJoinBusyIgnoresConditions(t *testing.T) {
+	config := test.NewConfig(&Config{
+		Field:    "log",
+		Start:    cfg.Regexp(`/^start/`),
+		Continue: cfg.Regexp(`/^ /`),
+	}, nil)
+
+	p, input, output := test.NewPipelineMock(
+		test.NewActionPluginStaticInfo(
+			factory,
+			config,
+			pipeline.MatchModeAnd,
+			pipeline.MatchConditions{{Field: []string{"kind"}, Values: []string{"trace"}}},
+			false,
+		),
+		"short_event_timeout",
+	)
+
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	output.SetOutFn(func(e *pipeline.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e.Root.EncodeToString())
+	})
+
+	lines := []string{
+		`{"kind":"trace","log":"start 1"}`,
+		`{"log":" at a"}`,
+		`{"log":" at b"}`,
+		`{"kind":"trace","log":"start 2"}`,
+		`{"log":" at c"}`,
+		`{"other":"x"}`,
+	}
+	for i, line := range lines {
+		input.In(0, "test.log", int64(i+1), []byte(line))
+	}
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(events) == 3
+	}, 10*time.Second, 10*time.Millisecond)
+	p.Stop()
+
+	require.Equal(t, []string{
+		`{"kind":"trace","log":"start 1 at a at b"}`,
+		`{"kind":"trace","log":"start 2 at c"}`,
+		`{"other":"x"}`,
+	}, events)
+}
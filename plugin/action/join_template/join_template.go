@@ -1,6 +1,8 @@
 package join_template
 
 import (
+	"regexp"
+
 	"github.com/ozontech/file.d/cfg"
 	"github.com/ozontech/file.d/fd"
 	"github.com/ozontech/file.d/logger"
@@ -11,6 +13,17 @@ import (
 /*{ introduction
 Alias to "join" plugin with predefined `start` and `continue` parameters.
 
+Available templates:
+* `go_panic` – Go panics and fatal errors.
+* `python_traceback` – Python tracebacks, the chained tracebacks are joined separately.
+* `java_exception` – Java exceptions with the `Caused by` and `Suppressed` sections.
+* `dotnet_exception` – .NET exceptions with the inner exceptions.
+* `ruby_exception` – Ruby exceptions.
+* `nodejs_error` – Node.js errors with the causes.
+* `auto` – the template is detected per stream by the first event which matches the `start` regexp of a template,
+the templates are checked in the order of the list above. The events of the stream pass the plugin as is until the template is detected.
+The streams are detected by each pipeline processor separately.
+
 > ⚠ Parsing the whole event flow could be very CPU intensive because the plugin uses regular expressions.
 > Consider `match_fields` parameter to process only particular events. Check out an example for details.
 
@@ -27,26 +40,49 @@ pipelines:
         stream: stderr // apply only for events which was written to stderr to save CPU time
     ...
 ```
-}*/
-
-type joinTemplates map[string]struct {
-	startRePat    string
-	continueRePat string
-}
 
-var templates = joinTemplates{
-	"go_panic": {
-		startRePat:    "/^(panic:)|(http: panic serving)|^(fatal error:)/",
-		continueRePat: "/(^\\s*$)|(goroutine [0-9]+ \\[)|(\\.go:[0-9]+)|(created by .*\\/?.*\\.)|(^\\[signal)|(panic.+[0-9]x[0-9,a-f]+)|(panic:)|([A-Za-z_]+[A-Za-z0-9_]*\\)?\\.[A-Za-z0-9_]+\\(.*\\))/",
-	},
-}
+**Example of joining the stack traces of any runtime**:
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: join_template
+      template: auto
+      field: log
+    ...
+```
+}*/
 
 type Plugin struct {
 	config *Config
+	params *pipeline.ActionPluginParams
 
 	jp *join.Plugin
+
+	// the fields are used in the auto mode
+	templates  []autoTemplate
+	streams    map[pipeline.SourceID]map[string]*streamTemplate
+	streamsCnt int
+	joining    *join.Plugin
+}
+
+type autoTemplate struct {
+	startRe *regexp.Regexp
+	jp      *join.Plugin
 }
 
+// streamTemplate is the template detected for the stream, jp is nil until the template is detected.
+type streamTemplate struct {
+	jp       *join.Plugin
+	lines    int
+	detected bool
+}
+
+// maxAutoStreams limits the number of the streams which templates are remembered in the auto mode,
+// the streams are detected again after the limit is reached.
+const maxAutoStreams = 65536
+
 // ! config-params
 // ^ config-params
 type Config struct {
@@ -63,8 +99,15 @@ type Config struct {
 
 	// > @3@4@5@6
 	// >
-	// > The name of the template. Available templates: `go_panic`.
+	// > The name of the template. Available templates: `go_panic`, `java_exception`, `python_traceback`,
+	// > `dotnet_exception`, `ruby_exception`, `nodejs_error`, `auto`.
 	Template string `json:"template" required:"true"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The number of the first events of the stream to detect the template by in the `auto` mode.
+	// > If no template is detected, the events of the stream aren't joined. `0` means no limit.
+	DetectLines int `json:"detect_lines" default:"1000"` // *
 }
 
 func init() {
@@ -80,20 +123,39 @@ func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
 
 func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
 	p.config = config.(*Config)
+	p.params = params
+
+	if p.config.DetectLines < 0 {
+		logger.Fatalf("detect_lines must be >= 0, got %d", p.config.DetectLines)
+	}
 
 	templateName := p.config.Template
-	template, ok := templates[templateName]
-	if !ok {
-		logger.Fatalf("join template \"%s\" not found", templateName)
+	if templateName == templateAuto {
+		p.templates = make([]autoTemplate, 0, len(templates))
+		for _, template := range templates {
+			p.templates = append(p.templates, p.startJoin(template))
+		}
+		p.streams = make(map[pipeline.SourceID]map[string]*streamTemplate)
+		return
 	}
 
+	for _, template := range templates {
+		if template.name == templateName {
+			p.jp = p.startJoin(template).jp
+			return
+		}
+	}
+	logger.Fatalf("join template \"%s\" not found", templateName)
+}
+
+func (p *Plugin) startJoin(template joinTemplate) autoTemplate {
 	startRe, err := cfg.CompileRegex(template.startRePat)
 	if err != nil {
-		logger.Fatalf("failed to compile regex for template \"%s\": %s", templateName, err.Error())
+		logger.Fatalf("failed to compile regex for template \"%s\": %s", template.name, err.Error())
 	}
 	continueRe, err := cfg.CompileRegex(template.continueRePat)
 	if err != nil {
-		logger.Fatalf("failed to compile regex for template \"%s\": %s", templateName, err.Error())
+		logger.Fatalf("failed to compile regex for template \"%s\": %s", template.name, err.Error())
 	}
 
 	jConfig := &join.Config{
@@ -102,14 +164,102 @@ func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginP
 		Start_:       startRe,
 		Continue_:    continueRe,
 	}
-	p.jp = &join.Plugin{}
-	p.jp.Start(jConfig, params)
+	jp := &join.Plugin{}
+	jp.Start(jConfig, p.params)
+
+	return autoTemplate{
+		startRe: startRe,
+		jp:      jp,
+	}
 }
 
 func (p *Plugin) Stop() {
-	p.jp.Stop()
+	if p.jp != nil {
+		p.jp.Stop()
+	}
+	for _, template := range p.templates {
+		template.jp.Stop()
+	}
 }
 
 func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
-	return p.jp.Do(event)
+	if p.jp != nil {
+		return p.jp.Do(event)
+	}
+	return p.doAuto(event)
+}
+
+// doAuto relies on the events of the joined sequence to come from the same stream,
+// so only one template can be joining at a time.
+func (p *Plugin) doAuto(event *pipeline.Event) pipeline.ActionResult {
+	if event.IsTimeoutKind() {
+		jp := p.joining
+		if jp == nil {
+			// nothing is held, so there is nothing to flush
+			return pipeline.ActionPass
+		}
+		p.joining = nil
+		return jp.Do(event)
+	}
+
+	jp := p.detect(event)
+	if jp == nil {
+		// the joining sequence is finished by the template which started it
+		jp = p.joining
+	}
+	if jp == nil {
+		return pipeline.ActionPass
+	}
+
+	result := jp.Do(event)
+	switch result {
+	case pipeline.ActionHold, pipeline.ActionCollapse:
+		p.joining = jp
+	default:
+		p.joining = nil
+	}
+	return result
+}
+
+func (p *Plugin) detect(event *pipeline.Event) *join.Plugin {
+	names, ok := p.streams[event.SourceID]
+	if !ok {
+		names = make(map[string]*streamTemplate)
+		p.streams[event.SourceID] = names
+	}
+	st, ok := names[string(event.StreamNameBytes())]
+	if !ok {
+		if p.streamsCnt >= maxAutoStreams {
+			clear(p.streams)
+			p.streamsCnt = 0
+			names = make(map[string]*streamTemplate)
+			p.streams[event.SourceID] = names
+		}
+		st = &streamTemplate{}
+		names[string(event.StreamNameBytes())] = st
+		p.streamsCnt++
+	}
+	if st.detected {
+		return st.jp
+	}
+
+	node := event.Root.Dig(p.config.Field_...)
+	if node == nil || !node.IsString() {
+		return nil
+	}
+
+	st.lines++
+	value := node.AsString()
+	for _, template := range p.templates {
+		if template.startRe.MatchString(value) {
+			st.jp = template.jp
+			st.detected = true
+			return template.jp
+		}
+	}
+
+	if p.config.DetectLines > 0 && st.lines >= p.config.DetectLines {
+		st.detected = true
+	}
+	return nil
 }
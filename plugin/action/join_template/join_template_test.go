@@ -1,8 +1,10 @@
 package join_template
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -563,3 +565,191 @@ func TestJoinAfterNilNode(t *testing.T) {
 		})
 	}
 }
+
+// runtimeEvents are the events of the runtimes, each event is the list of the lines which must be joined.
+var runtimeEvents = map[string][][]string{
+	"java_exception": {
+		{"started in 1.5s"},
+		{
+			`Exception in thread "main" java.lang.IllegalStateException: A book has a null property`,
+			"\tat com.example.myproject.Author.getBookIds(Author.java:38)",
+			"\tat com.example.myproject.Bootstrap.main(Bootstrap.java:14)",
+			"Caused by: java.lang.NullPointerException",
+			"\tat com.example.myproject.Book.getId(Book.java:22)",
+			"\t... 1 more",
+		},
+		{
+			"org.springframework.web.client.ResourceAccessException: I/O error on GET request",
+			"\tat org.springframework.web.client.RestTemplate.doExecute(RestTemplate.java:785)",
+			"\tSuppressed: java.io.IOException: close failed",
+			"\t\tat java.base/java.io.FileInputStream.close(FileInputStream.java:356)",
+			"\t... 3 common frames omitted",
+		},
+		{"request is handled"},
+	},
+	"python_traceback": {
+		{"Starting worker"},
+		{
+			"Traceback (most recent call last):",
+			`  File "/app/main.py", line 10, in <module>`,
+			"    main()",
+			`  File "/app/main.py", line 6, in main`,
+			`    raise KeyError("user")`,
+			"KeyError: 'user'",
+			"",
+			"During handling of the above exception, another exception occurred:",
+			"",
+		},
+		{
+			"Traceback (most recent call last):",
+			`  File "/app/main.py", line 12, in <module>`,
+			"    sys.exit(1)",
+			"SystemExit: 1",
+		},
+		{"worker stopped"},
+	},
+	"dotnet_exception": {
+		{"Application started."},
+		{
+			"Unhandled exception. System.InvalidOperationException: Sequence contains no elements",
+			" ---> System.ArgumentNullException: Value cannot be null. (Parameter 'source')",
+			"   at System.Linq.ThrowHelper.ThrowArgumentNullException(ExceptionArgument argument)",
+			"   --- End of inner exception stack trace ---",
+			"   at MyApp.Services.OrderService.GetLatest() in /src/MyApp/Services/OrderService.cs:line 42",
+			"--- End of stack trace from previous location ---",
+		},
+		{
+			"MyApp.Exceptions.NotFoundException: Order 42 is not found",
+			"   at MyApp.Controllers.OrdersController.Get(Int32 id) in /src/MyApp/Controllers/OrdersController.cs:line 18",
+		},
+		{"Application is shutting down..."},
+	},
+	"ruby_exception": {
+		{"Puma starting in single mode..."},
+		{
+			"app.rb:3:in `divide': divided by 0 (ZeroDivisionError)",
+			"\tfrom app.rb:3:in `/'",
+			"\tfrom app.rb:7:in `<main>'",
+		},
+		{
+			"/app/lib/client.rb:15:in 'Client#fetch': connection refused (Net::OpenTimeout)",
+			"\tfrom /app/lib/client.rb:8:in 'Client#call'",
+			"\t ... 12 levels...",
+		},
+		{"Completed 200 OK in 5ms"},
+	},
+	"nodejs_error": {
+		{"Server listening on port 3000"},
+		{
+			"TypeError: Cannot read properties of undefined (reading 'id')",
+			"    at getUser (/app/src/users.js:12:20)",
+			"    at process.processTicksAndRejections (node:internal/process/task_queues:95:5)",
+		},
+		{
+			"Error [ERR_HTTP_HEADERS_SENT]: Cannot set headers after they are sent to the client",
+			"    at ServerResponse.setHeader (node:_http_outgoing:603:11)",
+			"  [cause]: Error: socket hang up",
+			"      at connResetException (node:internal/errors:720:14)",
+			"    ... 4 lines matching cause stack trace ...",
+		},
+		{"GET /health 200"},
+	},
+}
+
+func TestTemplates(t *testing.T) {
+	for template, events := range runtimeEvents {
+		t.Run(template, func(t *testing.T) {
+			out := joinEvents(t, template, map[pipeline.SourceID][][]string{0: events})
+			require.Equal(t, joinedEvents(events), out[0])
+		})
+	}
+}
+
+func TestAutoTemplate(t *testing.T) {
+	in := make(map[pipeline.SourceID][][]string)
+	expected := make(map[pipeline.SourceID][]string)
+
+	sourceID := pipeline.SourceID(0)
+	for _, events := range runtimeEvents {
+		in[sourceID] = events
+		expected[sourceID] = joinedEvents(events)
+		sourceID++
+	}
+
+	// the template isn't detected by the first lines, so the lines aren't joined
+	in[sourceID] = [][]string{{"GET /health 200"}, {"GET /health 200"}, {"TypeError: x"}, {"    at main (/app/index.js:1:1)"}}
+	expected[sourceID] = joinedEvents(in[sourceID])
+
+	require.Equal(t, expected, joinEvents(t, "auto", in, func(config *Config) {
+		config.DetectLines = 2
+	}))
+}
+
+func TestAutoTemplateTimeoutWithoutJoining(t *testing.T) {
+	plugin := &Plugin{}
+	plugin.Start(test.NewConfig(&Config{Field: "log", Template: "auto"}, nil), test.NewEmptyActionPluginParams())
+	defer plugin.Stop()
+
+	// the timeout may come when no sequence is joined
+	event := &pipeline.Event{}
+	event.SetTimeoutKind()
+	require.Equal(t, pipeline.ActionPass, plugin.Do(event))
+}
+
+func joinedEvents(events [][]string) []string {
+	joined := make([]string, 0, len(events))
+	for _, lines := range events {
+		joined = append(joined, strings.Join(lines, "\n")+"\n")
+	}
+	return joined
+}
+
+// joinEvents passes the lines of the sources through the plugin and returns the joined events of the sources.
+func joinEvents(t *testing.T, template string, in map[pipeline.SourceID][][]string, opts ...func(config *Config)) map[pipeline.SourceID][]string {
+	t.Helper()
+
+	config := &Config{
+		Field:    "log",
+		Template: template,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	p, input, output := test.NewPipelineMock(
+		test.NewActionPluginStaticInfo(factory, test.NewConfig(config, nil), pipeline.MatchModeAnd, nil, false),
+		"short_event_timeout",
+	)
+
+	mu := sync.Mutex{}
+	out := make(map[pipeline.SourceID][]string)
+	outEvents := atomic.Int32{}
+	output.SetOutFn(func(e *pipeline.Event) {
+		mu.Lock()
+		out[e.SourceID] = append(out[e.SourceID], e.Root.Dig("log").AsString())
+		mu.Unlock()
+		outEvents.Inc()
+	})
+
+	expEvents := int32(0)
+	for sourceID, events := range in {
+		offset := int64(0)
+		for _, lines := range events {
+			for _, line := range lines {
+				event, err := json.Marshal(map[string]string{"log": line + "\n"})
+				require.NoError(t, err)
+				input.In(sourceID, "test.log", offset, event)
+				offset++
+			}
+		}
+		expEvents += int32(len(events))
+	}
+
+	for i := 0; i < 100 && outEvents.Load() < expEvents; i++ {
+		time.Sleep(time.Millisecond * 100)
+	}
+	p.Stop()
+
+	require.Equal(t, expEvents, outEvents.Load(), "wrong out events count")
+	return out
+}
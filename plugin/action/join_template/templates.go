@@ -0,0 +1,48 @@
+package join_template
+
+type joinTemplate struct {
+	name          string
+	startRePat    string
+	continueRePat string
+}
+
+const templateAuto = "auto"
+
+// templates are checked by the auto detection in the order of the list,
+// so the templates with more specific start regexps go first.
+var templates = []joinTemplate{
+	{
+		name:          "go_panic",
+		startRePat:    "/^(panic:)|(http: panic serving)|^(fatal error:)/",
+		continueRePat: "/(^\\s*$)|(goroutine [0-9]+ \\[)|(\\.go:[0-9]+)|(created by .*\\/?.*\\.)|(^\\[signal)|(panic.+[0-9]x[0-9,a-f]+)|(panic:)|([A-Za-z_]+[A-Za-z0-9_]*\\)?\\.[A-Za-z0-9_]+\\(.*\\))/",
+	},
+	{
+		// the chained tracebacks are joined separately since each of them starts with the "Traceback" line
+		name:       "python_traceback",
+		startRePat: `/^Traceback \(most recent call last\):\s*$/`,
+		continueRePat: `/(^\s+)|(^([A-Za-z_]\w*\.)*[A-Za-z_]\w*(Error|Exception|Warning|Exit|Interrupt|Iteration)(: .*)?\s*$)|` +
+			`(^During handling of the above exception, another exception occurred:\s*$)|` +
+			`(^The above exception was the direct cause of the following exception:\s*$)|(^\s*$)/`,
+	},
+	{
+		// java packages are lowercase, which distinguishes the exceptions from the .NET ones
+		name:          "java_exception",
+		startRePat:    `/^(Exception in thread "[^"]*" )?([a-z_$][\w$]*\.)+[A-Za-z_$][\w$]*(Exception|Error|Throwable)(: .*)?\s*$/`,
+		continueRePat: `/(^\s+at \S+\(.*\)\s*$)|(^\s+\.\.\. \d+ (more|common frames omitted)\s*$)|(^\s*Caused by: )|(^\s*Suppressed: )/`,
+	},
+	{
+		name:          "dotnet_exception",
+		startRePat:    `/^(Unhandled [Ee]xception\. )?([A-Z]\w*\.)+[A-Z]\w*Exception(: .*)?\s*$/`,
+		continueRePat: `/(^\s+at \S+)|(^\s+---> )|(^\s*--- End of )/`,
+	},
+	{
+		name:          "ruby_exception",
+		startRePat:    `/^\S+:\d+:in [\x60'][^']*': .* \(([A-Z]\w*(::)?)+\)\s*$/`,
+		continueRePat: `/(^\s+from \S+:\d+:in )|(^\s+\.\.\. \d+ levels\.\.\.\s*$)/`,
+	},
+	{
+		name:          "nodejs_error",
+		startRePat:    `/^(Uncaught )?([A-Z]\w*)?(Error|Exception)( \[\w+\])?(: .*)?\s*$/`,
+		continueRePat: `/(^\s+at \S+)|(^\s+\.\.\. \d+ lines? matching cause stack trace \.\.\.\s*$)|(^\s*\[cause\]: )/`,
+	},
+}
@@ -0,0 +1,248 @@
+package log_pattern
+
+import (
+	"container/list"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+const wildcard = "<*>"
+
+type cluster struct {
+	id     string
+	tokens []string
+	count  int64
+
+	// leaf and elem are used to evict the cluster
+	leaf *treeNode
+	elem *list.Element
+}
+
+func (c *cluster) template() string {
+	return strings.Join(c.tokens, " ")
+}
+
+type treeNode struct {
+	children map[string]*treeNode
+	clusters []*cluster
+}
+
+func newTreeNode() *treeNode {
+	return &treeNode{children: make(map[string]*treeNode)}
+}
+
+// drain is the online log parser described in the paper
+// "Drain: An Online Log Parsing Approach with Fixed Depth Tree".
+// The messages are routed by the number of the tokens and by the first tokens to the leaf,
+// then the most similar cluster of the leaf is chosen or the new one is created.
+type drain struct {
+	depth       int
+	simTh       float64
+	maxChildren int
+	maxClusters int
+
+	mu   sync.Mutex
+	root *treeNode
+	// lru holds the clusters from the least to the most recently matched ones
+	lru *list.List
+}
+
+func newDrain(depth int, simTh float64, maxChildren, maxClusters int) *drain {
+	return &drain{
+		depth:       depth,
+		simTh:       simTh,
+		maxChildren: maxChildren,
+		maxClusters: maxClusters,
+		root:        newTreeNode(),
+		lru:         list.New(),
+	}
+}
+
+// match returns the id and the template of the cluster of the message.
+func (d *drain) match(message string) (string, string) {
+	tokens := tokenize(message)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	leaf := d.leaf(tokens)
+	c := d.bestCluster(leaf, tokens)
+	if c == nil {
+		c = d.addCluster(leaf, tokens)
+	} else {
+		for i, token := range tokens {
+			if c.tokens[i] != token {
+				c.tokens[i] = wildcard
+			}
+		}
+		d.lru.MoveToBack(c.elem)
+	}
+	c.count++
+
+	return c.id, c.template()
+}
+
+// leaf finds the leaf of the tokens and creates the missing nodes on the path.
+func (d *drain) leaf(tokens []string) *treeNode {
+	node := d.child(d.root, strconv.Itoa(len(tokens)), true)
+
+	// the root, the length and the leaf layers are the part of the depth
+	for i := 0; i < d.depth-3 && i < len(tokens); i++ {
+		node = d.child(node, tokens[i], false)
+	}
+	return node
+}
+
+func (d *drain) child(node *treeNode, token string, unlimited bool) *treeNode {
+	if child, has := node.children[token]; has {
+		return child
+	}
+	if !unlimited && token != wildcard && len(node.children) >= d.maxChildren-1 {
+		token = wildcard
+		if child, has := node.children[token]; has {
+			return child
+		}
+	}
+
+	child := newTreeNode()
+	node.children[strings.Clone(token)] = child
+	return child
+}
+
+func (d *drain) bestCluster(leaf *treeNode, tokens []string) *cluster {
+	var (
+		best       *cluster
+		bestSim    = -1.0
+		bestParams = -1
+	)
+	for _, c := range leaf.clusters {
+		sim, params := similarity(c.tokens, tokens)
+		if sim > bestSim || (sim == bestSim && params > bestParams) {
+			best, bestSim, bestParams = c, sim, params
+		}
+	}
+	if best == nil || bestSim < d.simTh {
+		return nil
+	}
+	return best
+}
+
+func (d *drain) addCluster(leaf *treeNode, tokens []string) *cluster {
+	if d.lru.Len() >= d.maxClusters {
+		d.evict()
+	}
+
+	// the tokens may reference the event
+	for i, token := range tokens {
+		tokens[i] = strings.Clone(token)
+	}
+	c := &cluster{
+		id:     clusterID(tokens),
+		tokens: tokens,
+		leaf:   leaf,
+	}
+	c.elem = d.lru.PushBack(c)
+	leaf.clusters = append(leaf.clusters, c)
+	return c
+}
+
+func (d *drain) evict() {
+	c := d.lru.Remove(d.lru.Front()).(*cluster)
+	clusters := c.leaf.clusters
+	for i := range clusters {
+		if clusters[i] == c {
+			clusters[i] = clusters[len(clusters)-1]
+			clusters[len(clusters)-1] = nil
+			c.leaf.clusters = clusters[:len(clusters)-1]
+			break
+		}
+	}
+}
+
+type pattern struct {
+	ID       string `json:"pattern_id"`
+	Template string `json:"template"`
+	Count    int64  `json:"count"`
+}
+
+// top returns n clusters with the most events.
+func (d *drain) top(n int) []pattern {
+	d.mu.Lock()
+	patterns := make([]pattern, 0, d.lru.Len())
+	for e := d.lru.Front(); e != nil; e = e.Next() {
+		c := e.Value.(*cluster)
+		patterns = append(patterns, pattern{
+			ID:       c.id,
+			Template: c.template(),
+			Count:    c.count,
+		})
+	}
+	d.mu.Unlock()
+
+	sort.Slice(patterns, func(i, j int) bool {
+		if patterns[i].Count != patterns[j].Count {
+			return patterns[i].Count > patterns[j].Count
+		}
+		return patterns[i].ID < patterns[j].ID
+	})
+	if n > 0 && len(patterns) > n {
+		patterns = patterns[:n]
+	}
+	return patterns
+}
+
+// similarity returns the share of the equal tokens and the number of the wildcards in the template.
+func similarity(template, tokens []string) (float64, int) {
+	if len(template) == 0 {
+		return 1, 0
+	}
+
+	equal, params := 0, 0
+	for i, token := range template {
+		if token == wildcard {
+			params++
+			continue
+		}
+		if token == tokens[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(template)), params
+}
+
+// tokenize splits the message by the whitespaces, the tokens with the digits are replaced with the wildcard
+// since they are the variables in the most cases, e.g. the ids, the durations and the addresses.
+func tokenize(message string) []string {
+	tokens := strings.Fields(message)
+	for i, token := range tokens {
+		if hasDigit(token) {
+			tokens[i] = wildcard
+		}
+	}
+	return tokens
+}
+
+func hasDigit(s string) bool {
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterID is the hash of the first template of the cluster,
+// so the same message creates the cluster with the same id after the restart.
+func clusterID(tokens []string) string {
+	h := xxhash.New()
+	for _, token := range tokens {
+		_, _ = h.WriteString(token)
+		_, _ = h.Write([]byte{' '})
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
@@ -0,0 +1,152 @@
+package log_pattern
+
+import (
+	"github.com/ozontech/file.d/cfg"
+	"github.com/ozontech/file.d/fd"
+	"github.com/ozontech/file.d/pipeline"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It clusters the messages of the events by their patterns with the [Drain](https://jiemingzhu.github.io/pub/pjhe_icws2017.pdf) algorithm
+and sets the id and the template of the pattern to the event.
+E.g. the messages `user 42 logged in from 10.0.0.1` and `user admin logged in from 10.0.0.2`
+have the pattern `user <*> logged in from <*>`.
+
+It helps to find the noisiest message types and to throttle the events by the pattern with the `throttle` plugin.
+
+The messages are split into the tokens by the whitespaces, the tokens with the digits are considered to be the variables.
+The messages with the different number of the tokens always have the different patterns.
+The template of the pattern is generalized as the new messages come, but the id of the pattern stays the same.
+The id is the hash of the first message of the pattern, so it's the same after file.d restart if the first message is the same.
+
+The patterns are shared by all the pipeline processors, they are lost on file.d restart.
+
+The top patterns are available on the `patterns` endpoint of the action, e.g. `/pipelines/example_pipeline/1/patterns?limit=10`.
+The endpoint returns the patterns of all `log_pattern` actions of the pipeline:
+```json
+[{"field":"message","patterns":[{"pattern_id":"8d2f1b0c5a4e3f21","template":"user <*> logged in from <*>","count":1024}]}]
+```
+
+The events without the string `field` pass the plugin as is.
+
+**Example of throttling by the pattern:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: log_pattern
+      field: message
+    - type: throttle
+      throttle_field: pattern_id
+      default_limit: 1000
+    ...
+```
+}*/
+
+type Plugin struct {
+	config *Config
+	logger *zap.Logger
+
+	drain *drain
+}
+
+// ! config-params
+// ^ config-params
+type Config struct {
+	// > @3@4@5@6
+	// >
+	// > The event field with the message to cluster.
+	Field  cfg.FieldSelector `json:"field" default:"message" parse:"selector"` // *
+	Field_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The event field to put the id of the pattern into.
+	PatternIDField  cfg.FieldSelector `json:"pattern_id_field" default:"pattern_id" parse:"selector"` // *
+	PatternIDField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The event field to put the template of the pattern into.
+	TemplateField  cfg.FieldSelector `json:"template_field" default:"pattern" parse:"selector"` // *
+	TemplateField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The depth of the parse tree, the first `depth - 3` tokens of the messages route them to the patterns.
+	// > The messages which differ in the first tokens never have the same pattern.
+	Depth int `json:"depth" default:"4"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The minimal share of the equal tokens of the message and the pattern to match them.
+	SimilarityThreshold float64 `json:"similarity_threshold" default:"0.4"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The maximum number of the children of the parse tree node.
+	// > The tokens above the limit are routed as the variables.
+	MaxChildren int `json:"max_children" default:"100"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The maximum number of the patterns. The least recently matched patterns are evicted above the limit.
+	MaxPatterns int `json:"max_patterns" default:"1000"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The default number of the patterns which the `patterns` endpoint returns.
+	TopN int `json:"top_n" default:"100"` // *
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:      "log_pattern",
+		Factory:   factory,
+		Endpoints: endpoints,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger.Desugar()
+
+	if p.config.Depth < 3 {
+		p.logger.Fatal("depth must be >= 3", zap.Int("depth", p.config.Depth))
+	}
+	if p.config.SimilarityThreshold < 0 || p.config.SimilarityThreshold > 1 {
+		p.logger.Fatal("similarity_threshold must be in [0, 1]", zap.Float64("similarity_threshold", p.config.SimilarityThreshold))
+	}
+	if p.config.MaxChildren < 2 {
+		p.logger.Fatal("max_children must be >= 2", zap.Int("max_children", p.config.MaxChildren))
+	}
+	if p.config.MaxPatterns <= 0 {
+		p.logger.Fatal("max_patterns must be > 0", zap.Int("max_patterns", p.config.MaxPatterns))
+	}
+
+	p.drain = acquireDrain(params.PipelineName, p.config)
+}
+
+func (p *Plugin) Stop() {
+	releaseDrain(p.config)
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.Field_...)
+	if node == nil || !node.IsString() {
+		return pipeline.ActionPass
+	}
+
+	id, template := p.drain.match(node.AsString())
+
+	pipeline.CreateNestedField(event.Root, p.config.PatternIDField_).MutateToString(id)
+	pipeline.CreateNestedField(event.Root, p.config.TemplateField_).MutateToString(template)
+
+	return pipeline.ActionPass
+}
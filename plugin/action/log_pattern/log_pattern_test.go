@@ -0,0 +1,147 @@
+package log_pattern
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/test"
+	"github.com/stretchr/testify/require"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+func TestDrain(t *testing.T) {
+	d := newDrain(4, 0.4, 100, 1000)
+
+	loginID, loginTemplate := d.match("user 42 logged in from 10.0.0.1")
+	require.Equal(t, "user <*> logged in from <*>", loginTemplate)
+
+	id, template := d.match("user admin logged in from 10.0.0.2")
+	require.Equal(t, loginID, id, "the id of the pattern must be stable")
+	require.Equal(t, "user <*> logged in from <*>", template)
+
+	// the first token routes the message
+	id, template = d.match("admin user logged in from 10.0.0.2")
+	require.NotEqual(t, loginID, id)
+	require.Equal(t, "admin user logged in from <*>", template)
+
+	// the number of the tokens routes the message
+	id, _ = d.match("user 42 logged in")
+	require.NotEqual(t, loginID, id)
+
+	// the message isn't similar enough
+	_, template = d.match("user 42 failed to connect to db")
+	require.Equal(t, "user <*> failed to connect to db", template)
+	id, template = d.match("user bob logged out with status ok")
+	require.NotEqual(t, loginID, id)
+	require.Equal(t, "user bob logged out with status ok", template)
+
+	_, template = d.match("")
+	require.Equal(t, "", template)
+
+	top := d.top(2)
+	require.Equal(t, []pattern{{ID: loginID, Template: loginTemplate, Count: 2}}, top[:1])
+	require.Len(t, top, 2)
+	require.Len(t, d.top(0), 6)
+}
+
+func TestDrainSameIDAfterRestart(t *testing.T) {
+	id1, _ := newDrain(4, 0.4, 100, 1000).match("request 1 done in 5ms")
+	id2, _ := newDrain(4, 0.4, 100, 1000).match("request 2 done in 7ms")
+	require.Equal(t, id1, id2)
+}
+
+func TestDrainMaxChildren(t *testing.T) {
+	d := newDrain(4, 0.4, 2, 1000)
+
+	_, template := d.match("first message")
+	require.Equal(t, "first message", template)
+
+	// the first token above the limit is routed as the variable
+	_, template = d.match("second message")
+	require.Equal(t, "second message", template)
+	_, template = d.match("third message")
+	require.Equal(t, "<*> message", template)
+}
+
+func TestDrainMaxPatterns(t *testing.T) {
+	d := newDrain(4, 0.4, 100, 2)
+
+	aID, _ := d.match("a")
+	bID, _ := d.match("b")
+	d.match("a")
+	d.match("c")
+
+	top := d.top(0)
+	require.Len(t, top, 2)
+	for _, p := range top {
+		require.NotEqual(t, bID, p.ID, "the least recently matched pattern must be evicted")
+	}
+	require.Equal(t, aID, top[0].ID)
+}
+
+func TestLogPattern(t *testing.T) {
+	config := test.NewConfig(&Config{
+		Field:          "log.message",
+		PatternIDField: "pattern.id",
+		TemplateField:  "pattern.template",
+	}, nil)
+
+	params := test.NewEmptyActionPluginParams()
+	params.PipelineName = "test_log_pattern"
+
+	plugin := &Plugin{}
+	plugin.Start(config, params)
+	defer plugin.Stop()
+
+	// the plugin instances of the processors share the patterns
+	plugin2 := &Plugin{}
+	plugin2.Start(config, params)
+	defer plugin2.Stop()
+
+	do := func(plugin *Plugin, e string) string {
+		root, err := insaneJSON.DecodeString(e)
+		require.NoError(t, err)
+		defer insaneJSON.Release(root)
+
+		require.Equal(t, pipeline.ActionPass, plugin.Do(&pipeline.Event{Root: root}))
+		return root.EncodeToString()
+	}
+
+	out := do(plugin, `{"log":{"message":"job 1 is done"}}`)
+	var event struct {
+		Pattern struct {
+			ID       string `json:"id"`
+			Template string `json:"template"`
+		} `json:"pattern"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(out), &event))
+	require.NotEmpty(t, event.Pattern.ID)
+	require.Equal(t, "job <*> is done", event.Pattern.Template)
+
+	out = do(plugin2, `{"log":{"message":"job export is done"}}`)
+	require.Equal(t,
+		`{"log":{"message":"job export is done"},"pattern":{"id":"`+event.Pattern.ID+`","template":"job <*> is done"}}`,
+		out,
+	)
+
+	require.Equal(t, `{"log":{"message":1}}`, do(plugin, `{"log":{"message":1}}`))
+
+	rec := httptest.NewRecorder()
+	servePatterns(rec, httptest.NewRequest(http.MethodGet, "/pipelines/test_log_pattern/1/patterns?limit=1", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t,
+		`[{"field":"log.message","patterns":[{"pattern_id":"`+event.Pattern.ID+`","template":"job <*> is done","count":2}]}]`,
+		rec.Body.String(),
+	)
+
+	rec = httptest.NewRecorder()
+	servePatterns(rec, httptest.NewRequest(http.MethodGet, "/pipelines/unknown/1/patterns", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	servePatterns(rec, httptest.NewRequest(http.MethodGet, "/pipelines/test_log_pattern/1/patterns?limit=x", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,122 @@
+package log_pattern
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var endpoints = map[string]func(http.ResponseWriter, *http.Request){
+	"patterns": servePatterns,
+}
+
+type sharedDrain struct {
+	drain  *drain
+	config *Config
+	refs   int
+}
+
+var (
+	drainsMu = &sync.Mutex{}
+	// drains are shared by the plugin instances of the pipeline processors
+	drains = make(map[*Config]*sharedDrain)
+	// pipelineDrains are the drains of the pipeline in the order of the actions
+	pipelineDrains = make(map[string][]*sharedDrain)
+)
+
+func acquireDrain(pipelineName string, config *Config) *drain {
+	drainsMu.Lock()
+	defer drainsMu.Unlock()
+
+	if d, has := drains[config]; has {
+		d.refs++
+		return d.drain
+	}
+
+	d := &sharedDrain{
+		drain:  newDrain(config.Depth, config.SimilarityThreshold, config.MaxChildren, config.MaxPatterns),
+		config: config,
+		refs:   1,
+	}
+	drains[config] = d
+	pipelineDrains[pipelineName] = append(pipelineDrains[pipelineName], d)
+	return d.drain
+}
+
+func releaseDrain(config *Config) {
+	drainsMu.Lock()
+	defer drainsMu.Unlock()
+
+	d, has := drains[config]
+	if !has {
+		return
+	}
+	d.refs--
+	if d.refs > 0 {
+		return
+	}
+
+	delete(drains, config)
+	for name, list := range pipelineDrains {
+		for i := range list {
+			if list[i] == d {
+				list = append(list[:i], list[i+1:]...)
+				break
+			}
+		}
+		if len(list) == 0 {
+			delete(pipelineDrains, name)
+		} else {
+			pipelineDrains[name] = list
+		}
+	}
+}
+
+type actionPatterns struct {
+	Field    string    `json:"field"`
+	Patterns []pattern `json:"patterns"`
+}
+
+// servePatterns responds with the top patterns of the log_pattern actions of the pipeline.
+// The number of the patterns is set by the limit query param, the top_n config param is used by default.
+func servePatterns(w http.ResponseWriter, r *http.Request) {
+	pipelineName := strings.Split(r.URL.Path, "/")[2]
+
+	limit := -1
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			http.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	drainsMu.Lock()
+	list := append([]*sharedDrain(nil), pipelineDrains[pipelineName]...)
+	drainsMu.Unlock()
+
+	if len(list) == 0 {
+		http.Error(w, "no log_pattern actions in pipeline "+pipelineName, http.StatusNotFound)
+		return
+	}
+
+	resp := make([]actionPatterns, 0, len(list))
+	for _, d := range list {
+		n := limit
+		if n < 0 {
+			n = d.config.TopN
+		}
+		resp = append(resp, actionPatterns{
+			Field:    string(d.config.Field),
+			Patterns: d.drain.top(n),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(resp)
+}
@@ -1,6 +1,10 @@
 package mask
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
 	"regexp"
 	"slices"
 	"strings"
@@ -36,10 +40,30 @@ pipelines:
     ...
 ```
 
+**Example of replacing emails with the tokens which can still be joined:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: mask
+      masks:
+      - re: "[a-z0-9._%+-]+@[a-z0-9.-]+\\.[a-z]+"
+        groups: [0]
+        mode: hash
+        hash_key: vault(secret/data/file.d, mask_key)
+    ...
+```
+
 }*/
 
 const (
 	substitution = byte('*')
+
+	modeMask = "mask"
+	modeHash = "hash"
+
+	defaultHashLength = 16
 )
 
 type Plugin struct {
@@ -50,6 +74,12 @@ type Plugin struct {
 	// maskBuf buffer for storing data in the process of masking
 	// (data before masked entry, its replacement and data after masked entry)
 	maskBuf []byte
+	// appliedPresets are the names of the presets applied to the current event
+	appliedPresets []string
+
+	// hashBuf and hexBuf are buffers for the tokens in the hash mode
+	hashBuf []byte
+	hexBuf  []byte
 
 	// common match regex
 	matchRe *regexp.Regexp
@@ -81,6 +111,7 @@ type Config struct {
 	// > @3@4@5@6
 	// >
 	// > If any mask has been applied then `mask_applied_field` will be set to `mask_applied_value` in the event.
+	// > If `mask_applied_value` isn't set, the names of the applied presets joined by comma are set, e.g. `credit_card,email`.
 	MaskAppliedField string `json:"mask_applied_field"` // *
 
 	// > @3@4@5@6
@@ -122,6 +153,21 @@ type Mask struct {
 	// > List of matching rules to filter out events before checking regular expression for masking.
 	MatchRules matchrule.RuleSets `json:"match_rules"` // *
 
+	// > @3@4@5@6
+	// >
+	// > The name of the built-in pattern to mask instead of `re`. The matches are validated to avoid false positives.
+	// > The default groups of the preset are used if `groups` aren't set.
+	// > * `credit_card` – card numbers of the known card networks with the valid Luhn checksum,
+	// > the digits must be written together or grouped by the same separator as on the cards, e.g. `4111 1111 1111 1111`.
+	// > * `iban` – IBANs with the valid mod-97 checksum.
+	// > * `email` – emails.
+	// > * `jwt` – JSON web tokens.
+	// > * `aws_access_key` – AWS access key IDs.
+	// > * `aws_secret_key` – AWS secret access keys, only the strings of high entropy are masked.
+	// > * `private_key` – PEM private key blocks.
+	// > * `bearer_token` – the tokens of the `Bearer` authorization, the `Bearer` word is kept.
+	Preset string `json:"preset" options:"credit_card|iban|email|jwt|aws_access_key|aws_secret_key|private_key|bearer_token"` // *
+
 	// > @3@4@5@6
 	// >
 	// > Regular expression for masking.
@@ -143,6 +189,32 @@ type Mask struct {
 	// > ReplaceWord, if set, is used instead of asterisks for masking patterns that are of the same length or longer.
 	ReplaceWord string `json:"replace_word"` // *
 
+	// > @3@4@5@6
+	// >
+	// > The way to replace the masking groups:
+	// > * `mask` – with the asterisks or `replace_word`.
+	// > * `hash` – with the truncated HMAC-SHA256 of the group keyed by `hash_key`,
+	// > so the same values are always replaced with the same tokens and the masked events can still be joined.
+	Mode string `json:"mode" default:"mask" options:"mask|hash"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The secret key of HMAC in the `hash` mode. It's better to keep it in the vault,
+	// > e.g. `hash_key: vault(secret/data/file.d, mask_key)`.
+	HashKey string `json:"hash_key"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The number of hex characters of the token in the `hash` mode, up to 64.
+	HashLength int `json:"hash_length" default:"16"` // *
+
+	// > @3@4@5@6
+	// >
+	// > If set, the token in the `hash` mode keeps the format of the masking group:
+	// > the digits are replaced with digits, the letters are replaced with letters of the same case and other symbols are kept.
+	// > It's useful for the card and phone numbers.
+	PreserveFormat bool `json:"preserve_format"` // *
+
 	// > @3@4@5@6
 	// >
 	// > If the mask has been applied then `applied_field` will be set to `applied_value` in the event.
@@ -167,6 +239,13 @@ type Mask struct {
 
 	// mask metric
 	appliedMetric *prometheus.CounterVec
+
+	// hasher computes HMAC in the hash mode
+	hasher hash.Hash
+
+	// validate filters the matches of the preset by the value of the validateGroup
+	validate      func(match []byte) bool
+	validateGroup int
 }
 
 func init() {
@@ -222,6 +301,9 @@ func compileMasks(masks []Mask, logger *zap.Logger) ([]Mask, *regexp.Regexp) {
 }
 
 func compileMask(m *Mask, logger *zap.Logger) {
+	if m.Preset != "" {
+		applyPreset(m, logger)
+	}
 	if m.Re == "" && len(m.MatchRules) == 0 {
 		logger.Fatal("mask must have either nonempty regex or ruleset, or both")
 	}
@@ -233,6 +315,16 @@ func compileMask(m *Mask, logger *zap.Logger) {
 		}
 		m.Re_ = re
 		m.Groups = cfg.VerifyGroupNumbers(m.Groups, re.NumSubexp(), logger)
+		// groups are masked in the order of their positions in the value
+		m.Groups = slices.Clone(m.Groups)
+		slices.Sort(m.Groups)
+	}
+	switch m.Mode {
+	case "", modeMask:
+	case modeHash:
+		compileHash(m, logger)
+	default:
+		logger.Fatal("unknown mask mode", zap.String("mode", m.Mode))
 	}
 	for i, matchRule := range m.MatchRules {
 		if len(matchRule.Rules) == 0 {
@@ -247,6 +339,39 @@ func compileMask(m *Mask, logger *zap.Logger) {
 	}
 }
 
+func applyPreset(m *Mask, logger *zap.Logger) {
+	preset, ok := presets[m.Preset]
+	if !ok {
+		logger.Fatal("unknown mask preset", zap.String("preset", m.Preset))
+	}
+	if m.Re != "" {
+		logger.Fatal("re can't be used with preset", zap.String("preset", m.Preset))
+	}
+
+	m.Re = preset.re
+	if len(m.Groups) == 0 {
+		m.Groups = preset.groups
+	}
+	m.validate = preset.validate
+	m.validateGroup = preset.groups[0]
+}
+
+func compileHash(m *Mask, logger *zap.Logger) {
+	if m.HashKey == "" {
+		logger.Fatal("hash_key must be set in the hash mode")
+	}
+	if m.ReplaceWord != "" || m.MaxCount > 0 {
+		logger.Fatal("replace_word and max_count can't be used in the hash mode")
+	}
+	if m.HashLength == 0 {
+		m.HashLength = defaultHashLength
+	}
+	if m.HashLength < 0 || m.HashLength > sha256.Size*2 {
+		logger.Fatal("hash_length must be in range [1, 64]", zap.Int("hash_length", m.HashLength))
+	}
+	m.hasher = hmac.New(sha256.New, []byte(m.HashKey))
+}
+
 func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
 	p.config = *config.(*Config)                            // copy shared config
 	p.config.Masks = append([]Mask(nil), p.config.Masks...) // copy shared masks
@@ -314,31 +439,63 @@ func (p *Plugin) registerMetrics(ctl *metric.Ctl) {
 func (p *Plugin) Stop() {
 }
 
-func (p *Plugin) appendMask(mask *Mask, dst, src []byte, begin, end int) ([]byte, int) {
-	runeCounter := utf8.RuneCount(src[begin:end])
+func (p *Plugin) appendMask(mask *Mask, dst, section []byte) []byte {
+	if mask.hasher != nil {
+		return p.appendHash(mask, dst, section)
+	}
 	if mask.ReplaceWord != "" {
-		dst = append(dst, []byte(mask.ReplaceWord)...)
-		return dst, len(src[begin:end]) - len(mask.ReplaceWord)
+		return append(dst, mask.ReplaceWord...)
 	}
+	runeCounter := utf8.RuneCount(section)
 	for j := 0; j < runeCounter; j++ {
 		if mask.MaxCount != 0 && j >= mask.MaxCount {
 			break
 		}
 		dst = append(dst, substitution)
 	}
-	return dst, len(src[begin:end]) - runeCounter
+	return dst
 }
 
-func (p *Plugin) maskSection(mask *Mask, dst, src []byte, begin, end int) ([]byte, int) {
-	dst = append(dst, src[:begin]...)
+func (p *Plugin) appendHash(mask *Mask, dst, section []byte) []byte {
+	mask.hasher.Reset()
+	_, _ = mask.hasher.Write(section)
+	p.hashBuf = mask.hasher.Sum(p.hashBuf[:0])
 
-	dst, offset := p.appendMask(mask, dst, src, begin, end)
+	if !mask.PreserveFormat {
+		n := hex.EncodedLen(len(p.hashBuf))
+		p.hexBuf = slices.Grow(p.hexBuf[:0], n)[:n]
+		hex.Encode(p.hexBuf, p.hashBuf)
+		return append(dst, p.hexBuf[:mask.HashLength]...)
+	}
 
-	if len(dst)+offset < len(src) {
-		dst = append(dst, src[end:]...)
+	// the symbols of the token are taken from the hash,
+	// the hash is extended by hashing it again if the group is longer than the hash
+	pos := 0
+	for _, r := range string(section) {
+		var from, size rune
+		switch {
+		case r >= '0' && r <= '9':
+			from, size = '0', 10
+		case r >= 'a' && r <= 'z':
+			from, size = 'a', 26
+		case r >= 'A' && r <= 'Z':
+			from, size = 'A', 26
+		default:
+			dst = utf8.AppendRune(dst, r)
+			continue
+		}
+
+		if pos == len(p.hashBuf) {
+			mask.hasher.Reset()
+			_, _ = mask.hasher.Write(p.hashBuf)
+			p.hashBuf = mask.hasher.Sum(p.hashBuf[:0])
+			pos = 0
+		}
+		dst = append(dst, byte(from+rune(p.hashBuf[pos])%size))
+		pos++
 	}
 
-	return dst, offset
+	return dst
 }
 
 func (p *Plugin) applyMaskMetric(mask *Mask, event *pipeline.Event) {
@@ -382,6 +539,12 @@ func (p *Plugin) maskValue(mask *Mask, value, buf []byte) ([]byte, bool) {
 	}
 
 	indexes := mask.Re_.FindAllSubmatchIndex(value, -1)
+	if mask.validate != nil {
+		indexes = slices.DeleteFunc(indexes, func(index []int) bool {
+			begin, end := index[mask.validateGroup*2], index[mask.validateGroup*2+1]
+			return begin < 0 || !mask.validate(value[begin:end])
+		})
+	}
 	if len(indexes) == 0 {
 		return buf, false
 	}
@@ -393,20 +556,23 @@ func (p *Plugin) maskValue(mask *Mask, value, buf []byte) ([]byte, bool) {
 
 	buf = buf[:0]
 
-	offset := 0
+	// prev is the end of the previous masked section
+	prev := 0
 	for _, index := range indexes {
 		for _, grp := range mask.Groups {
-			value, offset = p.maskSection(
-				mask,
-				buf,
-				value,
-				index[grp*2]-offset,
-				index[grp*2+1]-offset,
-			)
+			begin, end := index[grp*2], index[grp*2+1]
+			// skip unmatched groups and groups nested in the masked ones
+			if begin < prev {
+				continue
+			}
+			buf = append(buf, value[prev:begin]...)
+			buf = p.appendMask(mask, buf, value[begin:end])
+			prev = end
 		}
 	}
+	buf = append(buf, value[prev:]...)
 
-	return value, true
+	return buf, true
 }
 
 func getNestedValueNodes(currentNode *insaneJSON.Node, ignoredNodes []*insaneJSON.Node, valueNodes []*insaneJSON.Node) []*insaneJSON.Node {
@@ -455,6 +621,8 @@ func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
 	maskApplied := false
 	locApplied := false
 
+	p.appliedPresets = p.appliedPresets[:0]
+
 	p.valueNodes = p.valueNodes[:0]
 	p.valueNodes = p.getValueNodes(root, p.valueNodes)
 	for _, v := range p.valueNodes {
@@ -488,6 +656,9 @@ func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
 			if mask.MetricName != "" {
 				p.applyMaskMetric(mask, event)
 			}
+			if mask.Preset != "" && !slices.Contains(p.appliedPresets, mask.Preset) {
+				p.appliedPresets = append(p.appliedPresets, mask.Preset)
+			}
 
 			maskApplied = true
 		}
@@ -495,7 +666,12 @@ func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
 	}
 
 	if p.config.MaskAppliedField != "" && maskApplied {
-		event.Root.AddFieldNoAlloc(event.Root, p.config.MaskAppliedField).MutateToString(p.config.MaskAppliedValue)
+		value := p.config.MaskAppliedValue
+		if value == "" && len(p.appliedPresets) > 0 {
+			slices.Sort(p.appliedPresets)
+			value = strings.Join(p.appliedPresets, ",")
+		}
+		event.Root.AddFieldNoAlloc(event.Root, p.config.MaskAppliedField).MutateToString(value)
 	}
 
 	if maskApplied && p.config.AppliedMetricName != "" {
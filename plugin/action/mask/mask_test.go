@@ -1,6 +1,9 @@
 package mask
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"regexp"
@@ -1086,3 +1089,91 @@ func BenchmarkSkipManyValuesAtOnce(b *testing.B) {
 		pl.getValueNodes(root.Node, pl.valueNodes)
 	}
 }
+
+func TestHashMode(t *testing.T) {
+	const key = "secret"
+
+	hmacHex := func(key, value string) string {
+		h := hmac.New(sha256.New, []byte(key))
+		h.Write([]byte(value))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	suits := []struct {
+		name     string
+		mask     Mask
+		input    string
+		expected string
+	}{
+		{
+			name:     "email",
+			mask:     Mask{Re: kEMailRegExp, Groups: []int{0}, Mode: modeHash, HashKey: key},
+			input:    "from login@domain.ru to admin@domain.ru",
+			expected: "from " + hmacHex(key, "login@domain.ru")[:16] + " to " + hmacHex(key, "admin@domain.ru")[:16],
+		},
+		{
+			name:     "hash length",
+			mask:     Mask{Re: kEMailRegExp, Groups: []int{0}, Mode: modeHash, HashKey: key, HashLength: 64},
+			input:    "login@domain.ru",
+			expected: hmacHex(key, "login@domain.ru"),
+		},
+		{
+			name:     "groups",
+			mask:     Mask{Re: `user=(\w+) id=(\d+)`, Groups: []int{2, 1}, Mode: modeHash, HashKey: key, HashLength: 8},
+			input:    "user=bob id=42 user=alice id=1",
+			expected: "user=" + hmacHex(key, "bob")[:8] + " id=" + hmacHex(key, "42")[:8] + " user=" + hmacHex(key, "alice")[:8] + " id=" + hmacHex(key, "1")[:8],
+		},
+	}
+
+	var plugin Plugin
+	for _, tCase := range suits {
+		t.Run(tCase.name, func(t *testing.T) {
+			compileMask(&tCase.mask, zap.NewNop())
+			buf, masked := plugin.maskValue(&tCase.mask, []byte(tCase.input), nil)
+			require.True(t, masked)
+			require.Equal(t, tCase.expected, string(buf))
+		})
+	}
+}
+
+func TestHashModePreserveFormat(t *testing.T) {
+	mask := Mask{
+		Re:             `(\+?[\d\- ]{10,})|([A-Za-z]{2}\d{6})`,
+		Groups:         []int{0},
+		Mode:           modeHash,
+		HashKey:        "secret",
+		PreserveFormat: true,
+	}
+	compileMask(&mask, zap.NewNop())
+
+	var plugin Plugin
+
+	input := "card 5408-7430-0756-2004, phone +7 912 345-67-89, passport Ab123456"
+	first, masked := plugin.maskValue(&mask, []byte(input), nil)
+	require.True(t, masked)
+	require.NotEqual(t, input, string(first))
+	require.Regexp(t, `^card \d{4}-\d{4}-\d{4}-\d{4}, phone \+\d \d{3} \d{3}-\d{2}-\d{2}, passport [A-Z][a-z]\d{6}$`, string(first))
+
+	// the same values are replaced with the same tokens
+	second, _ := plugin.maskValue(&mask, []byte(input), nil)
+	require.Equal(t, string(first), string(second))
+
+	// long groups extend the hash
+	long := strings.Repeat("1", 100)
+	buf, _ := plugin.maskValue(&mask, []byte(long), nil)
+	require.Regexp(t, `^\d{100}$`, string(buf))
+}
+
+func TestHashModeDifferentKeys(t *testing.T) {
+	newMask := func(key string) *Mask {
+		mask := &Mask{Re: kEMailRegExp, Groups: []int{0}, Mode: modeHash, HashKey: key}
+		compileMask(mask, zap.NewNop())
+		return mask
+	}
+
+	var plugin Plugin
+	input := []byte("login@domain.ru")
+	first, _ := plugin.maskValue(newMask("first"), input, nil)
+	second, _ := plugin.maskValue(newMask("second"), input, nil)
+	require.NotEqual(t, string(first), string(second))
+}
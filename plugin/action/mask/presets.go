@@ -0,0 +1,232 @@
+package mask
+
+import (
+	"math"
+)
+
+// preset is a well-known sensitive pattern, the masked part of the pattern is always in the capture group.
+// The validator filters out the regex matches which only look like the pattern, e.g. the card numbers with a wrong checksum.
+type preset struct {
+	re       string
+	groups   []int
+	validate func(match []byte) bool
+}
+
+var presets = map[string]preset{
+	"credit_card": {
+		re:       `\b(\d(?:[ -]?\d){12,18})\b`,
+		groups:   []int{1},
+		validate: isCardNumber,
+	},
+	"iban": {
+		re:       `\b([A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30})\b`,
+		groups:   []int{1},
+		validate: isIBANValid,
+	},
+	"email": {
+		re:     `([A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,})`,
+		groups: []int{1},
+	},
+	"jwt": {
+		re:     `\b(eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*)`,
+		groups: []int{1},
+	},
+	"aws_access_key": {
+		re:     `\b((?:AKIA|ASIA|AIDA|AROA)[A-Z0-9]{16})\b`,
+		groups: []int{1},
+	},
+	"aws_secret_key": {
+		re:       `(?:^|[^A-Za-z0-9/+])([A-Za-z0-9/+]{40})(?:$|[^A-Za-z0-9/+])`,
+		groups:   []int{1},
+		validate: isHighEntropy,
+	},
+	"private_key": {
+		re:     `(-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----)`,
+		groups: []int{1},
+	},
+	"bearer_token": {
+		re:     `\b(?i:bearer)\s+([A-Za-z0-9\-._~+/]{8,}=*)`,
+		groups: []int{1},
+	},
+}
+
+// cardNetwork is the range of the issuer identification numbers (IIN) of a card network
+// and the lengths of its card numbers.
+type cardNetwork struct {
+	// from and to are the bounds of the range of the first prefixLen digits
+	from, to  int
+	prefixLen int
+	lengths   []int
+}
+
+var (
+	lengths16to19 = []int{16, 17, 18, 19}
+
+	cardNetworks = []cardNetwork{
+		{from: 4, to: 4, prefixLen: 1, lengths: []int{13, 16, 19}},             // Visa
+		{from: 51, to: 55, prefixLen: 2, lengths: []int{16}},                   // Mastercard
+		{from: 2221, to: 2720, prefixLen: 4, lengths: []int{16}},               // Mastercard
+		{from: 34, to: 34, prefixLen: 2, lengths: []int{15}},                   // American Express
+		{from: 37, to: 37, prefixLen: 2, lengths: []int{15}},                   // American Express
+		{from: 6011, to: 6011, prefixLen: 4, lengths: lengths16to19},           // Discover
+		{from: 644, to: 649, prefixLen: 3, lengths: lengths16to19},             // Discover
+		{from: 65, to: 65, prefixLen: 2, lengths: lengths16to19},               // Discover
+		{from: 62, to: 62, prefixLen: 2, lengths: lengths16to19},               // UnionPay
+		{from: 3528, to: 3589, prefixLen: 4, lengths: lengths16to19},           // JCB
+		{from: 300, to: 305, prefixLen: 3, lengths: []int{14, 16, 17, 18, 19}}, // Diners Club
+		{from: 36, to: 36, prefixLen: 2, lengths: []int{14, 16, 17, 18, 19}},   // Diners Club
+		{from: 38, to: 39, prefixLen: 2, lengths: lengths16to19},               // Diners Club
+		{from: 2200, to: 2204, prefixLen: 4, lengths: lengths16to19},           // Mir
+	}
+)
+
+// isCardNumber filters out the digit runs which only look like the card numbers:
+// the number must be grouped as the card numbers are printed, belong to a known card network
+// and have the valid Luhn checksum.
+func isCardNumber(match []byte) bool {
+	digits := make([]byte, 0, len(match))
+	groups := make([]int, 0, 5)
+	var sep byte
+	group := 0
+	for _, c := range match {
+		if c >= '0' && c <= '9' {
+			digits = append(digits, c)
+			group++
+			continue
+		}
+		// the separators of one number are the same
+		if sep != 0 && c != sep {
+			return false
+		}
+		sep = c
+		groups = append(groups, group)
+		group = 0
+	}
+	groups = append(groups, group)
+
+	return isCardGrouping(groups) && hasCardIIN(digits) && isLuhnValid(digits)
+}
+
+// isCardGrouping checks the lengths of the digit groups split by the separators.
+// The numbers are printed by 4 digits, American Express and Diners Club ones are printed as 4-6-5 and 4-6-4.
+func isCardGrouping(groups []int) bool {
+	if len(groups) == 1 {
+		return true
+	}
+	if len(groups) == 3 && groups[0] == 4 && groups[1] == 6 && (groups[2] == 5 || groups[2] == 4) {
+		return true
+	}
+
+	for i, group := range groups {
+		last := i == len(groups)-1
+		if group != 4 && (!last || group > 4) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasCardIIN(digits []byte) bool {
+	for _, network := range cardNetworks {
+		if len(digits) < network.prefixLen {
+			continue
+		}
+
+		prefix := 0
+		for _, c := range digits[:network.prefixLen] {
+			prefix = prefix*10 + int(c-'0')
+		}
+		if prefix < network.from || prefix > network.to {
+			continue
+		}
+
+		for _, length := range network.lengths {
+			if len(digits) == length {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isLuhnValid checks the checksum of the card number, the separators are skipped.
+func isLuhnValid(match []byte) bool {
+	sum := 0
+	double := false
+	for i := len(match) - 1; i >= 0; i-- {
+		c := match[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// isIBANValid checks the mod-97 checksum of the IBAN, the spaces are skipped.
+func isIBANValid(match []byte) bool {
+	iban := make([]byte, 0, len(match))
+	for _, c := range match {
+		if c != ' ' {
+			iban = append(iban, c)
+		}
+	}
+	if len(iban) < 15 {
+		return false
+	}
+
+	remainder := 0
+	// the country code and the check digits are moved to the end
+	for i := range iban {
+		c := iban[(i+4)%len(iban)]
+		switch {
+		case c >= '0' && c <= '9':
+			remainder = (remainder*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			// letters are replaced with two digits: A = 10, B = 11, ...
+			remainder = (remainder*100 + int(c-'A') + 10) % 97
+		default:
+			return false
+		}
+	}
+	return remainder == 1
+}
+
+// minSecretEntropy is the Shannon entropy in bits per symbol which random base64 strings of 40 symbols exceed,
+// but the words and the identifiers usually don't.
+const minSecretEntropy = 4.2
+
+func isHighEntropy(match []byte) bool {
+	return shannonEntropy(match) >= minSecretEntropy
+}
+
+func shannonEntropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+
+	var counts [256]int
+	for _, c := range data {
+		counts[c]++
+	}
+
+	entropy := 0.0
+	total := float64(len(data))
+	for _, cnt := range counts {
+		if cnt == 0 {
+			continue
+		}
+		p := float64(cnt) / total
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
@@ -0,0 +1,333 @@
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ozontech/file.d/cfg"
+	"github.com/ozontech/file.d/fd"
+	"github.com/ozontech/file.d/metric"
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/pipeline/doif"
+	"github.com/prometheus/client_golang/prometheus"
+	insaneJSON "github.com/vitkovskii/insane-json"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It derives Prometheus metrics from the events: counters, gauges and histograms with the labels taken from the event fields.
+The metrics are exposed by the file.d metrics endpoint with the `file_d_pipeline_<pipeline name>_` prefix.
+
+The label sets which aren't updated for the pipeline `metric_hold_duration` are deleted,
+so high-cardinality labels don't grow the metrics forever.
+
+The metric names must differ from the names of the pipeline metrics, e.g. `input_events_count`.
+The metrics actions of the pipeline may share a metric only if its type, labels and buckets are the same.
+
+**Example of counting the errors per service and measuring the request duration per endpoint:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: metrics
+      metrics:
+        - name: errors_total
+          type: counter
+          labels:
+            service: service
+          do_if:
+            op: equal
+            field: level
+            values: [error]
+        - name: request_duration_ms
+          type: histogram
+          value_field: duration_ms
+          buckets: [10, 50, 100, 500, 1000]
+          labels:
+            endpoint: request.path
+    ...
+```
+}*/
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+
+	defaultHelp = "Metric derived from the events by the metrics plugin"
+)
+
+var nameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// registered keeps the kinds of the metrics registered by the metrics actions of every pipeline.
+// The metric controller returns the already registered metric with the same name whatever its type and labels are,
+// so the names are checked across all the actions before the registration.
+var registered = struct {
+	mu      sync.Mutex
+	metrics map[*metric.Ctl]map[string]string
+}{
+	metrics: make(map[*metric.Ctl]map[string]string),
+}
+
+type Plugin struct {
+	config *Config
+	logger *zap.Logger
+
+	metrics []*eventMetric
+}
+
+type eventMetric struct {
+	checker    *doif.Checker
+	valueField []string
+
+	labelFields [][]string
+	labelValues []string
+
+	counter   metric.HeldCounterVec
+	gauge     metric.HeldGaugeVec
+	histogram metric.HeldHistogramVec
+	kind      string
+}
+
+// ! config-params
+// ^ config-params
+type Config struct {
+	// > @3@4@5@6
+	// >
+	// > The metrics to derive from the events. It's a list of objects with the following fields:
+	// > * `name` – the name of the metric, it must be unique within the pipeline.
+	// > * `type` – `counter`, `gauge` or `histogram`.
+	// > * `help` – the description of the metric.
+	// > * `labels` – the map of the label names to the event fields which values are used as the label values.
+	// > If the event has no field, the label value is empty.
+	// > * `value_field` – the event field with the numeric value. It's required for gauges and histograms.
+	// > Counters are increased by the value if it's set, otherwise by one.
+	// > The metric isn't updated if the event has no field or its value isn't a number.
+	// > * `buckets` – the histogram buckets, Prometheus default buckets are used if not set.
+	// > * `do_if` – the conditions in the same format as the action `do_if`, the metric is updated only if they are met.
+	Metrics []MetricConfig `json:"metrics" slice:"true" required:"true"` // *
+
+	// > @3@4@5@6
+	// >
+	// > If set, the event is discarded after it has been measured.
+	Discard bool `json:"discard" default:"false"` // *
+}
+
+type MetricConfig struct {
+	Name       string            `json:"name"`
+	Type       string            `json:"type"`
+	Help       string            `json:"help"`
+	Labels     map[string]string `json:"labels"`
+	ValueField string            `json:"value_field"`
+	Buckets    []float64         `json:"buckets"`
+	DoIf       map[string]any    `json:"do_if"`
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "metrics",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger.Desugar()
+
+	if len(p.config.Metrics) == 0 {
+		p.logger.Fatal("no metrics are set")
+	}
+
+	names := make(map[string]bool, len(p.config.Metrics))
+	for i := range p.config.Metrics {
+		mc := &p.config.Metrics[i]
+		if names[mc.Name] {
+			p.logger.Fatal("metric names must be unique", zap.String("name", mc.Name))
+		}
+		names[mc.Name] = true
+
+		p.metrics = append(p.metrics, p.newMetric(mc, params))
+	}
+}
+
+func (p *Plugin) newMetric(mc *MetricConfig, params *pipeline.ActionPluginParams) *eventMetric {
+	logger := p.logger.With(zap.String("metric", mc.Name))
+
+	if !nameRe.MatchString(mc.Name) {
+		logger.Fatal("invalid metric name")
+	}
+
+	m := &eventMetric{
+		kind: mc.Type,
+	}
+
+	if len(mc.DoIf) > 0 {
+		checker, err := fd.ExtractDoIfChecker(mc.DoIf)
+		if err != nil {
+			logger.Fatal("can't extract do_if conditions of the metric", zap.Error(err))
+		}
+		m.checker = checker
+	}
+
+	if mc.ValueField != "" {
+		m.valueField = cfg.ParseFieldSelector(mc.ValueField)
+	}
+
+	// labels are sorted to have the same order of the label values on every start
+	labelNames := make([]string, 0, len(mc.Labels))
+	for name := range mc.Labels {
+		if !nameRe.MatchString(name) {
+			logger.Fatal("invalid label name", zap.String("label", name))
+		}
+		labelNames = append(labelNames, name)
+	}
+	slices.Sort(labelNames)
+
+	for _, name := range labelNames {
+		m.labelFields = append(m.labelFields, cfg.ParseFieldSelector(mc.Labels[name]))
+	}
+	m.labelValues = make([]string, len(labelNames))
+
+	help := mc.Help
+	if help == "" {
+		help = defaultHelp
+	}
+
+	ctl := params.MetricCtl
+	holder := params.MetricHolder
+	kind := mc.Type + "{" + strings.Join(labelNames, ",") + "}"
+	switch mc.Type {
+	case typeCounter:
+		reserveName(logger, ctl, mc.Name, kind)
+		m.counter = holder.AddCounterVec(ctl.RegisterCounterVec(mc.Name, help, labelNames...))
+	case typeGauge:
+		if m.valueField == nil {
+			logger.Fatal("value_field is required for gauges")
+		}
+		reserveName(logger, ctl, mc.Name, kind)
+		m.gauge = holder.AddGaugeVec(ctl.RegisterGaugeVec(mc.Name, help, labelNames...))
+	case typeHistogram:
+		if m.valueField == nil {
+			logger.Fatal("value_field is required for histograms")
+		}
+		buckets := mc.Buckets
+		if len(buckets) == 0 {
+			buckets = prometheus.DefBuckets
+		}
+		if !sort.Float64sAreSorted(buckets) {
+			logger.Fatal("histogram buckets must be sorted in increasing order")
+		}
+		reserveName(logger, ctl, mc.Name, kind+fmt.Sprint(buckets))
+		m.histogram = holder.AddHistogramVec(ctl.RegisterHistogramVec(mc.Name, help, buckets, labelNames...))
+	default:
+		logger.Fatal("unknown metric type, must be one of counter, gauge or histogram", zap.String("type", mc.Type))
+	}
+
+	return m
+}
+
+func reserveName(logger *zap.Logger, ctl *metric.Ctl, name, kind string) {
+	if err := checkName(ctl, name, kind); err != nil {
+		logger.Fatal("can't register the metric", zap.Error(err))
+	}
+}
+
+// checkName reserves the name for the metric of the kind, which is the type, the labels and the buckets of the metric.
+// The actions may share the metric only if the kinds are the same.
+func checkName(ctl *metric.Ctl, name, kind string) error {
+	registered.mu.Lock()
+	defer registered.mu.Unlock()
+
+	names := registered.metrics[ctl]
+	if names == nil {
+		names = make(map[string]string)
+		registered.metrics[ctl] = names
+	}
+
+	has, ok := names[name]
+	if !ok {
+		if ctl.IsRegistered(name) {
+			return fmt.Errorf("metric name %q is already used by the pipeline or another plugin", name)
+		}
+		names[name] = kind
+		return nil
+	}
+	if has != kind {
+		return fmt.Errorf("metric %q is already registered by another metrics action as %s, not as %s", name, has, kind)
+	}
+	return nil
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	for _, m := range p.metrics {
+		m.observe(event.Root)
+	}
+
+	if p.config.Discard {
+		return pipeline.ActionDiscard
+	}
+	return pipeline.ActionPass
+}
+
+func (m *eventMetric) observe(root *insaneJSON.Root) {
+	if m.checker != nil && !m.checker.Check(root) {
+		return
+	}
+
+	value := 1.0
+	if m.valueField != nil {
+		var ok bool
+		value, ok = numericValue(root.Dig(m.valueField...))
+		if !ok {
+			return
+		}
+	}
+
+	for i, field := range m.labelFields {
+		m.labelValues[i] = ""
+		if node := root.Dig(field...); node != nil {
+			m.labelValues[i] = node.AsString()
+		}
+	}
+
+	switch m.kind {
+	case typeCounter:
+		// counters can't decrease
+		if value < 0 {
+			return
+		}
+		m.counter.WithLabelValues(m.labelValues...).Add(value)
+	case typeGauge:
+		m.gauge.WithLabelValues(m.labelValues...).Set(value)
+	case typeHistogram:
+		m.histogram.WithLabelValues(m.labelValues...).Observe(value)
+	}
+}
+
+// numericValue returns the value of the number or the string containing the number.
+func numericValue(node *insaneJSON.Node) (float64, bool) {
+	switch {
+	case node == nil:
+		return 0, false
+	case node.IsNumber():
+		return node.AsFloat(), true
+	case node.IsString():
+		value, err := strconv.ParseFloat(node.AsString(), 64)
+		return value, err == nil
+	default:
+		return 0, false
+	}
+}
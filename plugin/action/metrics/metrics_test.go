@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/ozontech/file.d/metric"
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/test"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+func runMetrics(t *testing.T, config *Config, in []string) (*metric.Ctl, []pipeline.ActionResult) {
+	t.Helper()
+
+	params := test.NewEmptyActionPluginParams()
+	params.MetricCtl = metric.NewCtl("test", prometheus.NewRegistry())
+
+	plugin := &Plugin{}
+	plugin.Start(test.NewConfig(config, nil), params)
+	defer plugin.Stop()
+
+	results := make([]pipeline.ActionResult, 0, len(in))
+	for _, e := range in {
+		root, err := insaneJSON.DecodeString(e)
+		require.NoError(t, err)
+
+		results = append(results, plugin.Do(&pipeline.Event{Root: root}))
+		insaneJSON.Release(root)
+	}
+
+	return params.MetricCtl, results
+}
+
+func TestCounter(t *testing.T) {
+	ctl, results := runMetrics(t, &Config{
+		Metrics: []MetricConfig{
+			{
+				Name:   "errors_total",
+				Type:   typeCounter,
+				Labels: map[string]string{"service": "service", "level": "level"},
+				DoIf: map[string]any{
+					"op":     "equal",
+					"field":  "level",
+					"values": []any{"error", "fatal"},
+				},
+			},
+			{
+				Name:       "bytes_total",
+				Type:       typeCounter,
+				ValueField: "size",
+			},
+		},
+	}, []string{
+		`{"service":"checkout","level":"error","size":10}`,
+		`{"service":"checkout","level":"error","size":"20"}`,
+		`{"service":"checkout","level":"info","size":-5}`,
+		`{"service":"search","level":"fatal","size":"wrong"}`,
+		`{"level":"error"}`,
+	})
+
+	require.Equal(t, []pipeline.ActionResult{
+		pipeline.ActionPass, pipeline.ActionPass, pipeline.ActionPass, pipeline.ActionPass, pipeline.ActionPass,
+	}, results)
+
+	// labels are sorted by name
+	errors := ctl.RegisterCounterVec("errors_total", "", "level", "service")
+	require.Equal(t, 2.0, testutil.ToFloat64(errors.WithLabelValues("error", "checkout")))
+	require.Equal(t, 1.0, testutil.ToFloat64(errors.WithLabelValues("fatal", "search")))
+	require.Equal(t, 1.0, testutil.ToFloat64(errors.WithLabelValues("error", "")))
+	require.Equal(t, 3, testutil.CollectAndCount(errors))
+
+	bytes := ctl.RegisterCounterVec("bytes_total", "")
+	require.Equal(t, 30.0, testutil.ToFloat64(bytes.WithLabelValues()))
+}
+
+func TestGaugeAndHistogram(t *testing.T) {
+	ctl, results := runMetrics(t, &Config{
+		Metrics: []MetricConfig{
+			{
+				Name:       "queue_size",
+				Type:       typeGauge,
+				ValueField: "queue.size",
+				Labels:     map[string]string{"queue": "queue.name"},
+			},
+			{
+				Name:       "duration_ms",
+				Type:       typeHistogram,
+				ValueField: "duration_ms",
+				Buckets:    []float64{10, 100},
+				Labels:     map[string]string{"endpoint": "endpoint"},
+			},
+		},
+		Discard: true,
+	}, []string{
+		`{"queue":{"name":"orders","size":5},"endpoint":"/api","duration_ms":7}`,
+		`{"queue":{"name":"orders","size":3},"endpoint":"/api","duration_ms":70}`,
+		`{"endpoint":"/api","duration_ms":700}`,
+	})
+
+	require.Equal(t, []pipeline.ActionResult{
+		pipeline.ActionDiscard, pipeline.ActionDiscard, pipeline.ActionDiscard,
+	}, results)
+
+	queue := ctl.RegisterGaugeVec("queue_size", "", "queue")
+	require.Equal(t, 3.0, testutil.ToFloat64(queue.WithLabelValues("orders")))
+	require.Equal(t, 1, testutil.CollectAndCount(queue))
+
+	duration := ctl.RegisterHistogramVec("duration_ms", "", nil, "endpoint")
+	require.Equal(t, 1, testutil.CollectAndCount(duration))
+
+	pb := &dto.Metric{}
+	require.NoError(t, duration.WithLabelValues("/api").(prometheus.Metric).Write(pb))
+	require.Equal(t, uint64(3), pb.GetHistogram().GetSampleCount())
+	require.Equal(t, 777.0, pb.GetHistogram().GetSampleSum())
+	require.Equal(t, uint64(1), pb.GetHistogram().GetBucket()[0].GetCumulativeCount())
+	require.Equal(t, uint64(2), pb.GetHistogram().GetBucket()[1].GetCumulativeCount())
+}
+
+func TestCheckName(t *testing.T) {
+	ctl := metric.NewCtl("test", prometheus.NewRegistry())
+	ctl.RegisterCounter("input_events_count", "")
+
+	require.Error(t, checkName(ctl, "input_events_count", "counter{}"))
+
+	require.NoError(t, checkName(ctl, "errors_total", "counter{level}"))
+	// the other instances of the action and the actions with the same metric share it
+	require.NoError(t, checkName(ctl, "errors_total", "counter{level}"))
+	require.Error(t, checkName(ctl, "errors_total", "gauge{level}"))
+	require.Error(t, checkName(ctl, "errors_total", "counter{level,service}"))
+
+	// the pipelines have their own metrics
+	other := metric.NewCtl("other", prometheus.NewRegistry())
+	require.NoError(t, checkName(other, "errors_total", "gauge{level}"))
+}
@@ -0,0 +1,204 @@
+package sample
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/ozontech/file.d/cfg"
+	"github.com/ozontech/file.d/fd"
+	"github.com/ozontech/file.d/metric"
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/pipeline/doif"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It keeps a representative fraction of the events and discards the rest.
+
+The decision is random by default. If `hash_field` is set, the decision is made by the hash of the field value,
+so all events with the same value (e.g. the same trace ID) are either kept or discarded together,
+and the decision is the same on all file.d instances.
+
+The `rate` can be overridden for groups of events by `rules`, the first matched rule is applied.
+To reconstruct the totals downstream, the number of discarded events can be written into the kept events
+with `sampled_out_field` and is always counted by the `sample_discarded_events_total` metric.
+
+**Example of keeping 10% of the traces and all the errors:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: sample
+      rate: 0.1
+      hash_field: trace_id
+      sampled_out_field: sampled_out
+      rules:
+        - rate: 1
+          do_if:
+            op: equal
+            field: level
+            values: [error]
+    ...
+```
+}*/
+
+const defaultRuleLabel = "default"
+
+type Plugin struct {
+	config *Config
+	logger *zap.Logger
+	rnd    *rand.Rand
+
+	rules []*rule
+
+	// plugin metrics
+	discardedMetric *prometheus.CounterVec
+}
+
+type rule struct {
+	checker   *doif.Checker
+	threshold uint64
+	label     string
+
+	// sampledOut is the number of events discarded since the last kept one
+	sampledOut int
+}
+
+// ! config-params
+// ^ config-params
+type Config struct {
+	// > @3@4@5@6
+	// >
+	// > The fraction of the events to keep, from `0` to `1`.
+	Rate float64 `json:"rate" required:"true"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The event field which value is hashed to make the decision.
+	// > If not set or the field is absent in the event, the decision is random.
+	HashField  cfg.FieldSelector `json:"hash_field" parse:"selector"` // *
+	HashField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > Rules to override the `rate` for different groups of events. It's a list of objects.
+	// > Each object has the `rate` and `do_if` fields.
+	// > * `rate` – the value which will override the `rate`, if `do_if` conditions are met.
+	// > * `do_if` – the conditions in the same format as the action `do_if`.
+	Rules []RuleConfig `json:"rules" slice:"true"` // *
+
+	// > @3@4@5@6
+	// >
+	// > If set, the number of the events discarded by the same rule since the previous kept event
+	// > is written into this field of the kept event.
+	SampledOutField  cfg.FieldSelector `json:"sampled_out_field" parse:"selector"` // *
+	SampledOutField_ []string
+}
+
+type RuleConfig struct {
+	Rate float64        `json:"rate"`
+	DoIf map[string]any `json:"do_if"`
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "sample",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger.Desugar()
+	p.rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	p.registerMetrics(params.MetricCtl)
+
+	for i, r := range p.config.Rules {
+		if len(r.DoIf) == 0 {
+			p.logger.Fatal("sample rule must have do_if conditions", zap.Int("rule", i))
+		}
+		checker, err := fd.ExtractDoIfChecker(r.DoIf)
+		if err != nil {
+			p.logger.Fatal("can't extract do_if conditions of the sample rule", zap.Int("rule", i), zap.Error(err))
+		}
+		p.rules = append(p.rules, p.newRule(checker, r.Rate, strconv.Itoa(i)))
+	}
+	p.rules = append(p.rules, p.newRule(nil, p.config.Rate, defaultRuleLabel))
+}
+
+func (p *Plugin) registerMetrics(ctl *metric.Ctl) {
+	p.discardedMetric = ctl.RegisterCounterVec(
+		"sample_discarded_events_total",
+		"Number of events discarded by the sample plugin",
+		"rule",
+	)
+}
+
+func (p *Plugin) newRule(checker *doif.Checker, rate float64, label string) *rule {
+	if rate < 0 || rate > 1 {
+		p.logger.Fatal("sample rate must be in range [0, 1]", zap.String("rule", label), zap.Float64("rate", rate))
+	}
+
+	return &rule{
+		checker:   checker,
+		threshold: rateThreshold(rate),
+		label:     label,
+	}
+}
+
+// rateThreshold maps the rate to the range of uint64, so the event is kept if its hash is below the threshold.
+func rateThreshold(rate float64) uint64 {
+	if rate >= 1 {
+		return math.MaxUint64
+	}
+	return uint64(rate * math.MaxUint64)
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	r := p.rules[len(p.rules)-1]
+	for _, candidate := range p.rules[:len(p.rules)-1] {
+		if candidate.checker.Check(event.Root) {
+			r = candidate
+			break
+		}
+	}
+
+	if !p.isKept(event, r) {
+		r.sampledOut++
+		p.discardedMetric.WithLabelValues(r.label).Inc()
+		return pipeline.ActionDiscard
+	}
+
+	if len(p.config.SampledOutField_) > 0 {
+		pipeline.CreateNestedField(event.Root, p.config.SampledOutField_).MutateToInt(r.sampledOut)
+	}
+	r.sampledOut = 0
+
+	return pipeline.ActionPass
+}
+
+func (p *Plugin) isKept(event *pipeline.Event, r *rule) bool {
+	if r.threshold == math.MaxUint64 {
+		return true
+	}
+
+	if len(p.config.HashField_) > 0 {
+		if node := event.Root.Dig(p.config.HashField_...); node != nil {
+			return xxhash.Sum64String(node.AsString()) < r.threshold
+		}
+	}
+
+	return p.rnd.Uint64() < r.threshold
+}
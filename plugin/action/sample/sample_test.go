@@ -0,0 +1,154 @@
+package sample
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/test"
+	"github.com/stretchr/testify/require"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+func runSample(t *testing.T, config *Config, in []string) []string {
+	t.Helper()
+
+	plugin := &Plugin{}
+	plugin.Start(test.NewConfig(config, nil), test.NewEmptyActionPluginParams())
+	defer plugin.Stop()
+
+	out := make([]string, 0)
+	for _, e := range in {
+		root, err := insaneJSON.DecodeString(e)
+		require.NoError(t, err)
+
+		if plugin.Do(&pipeline.Event{Root: root}) == pipeline.ActionPass {
+			out = append(out, root.EncodeToString())
+		}
+		insaneJSON.Release(root)
+	}
+
+	return out
+}
+
+func TestSampleRandom(t *testing.T) {
+	const total = 10000
+
+	in := make([]string, 0, total)
+	for i := 0; i < total; i++ {
+		in = append(in, fmt.Sprintf(`{"i":%d}`, i))
+	}
+
+	out := runSample(t, &Config{Rate: 0.25}, in)
+	require.InDelta(t, total/4, len(out), total/20)
+}
+
+func TestSampleHash(t *testing.T) {
+	const (
+		traces    = 1000
+		perTrace  = 5
+		traceRate = 0.3
+	)
+
+	in := make([]string, 0, traces*perTrace)
+	for j := 0; j < perTrace; j++ {
+		for i := 0; i < traces; i++ {
+			in = append(in, fmt.Sprintf(`{"trace_id":"trace-%d","span":%d}`, i, j))
+		}
+	}
+
+	out := runSample(t, &Config{Rate: traceRate, HashField: "trace_id"}, in)
+
+	kept := make(map[string]int)
+	for _, e := range out {
+		root, err := insaneJSON.DecodeString(e)
+		require.NoError(t, err)
+		kept[strings.Clone(root.Dig("trace_id").AsString())]++
+		insaneJSON.Release(root)
+	}
+
+	for id, cnt := range kept {
+		require.Equal(t, perTrace, cnt, "all events of the trace %s must be kept", id)
+	}
+	require.InDelta(t, traces*traceRate, len(kept), traces/10)
+
+	// the decision must be the same for the same values
+	again := runSample(t, &Config{Rate: traceRate, HashField: "trace_id"}, in)
+	require.Equal(t, out, again)
+}
+
+func TestSampleRules(t *testing.T) {
+	in := []string{
+		`{"level":"info"}`,
+		`{"level":"error"}`,
+		`{"level":"info"}`,
+		`{"level":"error"}`,
+	}
+
+	out := runSample(t, &Config{
+		Rate: 0.000001,
+		Rules: []RuleConfig{
+			{
+				Rate: 1,
+				DoIf: map[string]any{
+					"op":     "equal",
+					"field":  "level",
+					"values": []any{"error"},
+				},
+			},
+		},
+	}, in)
+
+	require.Equal(t, []string{`{"level":"error"}`, `{"level":"error"}`}, out)
+}
+
+func TestSampleSampledOutField(t *testing.T) {
+	const rate = 0.5
+
+	// find trace IDs with known decisions
+	var keptID, discardedID string
+	for i := 0; keptID == "" || discardedID == ""; i++ {
+		id := fmt.Sprintf("trace-%d", i)
+		if xxhash.Sum64String(id) < rateThreshold(rate) {
+			keptID = id
+		} else {
+			discardedID = id
+		}
+	}
+
+	in := []string{
+		fmt.Sprintf(`{"trace_id":%q}`, discardedID),
+		fmt.Sprintf(`{"trace_id":%q}`, discardedID),
+		fmt.Sprintf(`{"trace_id":%q}`, keptID),
+		fmt.Sprintf(`{"trace_id":%q}`, keptID),
+		fmt.Sprintf(`{"trace_id":%q,"level":"error"}`, discardedID),
+		fmt.Sprintf(`{"trace_id":%q}`, discardedID),
+		fmt.Sprintf(`{"trace_id":%q}`, keptID),
+	}
+
+	out := runSample(t, &Config{
+		Rate:            rate,
+		HashField:       "trace_id",
+		SampledOutField: "sample.sampled_out",
+		Rules: []RuleConfig{
+			{
+				Rate: 1,
+				DoIf: map[string]any{
+					"op":     "equal",
+					"field":  "level",
+					"values": []any{"error"},
+				},
+			},
+		},
+	}, in)
+
+	// sampled out events are counted separately for each rule
+	require.Equal(t, []string{
+		fmt.Sprintf(`{"trace_id":%q,"sample":{"sampled_out":2}}`, keptID),
+		fmt.Sprintf(`{"trace_id":%q,"sample":{"sampled_out":0}}`, keptID),
+		fmt.Sprintf(`{"trace_id":%q,"level":"error","sample":{"sampled_out":0}}`, discardedID),
+		fmt.Sprintf(`{"trace_id":%q,"sample":{"sampled_out":1}}`, keptID),
+	}, out)
+}
@@ -0,0 +1,211 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/ozontech/file.d/pipeline"
+	insaneJSON "github.com/vitkovskii/insane-json"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// newEventObject creates the `event` object which is passed to the process function.
+// The object is the same for all the events, the methods work with the current event.
+func (p *Plugin) newEventObject() *lua.LUserData {
+	methods := p.state.SetFuncs(p.state.NewTable(), map[string]lua.LGFunction{
+		"get":    p.luaGet,
+		"set":    p.luaSet,
+		"remove": p.luaRemove,
+		"spawn":  p.luaSpawn,
+	})
+
+	meta := p.state.NewTable()
+	p.state.SetField(meta, "__index", methods)
+	p.state.SetField(meta, "__metatable", lua.LFalse)
+
+	event := p.state.NewUserData()
+	p.state.SetMetatable(event, meta)
+	return event
+}
+
+// checkEvent checks that the method is called for the event object and the event is being processed.
+func (p *Plugin) checkEvent(state *lua.LState) *pipeline.Event {
+	if state.CheckUserData(1) != p.event {
+		state.ArgError(1, "event expected, use event:method() to call the methods")
+	}
+	if p.current == nil {
+		state.RaiseError("event is available only in the process function")
+	}
+	return p.current
+}
+
+func (p *Plugin) luaGet(state *lua.LState) int {
+	event := p.checkEvent(state)
+	node := event.Root.Dig(p.path(state.CheckString(2))...)
+	state.Push(nodeToLua(state, node))
+	return 1
+}
+
+func (p *Plugin) luaSet(state *lua.LState) int {
+	event := p.checkEvent(state)
+	path := p.path(state.CheckString(2))
+	if len(path) == 0 {
+		state.ArgError(2, "field is empty")
+	}
+
+	value := state.CheckAny(3)
+	if value == lua.LNil {
+		event.Root.Dig(path...).Suicide()
+		return 0
+	}
+
+	node := pipeline.CreateNestedField(event.Root, path)
+	if err := setNode(event.Root, node, value, 0); err != nil {
+		state.RaiseError("can't set %q: %s", state.CheckString(2), err.Error())
+	}
+	return 0
+}
+
+func (p *Plugin) luaRemove(state *lua.LState) int {
+	event := p.checkEvent(state)
+	path := p.path(state.CheckString(2))
+	if len(path) == 0 {
+		state.ArgError(2, "field is empty")
+	}
+
+	event.Root.Dig(path...).Suicide()
+	return 0
+}
+
+func (p *Plugin) luaSpawn(state *lua.LState) int {
+	event := p.checkEvent(state)
+	table := state.CheckTable(2)
+	if event.IsChildKind() {
+		state.RaiseError("spawned event can't spawn events")
+	}
+
+	// the node lives as long as the parent event
+	node, err := event.Root.DecodeStringAdditional("{}")
+	if err != nil {
+		state.RaiseError("can't spawn event: %s", err.Error())
+	}
+	if err := setNode(event.Root, node, table, 0); err != nil {
+		state.RaiseError("can't spawn event: %s", err.Error())
+	}
+	if !node.IsObject() {
+		state.RaiseError("spawned event must be an object")
+	}
+
+	p.spawned = append(p.spawned, node)
+	return 0
+}
+
+func nodeToLua(state *lua.LState, node *insaneJSON.Node) lua.LValue {
+	switch {
+	case node == nil:
+		return lua.LNil
+	case node.IsString():
+		return lua.LString(node.AsString())
+	case node.IsNumber():
+		return lua.LNumber(node.AsFloat())
+	case node.IsTrue():
+		return lua.LTrue
+	case node.IsFalse():
+		return lua.LFalse
+	case node.IsObject():
+		fields := node.AsFields()
+		table := state.CreateTable(0, len(fields))
+		for _, field := range fields {
+			table.RawSetString(field.AsString(), nodeToLua(state, field.AsFieldValue()))
+		}
+		return table
+	case node.IsArray():
+		elements := node.AsArray()
+		table := state.CreateTable(len(elements), 0)
+		for i, element := range elements {
+			table.RawSetInt(i+1, nodeToLua(state, element))
+		}
+		return table
+	default:
+		return lua.LNil
+	}
+}
+
+// setNode mutates the node to the Lua value, the nodes of the tables are allocated in the root.
+func setNode(root *insaneJSON.Root, node *insaneJSON.Node, value lua.LValue, depth int) error {
+	switch v := value.(type) {
+	case lua.LString:
+		node.MutateToString(string(v))
+	case lua.LNumber:
+		f := float64(v)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("number %v can't be encoded to JSON", f)
+		}
+		node.MutateToFloat(f)
+	case lua.LBool:
+		node.MutateToBool(bool(v))
+	case *lua.LNilType:
+		node.MutateToNull()
+	case *lua.LTable:
+		if depth >= maxTableDepth {
+			return errors.New("table is too deep or has a cycle")
+		}
+		return setTable(root, node, v, depth+1)
+	default:
+		return fmt.Errorf("%s value can't be encoded to JSON", value.Type().String())
+	}
+	return nil
+}
+
+// setTable mutates the node to the array if the keys of the table are 1..n, otherwise to the object.
+// The fields of the object are sorted to make the output stable.
+func setTable(root *insaneJSON.Root, node *insaneJSON.Node, table *lua.LTable, depth int) error {
+	type field struct {
+		name  string
+		value lua.LValue
+	}
+
+	var (
+		fields  []field
+		isArray = true
+		err     error
+	)
+	table.ForEach(func(key, value lua.LValue) {
+		switch k := key.(type) {
+		case lua.LString:
+			isArray = false
+			fields = append(fields, field{name: string(k), value: value})
+		case lua.LNumber:
+			fields = append(fields, field{name: k.String(), value: value})
+		default:
+			err = fmt.Errorf("%s key can't be encoded to JSON", key.Type().String())
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	n := len(fields)
+	if isArray && n > 0 && table.MaxN() == n {
+		node.MutateToArray()
+		for i := 1; i <= n; i++ {
+			if err := setNode(root, node.AddElementNoAlloc(root), table.RawGetInt(i), depth); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].name < fields[j].name
+	})
+	node.MutateToObject()
+	for _, f := range fields {
+		if err := setNode(root, node.AddFieldNoAlloc(root, f.name), f.value, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,317 @@
+package script
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ozontech/file.d/cfg"
+	"github.com/ozontech/file.d/fd"
+	"github.com/ozontech/file.d/metric"
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/prometheus/client_golang/prometheus"
+	insaneJSON "github.com/vitkovskii/insane-json"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It runs the Lua script for each event. The script must define the `process(event)` function.
+The function reads and writes the event fields via the `event` object:
+* `event:get(field)` returns the value of the field or `nil` if there is no field.
+The objects and the arrays are returned as the tables.
+* `event:set(field, value)` sets the value of the field, the nested fields are created if needed.
+The tables are set as the objects, or as the arrays if all the keys are `1..n`. Setting `nil` removes the field.
+* `event:remove(field)` removes the field.
+* `event:spawn(table)` emits the new event after the current one, the spawned events go through the next actions.
+The events spawned by the other actions can't spawn the events.
+
+The `field` is the same selector as in the other plugins, e.g. `request.headers.host`.
+If `process` returns `false`, the event is discarded.
+
+The script is compiled once on start. The script runs in the sandbox:
+only the base functions, `string`, `table` and `math` libraries are available,
+the functions which load the code or access the filesystem are removed.
+Each event is processed in `timeout`, otherwise the script is interrupted.
+
+If the script fails, the failure is counted by the `script_errors_total` metric and the `on_error` policy is applied.
+The metric has the `action` label with the index of the action in the pipeline, so the failures of the different scripts are counted apart.
+The changes which the script made before the failure aren't reverted.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: script
+      metric_name: script
+      script: |
+        function process(event)
+          local status = tonumber(event:get("status"))
+          if status == nil then
+            return false
+          end
+          event:set("level", status >= 500 and "error" or "info")
+          if status == 429 then
+            event:spawn({alert = "rate_limited", service = event:get("service")})
+          end
+        end
+    ...
+```
+}*/
+
+const (
+	processFunc = "process"
+
+	onErrorPass    = "pass"
+	onErrorDiscard = "discard"
+
+	// maxTableDepth limits the nesting of the tables set to the event fields, it also stops the cycles.
+	maxTableDepth = 64
+
+	// maxCachedPaths limits the cache of the parsed field selectors,
+	// since the scripts can build the selectors dynamically.
+	maxCachedPaths = 1024
+)
+
+var (
+	// unsafeBaseFuncs load the code or affect the interpreter
+	unsafeBaseFuncs = []string{
+		"collectgarbage", "dofile", "getfenv", "load", "loadfile", "loadstring",
+		"module", "newproxy", "print", "_printregs", "require", "setfenv",
+	}
+
+	// unsafeStringFuncs allocate the unbounded memory in one call, so the timeout can't stop them
+	unsafeStringFuncs = []string{"rep"}
+)
+
+type Plugin struct {
+	config     *Config
+	logger     *zap.Logger
+	controller pipeline.ActionPluginController
+
+	state   *lua.LState
+	process lua.LValue
+	event   *lua.LUserData
+
+	// current holds the event which is processed by the script
+	current *pipeline.Event
+	spawned []*insaneJSON.Node
+	paths   map[string][]string
+	buf     []byte
+
+	// plugin metrics
+	errorsMetric prometheus.Counter
+}
+
+// ! config-params
+// ^ config-params
+type Config struct {
+	// > @3@4@5@6
+	// >
+	// > The Lua script which defines the `process(event)` function.
+	Script string `json:"script" required:"true"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The maximum time of processing one event by the script.
+	Timeout  cfg.Duration `json:"timeout" parse:"duration" default:"10ms"` // *
+	Timeout_ time.Duration
+
+	// > @3@4@5@6
+	// >
+	// > The maximum depth of the Lua function calls.
+	CallStackSize int `json:"call_stack_size" default:"120"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The maximum number of the slots in the Lua value stack (the registry of the interpreter).
+	// > It limits the values on the stack, e.g. the arguments and the locals of the nested calls,
+	// > but not the memory of the tables and the strings which the script creates.
+	MaxStackSize int `json:"max_stack_size" default:"65536"` // *
+
+	// > @3@4@5@6
+	// >
+	// > What to do with the event if the script fails: `pass` it to the next action or `discard` it.
+	OnError string `json:"on_error" default:"pass" options:"pass|discard"` // *
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "script",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger.Desugar()
+	p.controller = params.Controller
+	p.paths = make(map[string][]string)
+	p.registerMetrics(params.MetricCtl, params.Index)
+
+	if p.config.Timeout_ <= 0 {
+		p.logger.Fatal("timeout must be > 0")
+	}
+	if p.config.CallStackSize <= 0 {
+		p.logger.Fatal("call_stack_size must be > 0", zap.Int("call_stack_size", p.config.CallStackSize))
+	}
+	if p.config.MaxStackSize <= 0 {
+		p.logger.Fatal("max_stack_size must be > 0", zap.Int("max_stack_size", p.config.MaxStackSize))
+	}
+
+	proto, err := compile(p.config.Script)
+	if err != nil {
+		p.logger.Fatal("can't compile script", zap.Error(err))
+	}
+
+	p.state = newState(p.config)
+	p.event = p.newEventObject()
+
+	p.state.Push(p.state.NewFunctionFromProto(proto))
+	if err := p.state.PCall(0, 0, nil); err != nil {
+		p.logger.Fatal("can't run script", zap.Error(err))
+	}
+
+	p.process = p.state.GetGlobal(processFunc)
+	if p.process.Type() != lua.LTFunction {
+		p.logger.Fatal("script doesn't define the process function")
+	}
+}
+
+func (p *Plugin) registerMetrics(ctl *metric.Ctl, index int) {
+	p.errorsMetric = ctl.RegisterCounterVec(
+		"script_errors_total",
+		"Number of events which the script failed to process",
+		"action",
+	).WithLabelValues(strconv.Itoa(index))
+}
+
+func compile(script string) (*lua.FunctionProto, error) {
+	chunk, err := parse.Parse(strings.NewReader(script), "script")
+	if err != nil {
+		return nil, err
+	}
+	return lua.Compile(chunk, "script")
+}
+
+func newState(config *Config) *lua.LState {
+	state := lua.NewState(lua.Options{
+		CallStackSize:   config.CallStackSize,
+		RegistryMaxSize: config.MaxStackSize,
+		SkipOpenLibs:    true,
+	})
+
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{name: lua.BaseLibName, open: lua.OpenBase},
+		{name: lua.TabLibName, open: lua.OpenTable},
+		{name: lua.StringLibName, open: lua.OpenString},
+		{name: lua.MathLibName, open: lua.OpenMath},
+	} {
+		state.Push(state.NewFunction(lib.open))
+		state.Push(lua.LString(lib.name))
+		state.Call(1, 0)
+	}
+
+	for _, name := range unsafeBaseFuncs {
+		state.SetGlobal(name, lua.LNil)
+	}
+	stringLib := state.GetGlobal(lua.StringLibName)
+	for _, name := range unsafeStringFuncs {
+		state.SetField(stringLib, name, lua.LNil)
+	}
+
+	return state
+}
+
+func (p *Plugin) Stop() {
+	if p.state != nil {
+		p.state.Close()
+	}
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	p.current = event
+	p.spawned = p.spawned[:0]
+
+	keep, err := p.run()
+	p.current = nil
+	if err != nil {
+		p.errorsMetric.Inc()
+		p.logger.Error("script failed", zap.Error(err))
+		if p.config.OnError == onErrorDiscard {
+			return pipeline.ActionDiscard
+		}
+		return pipeline.ActionPass
+	}
+
+	if len(p.spawned) == 0 {
+		if !keep {
+			return pipeline.ActionDiscard
+		}
+		return pipeline.ActionPass
+	}
+
+	// the parent of the spawned events isn't written by the output,
+	// so the event is spawned as well to pass it further
+	nodes := make([]*insaneJSON.Node, 0, len(p.spawned)+1)
+	if keep {
+		p.buf = event.Root.Encode(p.buf[:0])
+		node, err := event.Root.DecodeStringAdditional(string(p.buf))
+		if err != nil {
+			p.logger.Error("can't copy event", zap.Error(err))
+			return pipeline.ActionPass
+		}
+		nodes = append(nodes, node)
+	}
+	nodes = append(nodes, p.spawned...)
+	p.controller.Spawn(event, nodes)
+
+	return pipeline.ActionBreak
+}
+
+// run calls the process function and reports whether the event should be kept.
+func (p *Plugin) run() (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.config.Timeout_)
+	defer cancel()
+
+	p.state.SetContext(ctx)
+	defer p.state.RemoveContext()
+
+	err := p.state.CallByParam(lua.P{
+		Fn:      p.process,
+		NRet:    1,
+		Protect: true,
+	}, p.event)
+	if err != nil {
+		p.state.SetTop(0)
+		return true, err
+	}
+
+	ret := p.state.Get(-1)
+	p.state.Pop(1)
+
+	return ret != lua.LFalse, nil
+}
+
+func (p *Plugin) path(field string) []string {
+	if path, ok := p.paths[field]; ok {
+		return path
+	}
+
+	path := cfg.ParseFieldSelector(field)
+	if len(p.paths) < maxCachedPaths {
+		p.paths[field] = path
+	}
+	return path
+}
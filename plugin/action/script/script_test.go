@@ -0,0 +1,212 @@
+package script
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/test"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+type fakeController struct {
+	spawned []string
+}
+
+func (c *fakeController) Propagate(_ *pipeline.Event) {}
+
+func (c *fakeController) Spawn(_ *pipeline.Event, nodes []*insaneJSON.Node) {
+	for _, node := range nodes {
+		c.spawned = append(c.spawned, node.EncodeToString())
+	}
+}
+
+func (c *fakeController) IncMaxEventSizeExceeded() {}
+
+func startScript(t *testing.T, config *Config) (*Plugin, *fakeController) {
+	t.Helper()
+
+	ctl := &fakeController{}
+	params := test.NewEmptyActionPluginParams()
+	params.Controller = ctl
+
+	plugin := &Plugin{}
+	plugin.Start(test.NewConfig(config, nil), params)
+	t.Cleanup(plugin.Stop)
+
+	return plugin, ctl
+}
+
+func doScript(t *testing.T, plugin *Plugin, e string) (pipeline.ActionResult, string) {
+	t.Helper()
+
+	root, err := insaneJSON.DecodeString(e)
+	require.NoError(t, err)
+	defer insaneJSON.Release(root)
+
+	result := plugin.Do(&pipeline.Event{Root: root})
+	return result, root.EncodeToString()
+}
+
+func TestScript(t *testing.T) {
+	suits := []struct {
+		name     string
+		script   string
+		input    string
+		result   pipeline.ActionResult
+		expected string
+	}{
+		{
+			name: "get and set",
+			script: `function process(event)
+				local status = tonumber(event:get("request.status"))
+				event:set("level", status >= 500 and "error" or "info")
+				event:set("request.ok", status < 400)
+			end`,
+			input:    `{"request":{"status":"503"}}`,
+			result:   pipeline.ActionPass,
+			expected: `{"request":{"status":"503","ok":false},"level":"error"}`,
+		},
+		{
+			name: "tables",
+			script: `function process(event)
+				local tags = event:get("tags")
+				table.insert(tags, "new")
+				event:set("tags", tags)
+				event:set("meta", {host = event:get("host"), size = #tags, empty = {}})
+			end`,
+			input:    `{"host":"a","tags":["x",1]}`,
+			result:   pipeline.ActionPass,
+			expected: `{"host":"a","tags":["x",1,"new"],"meta":{"empty":{},"host":"a","size":3}}`,
+		},
+		{
+			name: "remove",
+			script: `function process(event)
+				event:remove("a.b")
+				event:set("c", nil)
+			end`,
+			input:    `{"a":{"b":1,"x":2},"c":3}`,
+			result:   pipeline.ActionPass,
+			expected: `{"a":{"x":2}}`,
+		},
+		{
+			name: "drop",
+			script: `function process(event)
+				return event:get("level") ~= "debug"
+			end`,
+			input:    `{"level":"debug"}`,
+			result:   pipeline.ActionDiscard,
+			expected: `{"level":"debug"}`,
+		},
+	}
+
+	for _, tCase := range suits {
+		t.Run(tCase.name, func(t *testing.T) {
+			plugin, _ := startScript(t, &Config{Script: tCase.script})
+
+			result, out := doScript(t, plugin, tCase.input)
+			require.Equal(t, tCase.result, result)
+			require.Equal(t, tCase.expected, out)
+			require.Zero(t, testutil.ToFloat64(plugin.errorsMetric))
+		})
+	}
+}
+
+func TestScriptSpawn(t *testing.T) {
+	plugin, ctl := startScript(t, &Config{Script: `
+		function process(event)
+			event:spawn({alert = event:get("service"), nested = {1, 2}})
+			return event:get("keep")
+		end
+	`})
+
+	result, _ := doScript(t, plugin, `{"service":"api","keep":true}`)
+	require.Equal(t, pipeline.ActionBreak, result)
+	require.Equal(t, []string{
+		`{"service":"api","keep":true}`,
+		`{"alert":"api","nested":[1,2]}`,
+	}, ctl.spawned)
+
+	// the dropped event isn't spawned
+	ctl.spawned = nil
+	result, _ = doScript(t, plugin, `{"service":"api","keep":false}`)
+	require.Equal(t, pipeline.ActionBreak, result)
+	require.Equal(t, []string{`{"alert":"api","nested":[1,2]}`}, ctl.spawned)
+}
+
+func TestScriptErrors(t *testing.T) {
+	suits := []struct {
+		name    string
+		script  string
+		onError string
+		result  pipeline.ActionResult
+	}{
+		{
+			name:   "runtime error",
+			script: `function process(event) error("boom") end`,
+			result: pipeline.ActionPass,
+		},
+		{
+			name:    "discard on error",
+			script:  `function process(event) event:set("a", function() end) end`,
+			onError: onErrorDiscard,
+			result:  pipeline.ActionDiscard,
+		},
+		{
+			name:   "timeout",
+			script: `function process(event) while true do end end`,
+			result: pipeline.ActionPass,
+		},
+		{
+			name:   "sandbox",
+			script: `function process(event) loadstring("return 1")() end`,
+			result: pipeline.ActionPass,
+		},
+	}
+
+	for _, tCase := range suits {
+		t.Run(tCase.name, func(t *testing.T) {
+			plugin, _ := startScript(t, &Config{
+				Script:  tCase.script,
+				Timeout: "50ms",
+				OnError: tCase.onError,
+			})
+
+			start := time.Now()
+			result, _ := doScript(t, plugin, `{}`)
+			require.Less(t, time.Since(start), time.Second)
+			require.Equal(t, tCase.result, result)
+			require.Equal(t, 1.0, testutil.ToFloat64(plugin.errorsMetric))
+
+			// the state is usable after the error
+			_, _ = doScript(t, plugin, `{}`)
+			require.Equal(t, 2.0, testutil.ToFloat64(plugin.errorsMetric))
+		})
+	}
+}
+
+func TestScriptErrorsPerAction(t *testing.T) {
+	params := test.NewEmptyActionPluginParams()
+	params.Controller = &fakeController{}
+
+	start := func(script string, index int) *Plugin {
+		actionParams := *params
+		actionParams.Index = index
+
+		plugin := &Plugin{}
+		plugin.Start(test.NewConfig(&Config{Script: script}, nil), &actionParams)
+		t.Cleanup(plugin.Stop)
+		return plugin
+	}
+
+	failing := start(`function process(event) error("boom") end`, 1)
+	ok := start(`function process(event) return true end`, 3)
+
+	_, _ = doScript(t, failing, `{}`)
+	_, _ = doScript(t, ok, `{}`)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(failing.errorsMetric))
+	require.Zero(t, testutil.ToFloat64(ok.errorsMetric))
+}
@@ -0,0 +1,255 @@
+package validate_schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ozontech/file.d/cfg"
+	"github.com/ozontech/file.d/fd"
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It validates the events against the [JSON Schema](https://json-schema.org) documents.
+The schemas are loaded on start from the `*.json` files of `schemas_dir`, the name of the schema is the file name without the extension.
+The schema of the event is selected by the value of `schema_field`.
+The schemas may refer to each other by the relative file names, e.g. `"$ref": "common.json#/$defs/user"`.
+The schemas without `$schema` keyword are considered to be of the draft 2020-12.
+
+The events which don't match the schema get the list of the violations in `errors_field` or are discarded, depending on `on_violation`.
+The events with no `schema_field` or with the unknown schema pass the plugin as is.
+
+**Example of validating the events before writing them to ClickHouse:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    actions:
+    - type: validate_schema
+      schemas_dir: /etc/file.d/schemas
+      schema_field: schema_name
+      on_violation: add_errors
+      errors_field: schema_errors
+    - type: discard
+      do_if:
+        op: exists
+        field: schema_errors
+    ...
+```
+
+The event `{"schema_name":"order","id":"42"}` for the schema `/etc/file.d/schemas/order.json`
+which requires the integer `id` and the `amount` field becomes:
+```json
+{"schema_name":"order","id":"42","schema_errors":["/: missing properties: 'amount'","/id: expected integer, but got string"]}
+```
+}*/
+
+const (
+	onViolationAddErrors = "add_errors"
+	onViolationDiscard   = "discard"
+
+	schemaExt = ".json"
+)
+
+type Plugin struct {
+	config *Config
+	logger *zap.Logger
+
+	schemas map[string]*schema
+	buf     []byte
+}
+
+type schema struct {
+	schema *jsonschema.Schema
+
+	violationsMetric prometheus.Counter
+}
+
+// ! config-params
+// ^ config-params
+type Config struct {
+	// > @3@4@5@6
+	// >
+	// > The directory with the JSON Schema documents.
+	SchemasDir string `json:"schemas_dir" required:"true"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The event field with the name of the schema to validate the event against.
+	SchemaField  cfg.FieldSelector `json:"schema_field" default:"schema_name" parse:"selector"` // *
+	SchemaField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > What to do with the event which doesn't match the schema:
+	// > `add_errors` puts the violations into `errors_field`, `discard` discards the event.
+	OnViolation string `json:"on_violation" default:"add_errors" options:"add_errors|discard"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The event field to put the list of the violations into.
+	ErrorsField  cfg.FieldSelector `json:"errors_field" default:"schema_errors" parse:"selector"` // *
+	ErrorsField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The maximum number of the violations to put into `errors_field`.
+	MaxErrors int `json:"max_errors" default:"10"` // *
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterAction(&pipeline.PluginStaticInfo{
+		Type:    "validate_schema",
+		Factory: factory,
+	})
+}
+
+func factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.ActionPluginParams) {
+	p.config = config.(*Config)
+	p.logger = params.Logger.Desugar()
+
+	if p.config.MaxErrors <= 0 {
+		p.logger.Fatal("max_errors must be > 0", zap.Int("max_errors", p.config.MaxErrors))
+	}
+
+	schemas, err := loadSchemas(p.config.SchemasDir)
+	if err != nil {
+		p.logger.Fatal("can't load schemas", zap.Error(err))
+	}
+
+	violationsMetric := params.MetricCtl.RegisterCounterVec(
+		"validate_schema_violations_total",
+		"Number of events which don't match the schema",
+		"schema",
+	)
+	p.schemas = make(map[string]*schema, len(schemas))
+	for name, s := range schemas {
+		p.schemas[name] = &schema{
+			schema:           s,
+			violationsMetric: violationsMetric.WithLabelValues(name),
+		}
+	}
+}
+
+func loadSchemas(dir string) (map[string]*jsonschema.Schema, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+
+	schemas := make(map[string]*jsonschema.Schema)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != schemaExt {
+			continue
+		}
+
+		path, err := filepath.Abs(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		s, err := compiler.Compile(path)
+		if err != nil {
+			return nil, fmt.Errorf("compile schema %q: %w", path, err)
+		}
+		schemas[strings.TrimSuffix(entry.Name(), schemaExt)] = s
+	}
+
+	if len(schemas) == 0 {
+		return nil, fmt.Errorf("no %s files in %q", schemaExt, dir)
+	}
+	return schemas, nil
+}
+
+func (p *Plugin) Stop() {
+}
+
+func (p *Plugin) Do(event *pipeline.Event) pipeline.ActionResult {
+	node := event.Root.Dig(p.config.SchemaField_...)
+	if node == nil {
+		return pipeline.ActionPass
+	}
+	s, ok := p.schemas[node.AsString()]
+	if !ok {
+		return pipeline.ActionPass
+	}
+
+	p.buf = event.Root.Encode(p.buf[:0])
+	violations, err := p.validate(s.schema, p.buf)
+	if err != nil {
+		p.logger.Error("can't validate event", zap.Error(err))
+		return pipeline.ActionPass
+	}
+	if len(violations) == 0 {
+		return pipeline.ActionPass
+	}
+
+	s.violationsMetric.Inc()
+	if p.config.OnViolation == onViolationDiscard {
+		return pipeline.ActionDiscard
+	}
+
+	errorsNode := pipeline.CreateNestedField(event.Root, p.config.ErrorsField_).MutateToArray()
+	for _, violation := range violations {
+		errorsNode.AddElementNoAlloc(event.Root).MutateToString(violation)
+	}
+	return pipeline.ActionPass
+}
+
+// validate returns the violations of the schema by the event in the "<instance location>: <message>" form.
+func (p *Plugin) validate(s *jsonschema.Schema, event []byte) ([]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(event))
+	decoder.UseNumber()
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	err := s.Validate(v)
+	if err == nil {
+		return nil, nil
+	}
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil, err
+	}
+
+	// the order of the errors isn't stable, so the violations are sorted to be the same for the same events
+	violations := make([]string, 0)
+	collectViolations(validationErr, &violations)
+	sort.Strings(violations)
+	if len(violations) > p.config.MaxErrors {
+		violations = violations[:p.config.MaxErrors]
+	}
+	return violations, nil
+}
+
+// collectViolations collects the leaf errors, since the rest of them only say that the nested schemas aren't matched.
+func collectViolations(err *jsonschema.ValidationError, violations *[]string) {
+	if len(err.Causes) == 0 {
+		location := err.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		*violations = append(*violations, location+": "+err.Message)
+		return
+	}
+	for _, cause := range err.Causes {
+		collectViolations(cause, violations)
+	}
+}
@@ -0,0 +1,136 @@
+package validate_schema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/test"
+	"github.com/stretchr/testify/require"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+func writeSchemas(t *testing.T, schemas map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range schemas {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	return dir
+}
+
+var testSchemas = map[string]string{
+	"order.json": `{
+		"type": "object",
+		"required": ["id", "amount"],
+		"properties": {
+			"id": {"type": "integer"},
+			"amount": {"type": "number", "minimum": 0},
+			"user": {"$ref": "common.json#/$defs/user"}
+		}
+	}`,
+	"common.json": `{
+		"$defs": {
+			"user": {
+				"type": "object",
+				"required": ["name"],
+				"properties": {"name": {"type": "string", "pattern": "^[a-z]+$"}}
+			}
+		}
+	}`,
+	"README.md": `not a schema`,
+}
+
+func startValidate(t *testing.T, config *Config) *Plugin {
+	t.Helper()
+
+	plugin := &Plugin{}
+	plugin.Start(test.NewConfig(config, nil), test.NewEmptyActionPluginParams())
+	t.Cleanup(plugin.Stop)
+
+	return plugin
+}
+
+func doValidate(t *testing.T, plugin *Plugin, e string) (pipeline.ActionResult, string) {
+	t.Helper()
+
+	root, err := insaneJSON.DecodeString(e)
+	require.NoError(t, err)
+	defer insaneJSON.Release(root)
+
+	result := plugin.Do(&pipeline.Event{Root: root})
+	return result, root.EncodeToString()
+}
+
+func TestValidateSchema(t *testing.T) {
+	plugin := startValidate(t, &Config{
+		SchemasDir:  writeSchemas(t, testSchemas),
+		ErrorsField: "meta.errors",
+	})
+
+	cases := []struct {
+		name string
+		in   string
+		out  string
+	}{
+		{
+			name: "valid",
+			in:   `{"schema_name":"order","id":1,"amount":10.5,"user":{"name":"bob"}}`,
+			out:  `{"schema_name":"order","id":1,"amount":10.5,"user":{"name":"bob"}}`,
+		},
+		{
+			name: "invalid",
+			in:   `{"schema_name":"order","id":"1"}`,
+			out:  `{"schema_name":"order","id":"1","meta":{"errors":["/: missing properties: 'amount'","/id: expected integer, but got string"]}}`,
+		},
+		{
+			name: "invalid_ref",
+			in:   `{"schema_name":"order","id":1,"amount":-1,"user":{"name":"Bob"}}`,
+			out: `{"schema_name":"order","id":1,"amount":-1,"user":{"name":"Bob"},` +
+				`"meta":{"errors":["/amount: must be >= 0 but found -1","/user/name: does not match pattern '^[a-z]+$'"]}}`,
+		},
+		{
+			name: "unknown_schema",
+			in:   `{"schema_name":"common_order","id":"1"}`,
+			out:  `{"schema_name":"common_order","id":"1"}`,
+		},
+		{
+			name: "no_schema",
+			in:   `{"id":"1"}`,
+			out:  `{"id":"1"}`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, out := doValidate(t, plugin, tc.in)
+			require.Equal(t, pipeline.ActionPass, result)
+			require.Equal(t, tc.out, out)
+		})
+	}
+}
+
+func TestValidateSchemaDiscard(t *testing.T) {
+	plugin := startValidate(t, &Config{
+		SchemasDir:  writeSchemas(t, testSchemas),
+		SchemaField: "meta.schema",
+		OnViolation: onViolationDiscard,
+	})
+
+	result, _ := doValidate(t, plugin, `{"meta":{"schema":"order"},"id":1,"amount":1}`)
+	require.Equal(t, pipeline.ActionPass, result)
+
+	result, _ = doValidate(t, plugin, `{"meta":{"schema":"order"},"id":1}`)
+	require.Equal(t, pipeline.ActionDiscard, result)
+}
+
+func TestValidateSchemaMaxErrors(t *testing.T) {
+	plugin := startValidate(t, &Config{
+		SchemasDir: writeSchemas(t, testSchemas),
+		MaxErrors:  1,
+	})
+
+	_, out := doValidate(t, plugin, `{"schema_name":"order","id":"1"}`)
+	require.Equal(t, `{"schema_name":"order","id":"1","schema_errors":["/: missing properties: 'amount'"]}`, out)
+}
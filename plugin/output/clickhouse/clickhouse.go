@@ -7,6 +7,7 @@ import (
 	"errors"
 	"net"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ClickHouse/ch-go"
@@ -48,7 +49,13 @@ type Plugin struct {
 	ctx        context.Context
 	cancelFunc context.CancelFunc
 
-	query string
+	schema   atomic.Pointer[schema]
+	schemaMu sync.Mutex
+	// addColumnsMu serializes adding the columns, failedColumns holds the backoffs of the columns which aren't added.
+	addColumnsMu  sync.Mutex
+	failedColumns map[string]*columnBackoff
+	// columnFields is the reverse of field_renames.
+	columnFields map[string]string
 
 	// TODO: support shards
 	instances []Clickhouse
@@ -193,11 +200,53 @@ type Config struct {
 	// > * Bool
 	// > * Nullable
 	// > * IPv4, IPv6
+	// > * UUID
+	// > * Decimal(P, S), Decimal32(S), Decimal64(S), Decimal128(S), Decimal256(S)
 	// > * LowCardinality(String)
-	// > * Array(String)
+	// > * Array(T) of any supported type
+	// > * Map(String, T), the value is taken from the JSON object
+	// > * Tuple(T1, T2, ...), the value is taken from the JSON array,
+	// > and Tuple(a T1, b T2, ...), the value is taken from the JSON object
+	// > * Nested(a T1, b T2, ...), the value is taken from the array of the JSON objects.
+	// > It's inserted to the `name.a Array(T1)`, `name.b Array(T2)` columns, so `flatten_nested` setting must be enabled.
+	// >
+	// > The wrong elements of Array, Map, Tuple and Nested are replaced with the zero values.
 	// >
 	// > If you need more types, please, create an issue.
-	Columns []Column `json:"columns" required:"true"` // *
+	Columns []Column `json:"columns"` // *
+
+	// > @3@4@5@6
+	// >
+	// > If set, the columns are read from `system.columns` at start and every `schema_refresh_interval`,
+	// > so `columns` must be empty. The columns are mapped to the event fields with the same names, see `field_renames`.
+	// >
+	// > MATERIALIZED, ALIAS and EPHEMERAL columns and the columns of the unsupported types are skipped.
+	// > Note that the columns with DEFAULT expression get the zero values for the events without the field.
+	AutoSchema bool `json:"auto_schema" default:"false"` // *
+
+	// > @3@4@5@6
+	// >
+	// > How often to read the table schema if `auto_schema` is set. Zero disables the refreshing.
+	SchemaRefreshInterval  cfg.Duration `json:"schema_refresh_interval" default:"1m" parse:"duration"` // *
+	SchemaRefreshInterval_ time.Duration
+
+	// > @3@4@5@6
+	// >
+	// > The renames of the event fields to the column names.
+	// > E.g. `{"@timestamp": "ts"}` means that `ts` column takes the value from `@timestamp` field.
+	FieldRenames map[string]string `json:"field_renames"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The allowlist of the top level event fields which are added to the table
+	// > with `ALTER TABLE ADD COLUMN` if the table doesn't have the columns for them. It requires `auto_schema`.
+	// > The columns are added on every instance of `endpoints`, the failed ones are retried with the exponential backoff.
+	AddColumns []string `json:"add_columns" slice:"true"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The type of the columns added for `add_columns`.
+	AddColumnsType string `json:"add_columns_type" default:"Nullable(String)"` // *
 
 	// > @3@4@5@6
 	// >
@@ -212,6 +261,7 @@ type Config struct {
 	// > File.d converts the Object to "0" to prevent fall.
 	// >
 	// > In the non-strict mode, for String and Array(String) columns the value will be encoded to JSON.
+	// > If the value of Array column isn't an array, it's appended as the only array element.
 	// >
 	// > If the strict mode is enabled file.d fails (exit with code 1) in above examples.
 	StrictTypes bool `json:"strict_types" default:"false"` // *
@@ -347,12 +397,27 @@ func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.OutputPluginP
 		p.logger.Fatal("'db_request_timeout' can't be <1")
 	}
 
-	schema, err := inferInsaneColInputs(p.config.Columns)
-	if err != nil {
-		p.logger.Fatal("invalid database schema", zap.Error(err))
+	switch {
+	case p.config.AutoSchema && len(p.config.Columns) > 0:
+		p.logger.Fatal("'columns' must be empty if 'auto_schema' is set")
+	case !p.config.AutoSchema && len(p.config.Columns) == 0:
+		p.logger.Fatal("'columns' must be set if 'auto_schema' isn't set")
+	case !p.config.AutoSchema && len(p.config.AddColumns) > 0:
+		p.logger.Fatal("'add_columns' requires 'auto_schema'")
+	}
+
+	p.columnFields = make(map[string]string, len(p.config.FieldRenames))
+	for field, column := range p.config.FieldRenames {
+		p.columnFields[column] = field
+	}
+
+	if !p.config.AutoSchema {
+		schema, err := p.newSchema(p.config.Columns)
+		if err != nil {
+			p.logger.Fatal("invalid database schema", zap.Error(err))
+		}
+		p.schema.Store(schema)
 	}
-	input := inputFromColumns(schema)
-	p.query = input.Into(p.config.Table)
 
 	switch p.config.InsertStrategy {
 	case "round_robin":
@@ -414,6 +479,15 @@ func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.OutputPluginP
 		}
 	}
 
+	if p.config.AutoSchema {
+		if err := p.refreshSchema(p.ctx); err != nil {
+			p.logger.Fatal("can't discover database schema", zap.Error(err))
+		}
+		if p.config.SchemaRefreshInterval_ > 0 {
+			go p.refreshSchemaLoop()
+		}
+	}
+
 	batcherOpts := pipeline.BatcherOptions{
 		PipelineName:   params.PipelineName,
 		OutputType:     outPluginType,
@@ -467,11 +541,12 @@ func (p *Plugin) Out(event *pipeline.Event) {
 }
 
 type data struct {
-	cols  []InsaneColumn
-	input proto.Input
+	cols   []InsaneColumn
+	input  proto.Input
+	schema *schema
 }
 
-func (d data) reset() {
+func (d *data) reset() {
 	for i := range d.cols {
 		d.cols[i].ColInput.Reset()
 	}
@@ -479,21 +554,25 @@ func (d data) reset() {
 
 func (p *Plugin) out(workerData *pipeline.WorkerData, batch *pipeline.Batch) error {
 	if *workerData == nil {
-		// we don't check the error, schema already validated in the Start
-		columns, _ := inferInsaneColInputs(p.config.Columns)
-		input := inputFromColumns(columns)
-		*workerData = data{
-			cols:  columns,
-			input: input,
-		}
+		*workerData = &data{}
 	}
+	data := (*workerData).(*data)
 
-	data := (*workerData).(data)
+	schema := p.schema.Load()
+	if len(schema.missingFields) > 0 {
+		schema = p.addMissingColumns(schema, batch)
+	}
+	if data.schema != schema {
+		// we don't check the error, schema already validated
+		data.cols, _ = p.inferColumns(schema.columns)
+		data.input = inputFromColumns(data.cols)
+		data.schema = schema
+	}
 	data.reset()
 
 	batch.ForEach(func(event *pipeline.Event) {
 		for _, col := range data.cols {
-			node := event.Root.Dig(col.Name)
+			node := event.Root.Dig(col.Field)
 
 			var insaneNode InsaneNode
 			if node != nil && p.config.StrictTypes {
@@ -521,7 +600,7 @@ func (p *Plugin) out(workerData *pipeline.WorkerData, batch *pipeline.Batch) err
 	for i := range p.instances {
 		requestID := p.requestID.Inc()
 		clickhouse := p.getInstance(requestID, i)
-		err := p.do(clickhouse, data.input)
+		err := p.do(clickhouse, schema.query, data.input)
 		if err == nil {
 			return nil
 		}
@@ -537,14 +616,14 @@ func (p *Plugin) out(workerData *pipeline.WorkerData, batch *pipeline.Batch) err
 	return err
 }
 
-func (p *Plugin) do(clickhouse Clickhouse, queryInput proto.Input) error {
+func (p *Plugin) do(clickhouse Clickhouse, query string, queryInput proto.Input) error {
 	defer p.queriesCountMetric.Inc()
 
 	ctx, cancel := context.WithTimeout(p.ctx, p.config.InsertTimeout_)
 	defer cancel()
 
 	return clickhouse.Do(ctx, ch.Query{
-		Body:  p.query,
+		Body:  query,
 		Input: queryInput,
 	})
 }
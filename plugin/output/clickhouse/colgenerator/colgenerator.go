@@ -72,6 +72,7 @@ func clickhouseTypes() []Type {
 			ChTypeName: "Bool",
 			GoName:     "bool",
 			Nullable:   true,
+			Array:      true,
 		},
 		{
 			ChTypeName:     "String",
@@ -105,6 +106,7 @@ func clickhouseTypes() []Type {
 				GoName:      goName,
 				Convertable: true,
 				Nullable:    true,
+				Array:       true,
 			})
 		}
 	}
@@ -116,6 +118,7 @@ func clickhouseTypes() []Type {
 			Convertable:     true,
 			Nullable:        true,
 			isComplexNumber: true,
+			Array:           true,
 		},
 		Type{
 			ChTypeName:      "UInt128",
@@ -123,6 +126,7 @@ func clickhouseTypes() []Type {
 			Convertable:     true,
 			Nullable:        true,
 			isComplexNumber: true,
+			Array:           true,
 		},
 		Type{
 			ChTypeName:      "Int256",
@@ -130,6 +134,7 @@ func clickhouseTypes() []Type {
 			Convertable:     true,
 			Nullable:        true,
 			isComplexNumber: true,
+			Array:           true,
 		},
 		Type{
 			ChTypeName:      "UInt256",
@@ -137,16 +142,19 @@ func clickhouseTypes() []Type {
 			Convertable:     true,
 			Nullable:        true,
 			isComplexNumber: true,
+			Array:           true,
 		},
 		Type{
 			ChTypeName: "Float32",
 			GoName:     "float32",
 			Nullable:   true,
+			Array:      true,
 		},
 		Type{
 			ChTypeName: "Float64",
 			GoName:     "float64",
 			Nullable:   true,
+			Array:      true,
 		},
 		Type{
 			ChTypeName: "DateTime",
@@ -162,16 +170,19 @@ func clickhouseTypes() []Type {
 			ChTypeName: "IPv4",
 			GoName:     IPv4Name,
 			Nullable:   true,
+			Array:      true,
 		},
 		Type{
 			ChTypeName: "IPv6",
 			GoName:     IPv6Name,
 			Nullable:   true,
+			Array:      true,
 		},
 		Type{
 			ChTypeName: "UUID",
 			GoName:     UUIDName,
 			Nullable:   true,
+			Array:      true,
 		},
 	)
 
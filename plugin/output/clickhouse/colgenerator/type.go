@@ -19,12 +19,18 @@ type Type struct {
 	CustomImpl bool
 	// LowCardinality truth if the type can be low cardinality.
 	LowCardinality bool
+	// Array truth if the column for the Array of the type must be generated.
+	Array bool
 }
 
 func (t Type) ColumnTypeName() string {
 	return "Col" + t.ChTypeName
 }
 
+func (t Type) ArrayColumnTypeName() string {
+	return t.ColumnTypeName() + "Array"
+}
+
 func (t Type) ConvertInsaneJSONValue() string {
 	if t.isComplexNumber {
 		return fmt.Sprintf("%sFromInt", t.GoName)
@@ -32,3 +32,84 @@ func (t *ColStringArray) Append(array InsaneNode) error {
 
 	return nil
 }
+
+// ColArray represents Clickhouse Array type of the types which don't have the generated array column,
+// e.g. Array(Nullable(Int64)) or Array(Map(String, String)).
+type ColArray struct {
+	// offsets contains the end positions of the arrays in the data.
+	offsets proto.ColUInt64
+	data    InsaneColInput
+
+	// field is the name of the object field which is taken from every array element.
+	// It's used for the subcolumns of the Nested type.
+	field string
+}
+
+var (
+	_ InsaneColInput     = (*ColArray)(nil)
+	_ proto.StateEncoder = (*ColArray)(nil)
+	_ proto.Preparable   = (*ColArray)(nil)
+)
+
+func NewColArray(data InsaneColInput) *ColArray {
+	return &ColArray{
+		data: data,
+	}
+}
+
+// NewColNestedArray returns the column of the Nested subcolumn
+// containing the field values of the objects in the array.
+func NewColNestedArray(data InsaneColInput, field string) *ColArray {
+	return &ColArray{
+		data:  data,
+		field: field,
+	}
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColArray) Append(array InsaneNode) error {
+	var nodes []InsaneNode
+	if array != nil && !array.IsNull() {
+		var err error
+		nodes, err = array.AsNodeArray()
+		if err != nil {
+			return fmt.Errorf("converting node to the array: %w", err)
+		}
+	}
+
+	for _, node := range nodes {
+		if t.field != "" {
+			node = objectField(node, t.field)
+		}
+		appendOrZero(t.data, node)
+	}
+	t.offsets.Append(uint64(t.data.Rows()))
+
+	return nil
+}
+
+func (t *ColArray) Reset() {
+	t.offsets.Reset()
+	t.data.Reset()
+}
+
+func (t *ColArray) Type() proto.ColumnType {
+	return proto.ColumnTypeArray.Sub(t.data.Type())
+}
+
+func (t *ColArray) Rows() int {
+	return t.offsets.Rows()
+}
+
+func (t *ColArray) EncodeColumn(buffer *proto.Buffer) {
+	t.offsets.EncodeColumn(buffer)
+	t.data.EncodeColumn(buffer)
+}
+
+func (t *ColArray) EncodeState(buffer *proto.Buffer) {
+	encodeState(buffer, t.data)
+}
+
+func (t *ColArray) Prepare() error {
+	return prepare(t.data)
+}
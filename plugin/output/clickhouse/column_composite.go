@@ -0,0 +1,255 @@
+package clickhouse
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+// ColMap represents Clickhouse Map(String, T) type.
+type ColMap struct {
+	// offsets contains the end positions of the maps in the keys and the values.
+	offsets proto.ColUInt64
+	keys    proto.ColStr
+	values  InsaneColInput
+}
+
+var (
+	_ InsaneColInput     = (*ColMap)(nil)
+	_ proto.StateEncoder = (*ColMap)(nil)
+	_ proto.Preparable   = (*ColMap)(nil)
+)
+
+func NewColMap(values InsaneColInput) *ColMap {
+	return &ColMap{
+		values: values,
+	}
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColMap) Append(node InsaneNode) error {
+	var fields []InsaneField
+	if node != nil && !node.IsNull() {
+		var err error
+		fields, err = node.AsNodeMap()
+		if err != nil {
+			return fmt.Errorf("converting node to the map: %w", err)
+		}
+	}
+
+	for _, field := range fields {
+		t.keys.Append(field.Name)
+		appendOrZero(t.values, field.Value)
+	}
+	t.offsets.Append(uint64(t.keys.Rows()))
+
+	return nil
+}
+
+func (t *ColMap) Reset() {
+	t.offsets.Reset()
+	t.keys.Reset()
+	t.values.Reset()
+}
+
+func (t *ColMap) Type() proto.ColumnType {
+	return proto.ColumnTypeMap.Sub(proto.ColumnTypeString, t.values.Type())
+}
+
+func (t *ColMap) Rows() int {
+	return t.offsets.Rows()
+}
+
+func (t *ColMap) EncodeColumn(buffer *proto.Buffer) {
+	t.offsets.EncodeColumn(buffer)
+	t.keys.EncodeColumn(buffer)
+	t.values.EncodeColumn(buffer)
+}
+
+func (t *ColMap) EncodeState(buffer *proto.Buffer) {
+	encodeState(buffer, t.values)
+}
+
+func (t *ColMap) Prepare() error {
+	return prepare(t.values)
+}
+
+// ColTuple represents Clickhouse Tuple type.
+// The values of the named tuple are taken from the JSON object fields,
+// the values of the unnamed tuple are taken from the JSON array elements.
+type ColTuple struct {
+	// names contains the element names of the named tuple.
+	names []string
+	cols  []InsaneColInput
+}
+
+var (
+	_ InsaneColInput     = (*ColTuple)(nil)
+	_ proto.StateEncoder = (*ColTuple)(nil)
+	_ proto.Preparable   = (*ColTuple)(nil)
+)
+
+// NewColTuple returns the column of the tuple, names are nil for the unnamed tuple.
+func NewColTuple(names []string, cols []InsaneColInput) *ColTuple {
+	return &ColTuple{
+		names: names,
+		cols:  cols,
+	}
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColTuple) Append(node InsaneNode) error {
+	if node == nil || node.IsNull() {
+		return ErrNodeIsNil
+	}
+
+	if t.names != nil {
+		fields, err := node.AsNodeMap()
+		if err != nil {
+			return fmt.Errorf("converting node to the named tuple: %w", err)
+		}
+		for i, col := range t.cols {
+			var value InsaneNode
+			for _, field := range fields {
+				if field.Name == t.names[i] {
+					value = field.Value
+					break
+				}
+			}
+			appendOrZero(col, value)
+		}
+		return nil
+	}
+
+	nodes, err := node.AsNodeArray()
+	if err != nil {
+		return fmt.Errorf("converting node to the tuple: %w", err)
+	}
+	for i, col := range t.cols {
+		var value InsaneNode
+		if i < len(nodes) {
+			value = nodes[i]
+		}
+		appendOrZero(col, value)
+	}
+
+	return nil
+}
+
+func (t *ColTuple) Reset() {
+	for _, col := range t.cols {
+		col.Reset()
+	}
+}
+
+func (t *ColTuple) Type() proto.ColumnType {
+	elems := make([]string, len(t.cols))
+	for i, col := range t.cols {
+		elems[i] = col.Type().String()
+		if t.names != nil {
+			elems[i] = t.names[i] + " " + elems[i]
+		}
+	}
+	return proto.ColumnTypeTuple.With(elems...)
+}
+
+func (t *ColTuple) Rows() int {
+	return t.cols[0].Rows()
+}
+
+func (t *ColTuple) EncodeColumn(buffer *proto.Buffer) {
+	for _, col := range t.cols {
+		col.EncodeColumn(buffer)
+	}
+}
+
+func (t *ColTuple) EncodeState(buffer *proto.Buffer) {
+	for _, col := range t.cols {
+		encodeState(buffer, col)
+	}
+}
+
+func (t *ColTuple) Prepare() error {
+	for _, col := range t.cols {
+		if err := prepare(col); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendOrZero appends the node to the column of the composite type or the zero value if the node is wrong,
+// so the composite column never has the partially appended value.
+func appendOrZero(col InsaneColInput, node InsaneNode) {
+	if err := col.Append(node); err != nil {
+		// the zero value is always appended successfully
+		_ = col.Append(ZeroValueNode{})
+	}
+}
+
+// objectField returns the value of the object field or nil if there is no such field.
+func objectField(node InsaneNode, name string) InsaneNode {
+	fields, err := node.AsNodeMap()
+	if err != nil {
+		return nil
+	}
+	for _, field := range fields {
+		if field.Name == name {
+			return field.Value
+		}
+	}
+	return nil
+}
+
+func encodeState(buffer *proto.Buffer, col InsaneColInput) {
+	if col, ok := col.(proto.StateEncoder); ok {
+		col.EncodeState(buffer)
+	}
+}
+
+func prepare(col InsaneColInput) error {
+	if col, ok := col.(proto.Preparable); ok {
+		return col.Prepare()
+	}
+	return nil
+}
+
+// splitTypeArgs splits the arguments of the composite type like `String, Tuple(a UInt8, b String)`
+// by the top level commas.
+func splitTypeArgs(args proto.ColumnType) []string {
+	var (
+		result []string
+		depth  int
+		quoted bool
+		start  int
+	)
+	s := args.String()
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'' && (i == 0 || s[i-1] != '\\'):
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			result = append(result, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		result = append(result, last)
+	}
+	return result
+}
+
+// splitNamedTypeArg splits the element of the named tuple or nested like `a UInt8` to the name and the type.
+func splitNamedTypeArg(arg string) (string, proto.ColumnType, bool) {
+	name, typ, found := strings.Cut(arg, " ")
+	if !found || strings.ContainsAny(name, "(,'") {
+		return "", proto.ColumnType(arg), false
+	}
+	return strings.Trim(name, "`\""), proto.ColumnType(strings.TrimSpace(typ)), true
+}
@@ -3,6 +3,7 @@ package clickhouse
 import (
 	"errors"
 	"fmt"
+	"strconv"
 
 	"github.com/ClickHouse/ch-go/proto"
 )
@@ -177,3 +178,99 @@ func (t *ColString) EncodeState(b *proto.Buffer) {
 		t.lcCol.EncodeState(b)
 	}
 }
+
+// ColDecimal represents Clickhouse Decimal(P, S) type.
+type ColDecimal struct {
+	// typ is the decimal type with the precision and the scale.
+	typ proto.ColumnType
+	// scale is the count of the digits after the decimal point.
+	scale int64
+
+	// col contains values for the Decimal32, Decimal64, Decimal128 or Decimal256 type depending on the precision.
+	col proto.Column
+	// appendFn appends the value truncated to the size of the col.
+	appendFn func(v proto.Int256)
+
+	// nulls contains null map for the Nullable(Decimal) type.
+	nulls proto.ColUInt8
+	// nullable the truth if the column is nullable.
+	nullable bool
+}
+
+var _ InsaneColInput = (*ColDecimal)(nil)
+
+func NewColDecimal(precision, scale int, nullable bool) *ColDecimal {
+	t := &ColDecimal{
+		typ:      columnTypeDecimal.With(strconv.Itoa(precision), strconv.Itoa(scale)),
+		scale:    int64(scale),
+		nullable: nullable,
+	}
+
+	switch {
+	case precision <= 9:
+		col := new(proto.ColDecimal32)
+		t.col = col
+		t.appendFn = func(v proto.Int256) { col.Append(proto.Decimal32(v.Low.Low)) }
+	case precision <= 18:
+		col := new(proto.ColDecimal64)
+		t.col = col
+		t.appendFn = func(v proto.Int256) { col.Append(proto.Decimal64(v.Low.Low)) }
+	case precision <= 38:
+		col := new(proto.ColDecimal128)
+		t.col = col
+		t.appendFn = func(v proto.Int256) { col.Append(proto.Decimal128{Low: v.Low.Low, High: v.Low.High}) }
+	default:
+		col := new(proto.ColDecimal256)
+		t.col = col
+		t.appendFn = func(v proto.Int256) { col.Append(proto.Decimal256(v)) }
+	}
+
+	return t
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColDecimal) Append(node InsaneNode) error {
+	if node == nil || node.IsNull() {
+		if !t.nullable {
+			return ErrNodeIsNil
+		}
+		t.nulls.Append(1)
+		t.appendFn(proto.Int256{})
+		return nil
+	}
+
+	val, err := node.AsDecimal(t.scale)
+	if err != nil {
+		return fmt.Errorf("converting node to the decimal: %w", err)
+	}
+
+	if t.nullable {
+		t.nulls.Append(0)
+	}
+	t.appendFn(val)
+
+	return nil
+}
+
+func (t *ColDecimal) Reset() {
+	t.col.Reset()
+	t.nulls.Reset()
+}
+
+func (t *ColDecimal) Type() proto.ColumnType {
+	if t.nullable {
+		return proto.ColumnTypeNullable.Sub(t.typ)
+	}
+	return t.typ
+}
+
+func (t *ColDecimal) Rows() int {
+	return t.col.Rows()
+}
+
+func (t *ColDecimal) EncodeColumn(buffer *proto.Buffer) {
+	if t.nullable {
+		t.nulls.EncodeColumn(buffer)
+	}
+	t.col.EncodeColumn(buffer)
+}
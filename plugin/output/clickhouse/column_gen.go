@@ -80,6 +80,61 @@ func (t *ColBool) EncodeColumn(buffer *proto.Buffer) {
 	t.col.EncodeColumn(buffer)
 }
 
+// ColBoolArray represents Clickhouse Array(Bool) type.
+type ColBoolArray struct {
+	// col contains values for the Array(Bool) type.
+	col *proto.ColArr[bool]
+	// vals is the buffer for the values of the appended array.
+	vals []bool
+}
+
+var _ InsaneColInput = (*ColBoolArray)(nil)
+
+func NewColBoolArray() *ColBoolArray {
+	return &ColBoolArray{
+		col: new(proto.ColBool).Array(),
+	}
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColBoolArray) Append(array InsaneNode) error {
+	t.vals = t.vals[:0]
+	if array != nil && !array.IsNull() {
+		nodes, err := array.AsNodeArray()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			val, err := node.AsBool()
+			if err != nil {
+				return err
+			}
+			t.vals = append(t.vals, val)
+		}
+	}
+
+	t.col.Append(t.vals)
+
+	return nil
+}
+
+func (t *ColBoolArray) Reset() {
+	t.col.Reset()
+}
+
+func (t *ColBoolArray) Type() proto.ColumnType {
+	return t.col.Type()
+}
+
+func (t *ColBoolArray) Rows() int {
+	return t.col.Rows()
+}
+
+func (t *ColBoolArray) EncodeColumn(buffer *proto.Buffer) {
+	t.col.EncodeColumn(buffer)
+}
+
 // struct ColString defined and implemented in another file
 
 var _ InsaneColInput = (*ColString)(nil)
@@ -258,6 +313,61 @@ func (t *ColInt8) EncodeColumn(buffer *proto.Buffer) {
 	t.col.EncodeColumn(buffer)
 }
 
+// ColInt8Array represents Clickhouse Array(Int8) type.
+type ColInt8Array struct {
+	// col contains values for the Array(Int8) type.
+	col *proto.ColArr[int8]
+	// vals is the buffer for the values of the appended array.
+	vals []int8
+}
+
+var _ InsaneColInput = (*ColInt8Array)(nil)
+
+func NewColInt8Array() *ColInt8Array {
+	return &ColInt8Array{
+		col: new(proto.ColInt8).Array(),
+	}
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColInt8Array) Append(array InsaneNode) error {
+	t.vals = t.vals[:0]
+	if array != nil && !array.IsNull() {
+		nodes, err := array.AsNodeArray()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			v, err := node.AsInt()
+			if err != nil {
+				return err
+			}
+			t.vals = append(t.vals, int8(v))
+		}
+	}
+
+	t.col.Append(t.vals)
+
+	return nil
+}
+
+func (t *ColInt8Array) Reset() {
+	t.col.Reset()
+}
+
+func (t *ColInt8Array) Type() proto.ColumnType {
+	return t.col.Type()
+}
+
+func (t *ColInt8Array) Rows() int {
+	return t.col.Rows()
+}
+
+func (t *ColInt8Array) EncodeColumn(buffer *proto.Buffer) {
+	t.col.EncodeColumn(buffer)
+}
+
 // ColUInt8 represents Clickhouse UInt8 type.
 type ColUInt8 struct {
 	// col contains values for the UInt8 type.
@@ -333,6 +443,61 @@ func (t *ColUInt8) EncodeColumn(buffer *proto.Buffer) {
 	t.col.EncodeColumn(buffer)
 }
 
+// ColUInt8Array represents Clickhouse Array(UInt8) type.
+type ColUInt8Array struct {
+	// col contains values for the Array(UInt8) type.
+	col *proto.ColArr[uint8]
+	// vals is the buffer for the values of the appended array.
+	vals []uint8
+}
+
+var _ InsaneColInput = (*ColUInt8Array)(nil)
+
+func NewColUInt8Array() *ColUInt8Array {
+	return &ColUInt8Array{
+		col: new(proto.ColUInt8).Array(),
+	}
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColUInt8Array) Append(array InsaneNode) error {
+	t.vals = t.vals[:0]
+	if array != nil && !array.IsNull() {
+		nodes, err := array.AsNodeArray()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			v, err := node.AsInt()
+			if err != nil {
+				return err
+			}
+			t.vals = append(t.vals, uint8(v))
+		}
+	}
+
+	t.col.Append(t.vals)
+
+	return nil
+}
+
+func (t *ColUInt8Array) Reset() {
+	t.col.Reset()
+}
+
+func (t *ColUInt8Array) Type() proto.ColumnType {
+	return t.col.Type()
+}
+
+func (t *ColUInt8Array) Rows() int {
+	return t.col.Rows()
+}
+
+func (t *ColUInt8Array) EncodeColumn(buffer *proto.Buffer) {
+	t.col.EncodeColumn(buffer)
+}
+
 // ColInt16 represents Clickhouse Int16 type.
 type ColInt16 struct {
 	// col contains values for the Int16 type.
@@ -408,6 +573,61 @@ func (t *ColInt16) EncodeColumn(buffer *proto.Buffer) {
 	t.col.EncodeColumn(buffer)
 }
 
+// ColInt16Array represents Clickhouse Array(Int16) type.
+type ColInt16Array struct {
+	// col contains values for the Array(Int16) type.
+	col *proto.ColArr[int16]
+	// vals is the buffer for the values of the appended array.
+	vals []int16
+}
+
+var _ InsaneColInput = (*ColInt16Array)(nil)
+
+func NewColInt16Array() *ColInt16Array {
+	return &ColInt16Array{
+		col: new(proto.ColInt16).Array(),
+	}
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColInt16Array) Append(array InsaneNode) error {
+	t.vals = t.vals[:0]
+	if array != nil && !array.IsNull() {
+		nodes, err := array.AsNodeArray()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			v, err := node.AsInt()
+			if err != nil {
+				return err
+			}
+			t.vals = append(t.vals, int16(v))
+		}
+	}
+
+	t.col.Append(t.vals)
+
+	return nil
+}
+
+func (t *ColInt16Array) Reset() {
+	t.col.Reset()
+}
+
+func (t *ColInt16Array) Type() proto.ColumnType {
+	return t.col.Type()
+}
+
+func (t *ColInt16Array) Rows() int {
+	return t.col.Rows()
+}
+
+func (t *ColInt16Array) EncodeColumn(buffer *proto.Buffer) {
+	t.col.EncodeColumn(buffer)
+}
+
 // ColUInt16 represents Clickhouse UInt16 type.
 type ColUInt16 struct {
 	// col contains values for the UInt16 type.
@@ -483,6 +703,61 @@ func (t *ColUInt16) EncodeColumn(buffer *proto.Buffer) {
 	t.col.EncodeColumn(buffer)
 }
 
+// ColUInt16Array represents Clickhouse Array(UInt16) type.
+type ColUInt16Array struct {
+	// col contains values for the Array(UInt16) type.
+	col *proto.ColArr[uint16]
+	// vals is the buffer for the values of the appended array.
+	vals []uint16
+}
+
+var _ InsaneColInput = (*ColUInt16Array)(nil)
+
+func NewColUInt16Array() *ColUInt16Array {
+	return &ColUInt16Array{
+		col: new(proto.ColUInt16).Array(),
+	}
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColUInt16Array) Append(array InsaneNode) error {
+	t.vals = t.vals[:0]
+	if array != nil && !array.IsNull() {
+		nodes, err := array.AsNodeArray()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			v, err := node.AsInt()
+			if err != nil {
+				return err
+			}
+			t.vals = append(t.vals, uint16(v))
+		}
+	}
+
+	t.col.Append(t.vals)
+
+	return nil
+}
+
+func (t *ColUInt16Array) Reset() {
+	t.col.Reset()
+}
+
+func (t *ColUInt16Array) Type() proto.ColumnType {
+	return t.col.Type()
+}
+
+func (t *ColUInt16Array) Rows() int {
+	return t.col.Rows()
+}
+
+func (t *ColUInt16Array) EncodeColumn(buffer *proto.Buffer) {
+	t.col.EncodeColumn(buffer)
+}
+
 // ColInt32 represents Clickhouse Int32 type.
 type ColInt32 struct {
 	// col contains values for the Int32 type.
@@ -558,6 +833,61 @@ func (t *ColInt32) EncodeColumn(buffer *proto.Buffer) {
 	t.col.EncodeColumn(buffer)
 }
 
+// ColInt32Array represents Clickhouse Array(Int32) type.
+type ColInt32Array struct {
+	// col contains values for the Array(Int32) type.
+	col *proto.ColArr[int32]
+	// vals is the buffer for the values of the appended array.
+	vals []int32
+}
+
+var _ InsaneColInput = (*ColInt32Array)(nil)
+
+func NewColInt32Array() *ColInt32Array {
+	return &ColInt32Array{
+		col: new(proto.ColInt32).Array(),
+	}
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColInt32Array) Append(array InsaneNode) error {
+	t.vals = t.vals[:0]
+	if array != nil && !array.IsNull() {
+		nodes, err := array.AsNodeArray()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			v, err := node.AsInt()
+			if err != nil {
+				return err
+			}
+			t.vals = append(t.vals, int32(v))
+		}
+	}
+
+	t.col.Append(t.vals)
+
+	return nil
+}
+
+func (t *ColInt32Array) Reset() {
+	t.col.Reset()
+}
+
+func (t *ColInt32Array) Type() proto.ColumnType {
+	return t.col.Type()
+}
+
+func (t *ColInt32Array) Rows() int {
+	return t.col.Rows()
+}
+
+func (t *ColInt32Array) EncodeColumn(buffer *proto.Buffer) {
+	t.col.EncodeColumn(buffer)
+}
+
 // ColUInt32 represents Clickhouse UInt32 type.
 type ColUInt32 struct {
 	// col contains values for the UInt32 type.
@@ -633,6 +963,61 @@ func (t *ColUInt32) EncodeColumn(buffer *proto.Buffer) {
 	t.col.EncodeColumn(buffer)
 }
 
+// ColUInt32Array represents Clickhouse Array(UInt32) type.
+type ColUInt32Array struct {
+	// col contains values for the Array(UInt32) type.
+	col *proto.ColArr[uint32]
+	// vals is the buffer for the values of the appended array.
+	vals []uint32
+}
+
+var _ InsaneColInput = (*ColUInt32Array)(nil)
+
+func NewColUInt32Array() *ColUInt32Array {
+	return &ColUInt32Array{
+		col: new(proto.ColUInt32).Array(),
+	}
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColUInt32Array) Append(array InsaneNode) error {
+	t.vals = t.vals[:0]
+	if array != nil && !array.IsNull() {
+		nodes, err := array.AsNodeArray()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			v, err := node.AsInt()
+			if err != nil {
+				return err
+			}
+			t.vals = append(t.vals, uint32(v))
+		}
+	}
+
+	t.col.Append(t.vals)
+
+	return nil
+}
+
+func (t *ColUInt32Array) Reset() {
+	t.col.Reset()
+}
+
+func (t *ColUInt32Array) Type() proto.ColumnType {
+	return t.col.Type()
+}
+
+func (t *ColUInt32Array) Rows() int {
+	return t.col.Rows()
+}
+
+func (t *ColUInt32Array) EncodeColumn(buffer *proto.Buffer) {
+	t.col.EncodeColumn(buffer)
+}
+
 // ColInt64 represents Clickhouse Int64 type.
 type ColInt64 struct {
 	// col contains values for the Int64 type.
@@ -708,6 +1093,61 @@ func (t *ColInt64) EncodeColumn(buffer *proto.Buffer) {
 	t.col.EncodeColumn(buffer)
 }
 
+// ColInt64Array represents Clickhouse Array(Int64) type.
+type ColInt64Array struct {
+	// col contains values for the Array(Int64) type.
+	col *proto.ColArr[int64]
+	// vals is the buffer for the values of the appended array.
+	vals []int64
+}
+
+var _ InsaneColInput = (*ColInt64Array)(nil)
+
+func NewColInt64Array() *ColInt64Array {
+	return &ColInt64Array{
+		col: new(proto.ColInt64).Array(),
+	}
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColInt64Array) Append(array InsaneNode) error {
+	t.vals = t.vals[:0]
+	if array != nil && !array.IsNull() {
+		nodes, err := array.AsNodeArray()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			v, err := node.AsInt()
+			if err != nil {
+				return err
+			}
+			t.vals = append(t.vals, int64(v))
+		}
+	}
+
+	t.col.Append(t.vals)
+
+	return nil
+}
+
+func (t *ColInt64Array) Reset() {
+	t.col.Reset()
+}
+
+func (t *ColInt64Array) Type() proto.ColumnType {
+	return t.col.Type()
+}
+
+func (t *ColInt64Array) Rows() int {
+	return t.col.Rows()
+}
+
+func (t *ColInt64Array) EncodeColumn(buffer *proto.Buffer) {
+	t.col.EncodeColumn(buffer)
+}
+
 // ColUInt64 represents Clickhouse UInt64 type.
 type ColUInt64 struct {
 	// col contains values for the UInt64 type.
@@ -783,6 +1223,61 @@ func (t *ColUInt64) EncodeColumn(buffer *proto.Buffer) {
 	t.col.EncodeColumn(buffer)
 }
 
+// ColUInt64Array represents Clickhouse Array(UInt64) type.
+type ColUInt64Array struct {
+	// col contains values for the Array(UInt64) type.
+	col *proto.ColArr[uint64]
+	// vals is the buffer for the values of the appended array.
+	vals []uint64
+}
+
+var _ InsaneColInput = (*ColUInt64Array)(nil)
+
+func NewColUInt64Array() *ColUInt64Array {
+	return &ColUInt64Array{
+		col: new(proto.ColUInt64).Array(),
+	}
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColUInt64Array) Append(array InsaneNode) error {
+	t.vals = t.vals[:0]
+	if array != nil && !array.IsNull() {
+		nodes, err := array.AsNodeArray()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			v, err := node.AsInt()
+			if err != nil {
+				return err
+			}
+			t.vals = append(t.vals, uint64(v))
+		}
+	}
+
+	t.col.Append(t.vals)
+
+	return nil
+}
+
+func (t *ColUInt64Array) Reset() {
+	t.col.Reset()
+}
+
+func (t *ColUInt64Array) Type() proto.ColumnType {
+	return t.col.Type()
+}
+
+func (t *ColUInt64Array) Rows() int {
+	return t.col.Rows()
+}
+
+func (t *ColUInt64Array) EncodeColumn(buffer *proto.Buffer) {
+	t.col.EncodeColumn(buffer)
+}
+
 // ColInt128 represents Clickhouse Int128 type.
 type ColInt128 struct {
 	// col contains values for the Int128 type.
@@ -858,6 +1353,61 @@ func (t *ColInt128) EncodeColumn(buffer *proto.Buffer) {
 	t.col.EncodeColumn(buffer)
 }
 
+// ColInt128Array represents Clickhouse Array(Int128) type.
+type ColInt128Array struct {
+	// col contains values for the Array(Int128) type.
+	col *proto.ColArr[proto.Int128]
+	// vals is the buffer for the values of the appended array.
+	vals []proto.Int128
+}
+
+var _ InsaneColInput = (*ColInt128Array)(nil)
+
+func NewColInt128Array() *ColInt128Array {
+	return &ColInt128Array{
+		col: new(proto.ColInt128).Array(),
+	}
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColInt128Array) Append(array InsaneNode) error {
+	t.vals = t.vals[:0]
+	if array != nil && !array.IsNull() {
+		nodes, err := array.AsNodeArray()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			v, err := node.AsInt()
+			if err != nil {
+				return err
+			}
+			t.vals = append(t.vals, proto.Int128FromInt(v))
+		}
+	}
+
+	t.col.Append(t.vals)
+
+	return nil
+}
+
+func (t *ColInt128Array) Reset() {
+	t.col.Reset()
+}
+
+func (t *ColInt128Array) Type() proto.ColumnType {
+	return t.col.Type()
+}
+
+func (t *ColInt128Array) Rows() int {
+	return t.col.Rows()
+}
+
+func (t *ColInt128Array) EncodeColumn(buffer *proto.Buffer) {
+	t.col.EncodeColumn(buffer)
+}
+
 // ColUInt128 represents Clickhouse UInt128 type.
 type ColUInt128 struct {
 	// col contains values for the UInt128 type.
@@ -933,6 +1483,61 @@ func (t *ColUInt128) EncodeColumn(buffer *proto.Buffer) {
 	t.col.EncodeColumn(buffer)
 }
 
+// ColUInt128Array represents Clickhouse Array(UInt128) type.
+type ColUInt128Array struct {
+	// col contains values for the Array(UInt128) type.
+	col *proto.ColArr[proto.UInt128]
+	// vals is the buffer for the values of the appended array.
+	vals []proto.UInt128
+}
+
+var _ InsaneColInput = (*ColUInt128Array)(nil)
+
+func NewColUInt128Array() *ColUInt128Array {
+	return &ColUInt128Array{
+		col: new(proto.ColUInt128).Array(),
+	}
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColUInt128Array) Append(array InsaneNode) error {
+	t.vals = t.vals[:0]
+	if array != nil && !array.IsNull() {
+		nodes, err := array.AsNodeArray()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			v, err := node.AsInt()
+			if err != nil {
+				return err
+			}
+			t.vals = append(t.vals, proto.UInt128FromInt(v))
+		}
+	}
+
+	t.col.Append(t.vals)
+
+	return nil
+}
+
+func (t *ColUInt128Array) Reset() {
+	t.col.Reset()
+}
+
+func (t *ColUInt128Array) Type() proto.ColumnType {
+	return t.col.Type()
+}
+
+func (t *ColUInt128Array) Rows() int {
+	return t.col.Rows()
+}
+
+func (t *ColUInt128Array) EncodeColumn(buffer *proto.Buffer) {
+	t.col.EncodeColumn(buffer)
+}
+
 // ColInt256 represents Clickhouse Int256 type.
 type ColInt256 struct {
 	// col contains values for the Int256 type.
@@ -1008,6 +1613,61 @@ func (t *ColInt256) EncodeColumn(buffer *proto.Buffer) {
 	t.col.EncodeColumn(buffer)
 }
 
+// ColInt256Array represents Clickhouse Array(Int256) type.
+type ColInt256Array struct {
+	// col contains values for the Array(Int256) type.
+	col *proto.ColArr[proto.Int256]
+	// vals is the buffer for the values of the appended array.
+	vals []proto.Int256
+}
+
+var _ InsaneColInput = (*ColInt256Array)(nil)
+
+func NewColInt256Array() *ColInt256Array {
+	return &ColInt256Array{
+		col: new(proto.ColInt256).Array(),
+	}
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColInt256Array) Append(array InsaneNode) error {
+	t.vals = t.vals[:0]
+	if array != nil && !array.IsNull() {
+		nodes, err := array.AsNodeArray()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			v, err := node.AsInt()
+			if err != nil {
+				return err
+			}
+			t.vals = append(t.vals, proto.Int256FromInt(v))
+		}
+	}
+
+	t.col.Append(t.vals)
+
+	return nil
+}
+
+func (t *ColInt256Array) Reset() {
+	t.col.Reset()
+}
+
+func (t *ColInt256Array) Type() proto.ColumnType {
+	return t.col.Type()
+}
+
+func (t *ColInt256Array) Rows() int {
+	return t.col.Rows()
+}
+
+func (t *ColInt256Array) EncodeColumn(buffer *proto.Buffer) {
+	t.col.EncodeColumn(buffer)
+}
+
 // ColUInt256 represents Clickhouse UInt256 type.
 type ColUInt256 struct {
 	// col contains values for the UInt256 type.
@@ -1083,6 +1743,61 @@ func (t *ColUInt256) EncodeColumn(buffer *proto.Buffer) {
 	t.col.EncodeColumn(buffer)
 }
 
+// ColUInt256Array represents Clickhouse Array(UInt256) type.
+type ColUInt256Array struct {
+	// col contains values for the Array(UInt256) type.
+	col *proto.ColArr[proto.UInt256]
+	// vals is the buffer for the values of the appended array.
+	vals []proto.UInt256
+}
+
+var _ InsaneColInput = (*ColUInt256Array)(nil)
+
+func NewColUInt256Array() *ColUInt256Array {
+	return &ColUInt256Array{
+		col: new(proto.ColUInt256).Array(),
+	}
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColUInt256Array) Append(array InsaneNode) error {
+	t.vals = t.vals[:0]
+	if array != nil && !array.IsNull() {
+		nodes, err := array.AsNodeArray()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			v, err := node.AsInt()
+			if err != nil {
+				return err
+			}
+			t.vals = append(t.vals, proto.UInt256FromInt(v))
+		}
+	}
+
+	t.col.Append(t.vals)
+
+	return nil
+}
+
+func (t *ColUInt256Array) Reset() {
+	t.col.Reset()
+}
+
+func (t *ColUInt256Array) Type() proto.ColumnType {
+	return t.col.Type()
+}
+
+func (t *ColUInt256Array) Rows() int {
+	return t.col.Rows()
+}
+
+func (t *ColUInt256Array) EncodeColumn(buffer *proto.Buffer) {
+	t.col.EncodeColumn(buffer)
+}
+
 // ColFloat32 represents Clickhouse Float32 type.
 type ColFloat32 struct {
 	// col contains values for the Float32 type.
@@ -1156,6 +1871,61 @@ func (t *ColFloat32) EncodeColumn(buffer *proto.Buffer) {
 	t.col.EncodeColumn(buffer)
 }
 
+// ColFloat32Array represents Clickhouse Array(Float32) type.
+type ColFloat32Array struct {
+	// col contains values for the Array(Float32) type.
+	col *proto.ColArr[float32]
+	// vals is the buffer for the values of the appended array.
+	vals []float32
+}
+
+var _ InsaneColInput = (*ColFloat32Array)(nil)
+
+func NewColFloat32Array() *ColFloat32Array {
+	return &ColFloat32Array{
+		col: new(proto.ColFloat32).Array(),
+	}
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColFloat32Array) Append(array InsaneNode) error {
+	t.vals = t.vals[:0]
+	if array != nil && !array.IsNull() {
+		nodes, err := array.AsNodeArray()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			val, err := node.AsFloat32()
+			if err != nil {
+				return err
+			}
+			t.vals = append(t.vals, val)
+		}
+	}
+
+	t.col.Append(t.vals)
+
+	return nil
+}
+
+func (t *ColFloat32Array) Reset() {
+	t.col.Reset()
+}
+
+func (t *ColFloat32Array) Type() proto.ColumnType {
+	return t.col.Type()
+}
+
+func (t *ColFloat32Array) Rows() int {
+	return t.col.Rows()
+}
+
+func (t *ColFloat32Array) EncodeColumn(buffer *proto.Buffer) {
+	t.col.EncodeColumn(buffer)
+}
+
 // ColFloat64 represents Clickhouse Float64 type.
 type ColFloat64 struct {
 	// col contains values for the Float64 type.
@@ -1229,6 +1999,61 @@ func (t *ColFloat64) EncodeColumn(buffer *proto.Buffer) {
 	t.col.EncodeColumn(buffer)
 }
 
+// ColFloat64Array represents Clickhouse Array(Float64) type.
+type ColFloat64Array struct {
+	// col contains values for the Array(Float64) type.
+	col *proto.ColArr[float64]
+	// vals is the buffer for the values of the appended array.
+	vals []float64
+}
+
+var _ InsaneColInput = (*ColFloat64Array)(nil)
+
+func NewColFloat64Array() *ColFloat64Array {
+	return &ColFloat64Array{
+		col: new(proto.ColFloat64).Array(),
+	}
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColFloat64Array) Append(array InsaneNode) error {
+	t.vals = t.vals[:0]
+	if array != nil && !array.IsNull() {
+		nodes, err := array.AsNodeArray()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			val, err := node.AsFloat64()
+			if err != nil {
+				return err
+			}
+			t.vals = append(t.vals, val)
+		}
+	}
+
+	t.col.Append(t.vals)
+
+	return nil
+}
+
+func (t *ColFloat64Array) Reset() {
+	t.col.Reset()
+}
+
+func (t *ColFloat64Array) Type() proto.ColumnType {
+	return t.col.Type()
+}
+
+func (t *ColFloat64Array) Rows() int {
+	return t.col.Rows()
+}
+
+func (t *ColFloat64Array) EncodeColumn(buffer *proto.Buffer) {
+	t.col.EncodeColumn(buffer)
+}
+
 // struct ColDateTime defined and implemented in another file
 
 var _ InsaneColInput = (*ColDateTime)(nil)
@@ -1342,6 +2167,61 @@ func (t *ColIPv4) EncodeColumn(buffer *proto.Buffer) {
 	t.col.EncodeColumn(buffer)
 }
 
+// ColIPv4Array represents Clickhouse Array(IPv4) type.
+type ColIPv4Array struct {
+	// col contains values for the Array(IPv4) type.
+	col *proto.ColArr[proto.IPv4]
+	// vals is the buffer for the values of the appended array.
+	vals []proto.IPv4
+}
+
+var _ InsaneColInput = (*ColIPv4Array)(nil)
+
+func NewColIPv4Array() *ColIPv4Array {
+	return &ColIPv4Array{
+		col: new(proto.ColIPv4).Array(),
+	}
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColIPv4Array) Append(array InsaneNode) error {
+	t.vals = t.vals[:0]
+	if array != nil && !array.IsNull() {
+		nodes, err := array.AsNodeArray()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			val, err := node.AsIPv4()
+			if err != nil {
+				return err
+			}
+			t.vals = append(t.vals, val)
+		}
+	}
+
+	t.col.Append(t.vals)
+
+	return nil
+}
+
+func (t *ColIPv4Array) Reset() {
+	t.col.Reset()
+}
+
+func (t *ColIPv4Array) Type() proto.ColumnType {
+	return t.col.Type()
+}
+
+func (t *ColIPv4Array) Rows() int {
+	return t.col.Rows()
+}
+
+func (t *ColIPv4Array) EncodeColumn(buffer *proto.Buffer) {
+	t.col.EncodeColumn(buffer)
+}
+
 // ColIPv6 represents Clickhouse IPv6 type.
 type ColIPv6 struct {
 	// col contains values for the IPv6 type.
@@ -1415,6 +2295,61 @@ func (t *ColIPv6) EncodeColumn(buffer *proto.Buffer) {
 	t.col.EncodeColumn(buffer)
 }
 
+// ColIPv6Array represents Clickhouse Array(IPv6) type.
+type ColIPv6Array struct {
+	// col contains values for the Array(IPv6) type.
+	col *proto.ColArr[proto.IPv6]
+	// vals is the buffer for the values of the appended array.
+	vals []proto.IPv6
+}
+
+var _ InsaneColInput = (*ColIPv6Array)(nil)
+
+func NewColIPv6Array() *ColIPv6Array {
+	return &ColIPv6Array{
+		col: new(proto.ColIPv6).Array(),
+	}
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColIPv6Array) Append(array InsaneNode) error {
+	t.vals = t.vals[:0]
+	if array != nil && !array.IsNull() {
+		nodes, err := array.AsNodeArray()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			val, err := node.AsIPv6()
+			if err != nil {
+				return err
+			}
+			t.vals = append(t.vals, val)
+		}
+	}
+
+	t.col.Append(t.vals)
+
+	return nil
+}
+
+func (t *ColIPv6Array) Reset() {
+	t.col.Reset()
+}
+
+func (t *ColIPv6Array) Type() proto.ColumnType {
+	return t.col.Type()
+}
+
+func (t *ColIPv6Array) Rows() int {
+	return t.col.Rows()
+}
+
+func (t *ColIPv6Array) EncodeColumn(buffer *proto.Buffer) {
+	t.col.EncodeColumn(buffer)
+}
+
 // ColUUID represents Clickhouse UUID type.
 type ColUUID struct {
 	// col contains values for the UUID type.
@@ -1487,3 +2422,58 @@ func (t *ColUUID) EncodeColumn(buffer *proto.Buffer) {
 	}
 	t.col.EncodeColumn(buffer)
 }
+
+// ColUUIDArray represents Clickhouse Array(UUID) type.
+type ColUUIDArray struct {
+	// col contains values for the Array(UUID) type.
+	col *proto.ColArr[uuid.UUID]
+	// vals is the buffer for the values of the appended array.
+	vals []uuid.UUID
+}
+
+var _ InsaneColInput = (*ColUUIDArray)(nil)
+
+func NewColUUIDArray() *ColUUIDArray {
+	return &ColUUIDArray{
+		col: new(proto.ColUUID).Array(),
+	}
+}
+
+// Append the insaneJSON.Node to the batch.
+func (t *ColUUIDArray) Append(array InsaneNode) error {
+	t.vals = t.vals[:0]
+	if array != nil && !array.IsNull() {
+		nodes, err := array.AsNodeArray()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			val, err := node.AsUUID()
+			if err != nil {
+				return err
+			}
+			t.vals = append(t.vals, val)
+		}
+	}
+
+	t.col.Append(t.vals)
+
+	return nil
+}
+
+func (t *ColUUIDArray) Reset() {
+	t.col.Reset()
+}
+
+func (t *ColUUIDArray) Type() proto.ColumnType {
+	return t.col.Type()
+}
+
+func (t *ColUUIDArray) Rows() int {
+	return t.col.Rows()
+}
+
+func (t *ColUUIDArray) EncodeColumn(buffer *proto.Buffer) {
+	t.col.EncodeColumn(buffer)
+}
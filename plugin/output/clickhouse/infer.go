@@ -2,10 +2,17 @@ package clickhouse
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/ClickHouse/ch-go/proto"
 )
 
+const (
+	columnTypeDecimal proto.ColumnType = "Decimal"
+	columnTypeNested  proto.ColumnType = "Nested"
+)
+
 //go:generate go run ./colgenerator
 
 type InsaneColInput interface {
@@ -15,7 +22,10 @@ type InsaneColInput interface {
 }
 
 type InsaneColumn struct {
-	Name     string
+	Name string
+	// Field is the event field containing the column values.
+	// It equals the Name except for the subcolumns of the Nested type.
+	Field    string
 	ColInput InsaneColInput
 }
 
@@ -26,18 +36,24 @@ func inferInsaneColInputs(columns []Column) ([]InsaneColumn, error) {
 			return nil, fmt.Errorf("empty column type")
 		}
 
-		auto := proto.ColAuto{}
-		if err := auto.Infer(proto.ColumnType(col.Type)); err != nil {
-			return nil, fmt.Errorf("auto infer: %w", err)
+		colType := proto.ColumnType(col.Type)
+		if colType.Base() == columnTypeNested {
+			nestedColumns, err := inferNestedColumns(col.Name, colType)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %w", col.Name, err)
+			}
+			insaneColumns = append(insaneColumns, nestedColumns...)
+			continue
 		}
 
-		insaneCol, err := insaneInfer(auto)
+		insaneCol, err := inferInsaneColInput(colType)
 		if err != nil {
 			return nil, err
 		}
 
 		insaneColumns = append(insaneColumns, InsaneColumn{
 			Name:     col.Name,
+			Field:    col.Name,
 			ColInput: insaneCol,
 		})
 	}
@@ -45,6 +61,186 @@ func inferInsaneColInputs(columns []Column) ([]InsaneColumn, error) {
 	return insaneColumns, nil
 }
 
+// inferNestedColumns returns the Array subcolumns of the Nested type like `n.a Array(String)`
+// because Clickhouse flattens the Nested columns by default.
+func inferNestedColumns(name string, colType proto.ColumnType) ([]InsaneColumn, error) {
+	args := splitTypeArgs(colType.Elem())
+	if len(args) == 0 {
+		return nil, fmt.Errorf("nested type %q has no elements", colType.String())
+	}
+
+	columns := make([]InsaneColumn, 0, len(args))
+	for _, arg := range args {
+		field, elemType, named := splitNamedTypeArg(arg)
+		if !named {
+			return nil, fmt.Errorf("nested element %q has no name", arg)
+		}
+
+		data, err := inferInsaneColInput(elemType)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, InsaneColumn{
+			Name:     name + "." + field,
+			Field:    name,
+			ColInput: NewColNestedArray(data, field),
+		})
+	}
+	return columns, nil
+}
+
+// inferInsaneColInput infers the composite types and the decimals itself and uses proto.ColAuto for the rest.
+func inferInsaneColInput(colType proto.ColumnType) (InsaneColInput, error) {
+	switch colType.Base() {
+	case proto.ColumnTypeArray:
+		return inferArray(colType.Elem())
+	case proto.ColumnTypeMap:
+		args := splitTypeArgs(colType.Elem())
+		if len(args) != 2 || args[0] != proto.ColumnTypeString.String() {
+			return nil, fmt.Errorf("map type %q is not supported, only String keys are supported", colType.String())
+		}
+		values, err := inferInsaneColInput(proto.ColumnType(args[1]))
+		if err != nil {
+			return nil, err
+		}
+		return NewColMap(values), nil
+	case proto.ColumnTypeTuple:
+		return inferTuple(colType)
+	case proto.ColumnTypeNullable:
+		if isDecimal(colType.Elem()) {
+			return inferDecimal(colType.Elem(), true)
+		}
+	case columnTypeDecimal, proto.ColumnTypeDecimal32, proto.ColumnTypeDecimal64, proto.ColumnTypeDecimal128, proto.ColumnTypeDecimal256:
+		return inferDecimal(colType, false)
+	}
+
+	auto := proto.ColAuto{}
+	if err := auto.Infer(colType); err != nil {
+		return nil, fmt.Errorf("auto infer: %w", err)
+	}
+	return insaneInfer(auto)
+}
+
+func inferArray(elemType proto.ColumnType) (InsaneColInput, error) {
+	switch elemType {
+	case proto.ColumnTypeString:
+		return NewColStringArray(), nil
+	case proto.ColumnTypeBool:
+		return NewColBoolArray(), nil
+	case proto.ColumnTypeInt8:
+		return NewColInt8Array(), nil
+	case proto.ColumnTypeUInt8:
+		return NewColUInt8Array(), nil
+	case proto.ColumnTypeInt16:
+		return NewColInt16Array(), nil
+	case proto.ColumnTypeUInt16:
+		return NewColUInt16Array(), nil
+	case proto.ColumnTypeInt32:
+		return NewColInt32Array(), nil
+	case proto.ColumnTypeUInt32:
+		return NewColUInt32Array(), nil
+	case proto.ColumnTypeInt64:
+		return NewColInt64Array(), nil
+	case proto.ColumnTypeUInt64:
+		return NewColUInt64Array(), nil
+	case proto.ColumnTypeInt128:
+		return NewColInt128Array(), nil
+	case proto.ColumnTypeUInt128:
+		return NewColUInt128Array(), nil
+	case proto.ColumnTypeInt256:
+		return NewColInt256Array(), nil
+	case proto.ColumnTypeUInt256:
+		return NewColUInt256Array(), nil
+	case proto.ColumnTypeFloat32:
+		return NewColFloat32Array(), nil
+	case proto.ColumnTypeFloat64:
+		return NewColFloat64Array(), nil
+	case proto.ColumnTypeIPv4:
+		return NewColIPv4Array(), nil
+	case proto.ColumnTypeIPv6:
+		return NewColIPv6Array(), nil
+	case proto.ColumnTypeUUID:
+		return NewColUUIDArray(), nil
+	}
+
+	data, err := inferInsaneColInput(elemType)
+	if err != nil {
+		return nil, err
+	}
+	return NewColArray(data), nil
+}
+
+func inferTuple(colType proto.ColumnType) (InsaneColInput, error) {
+	args := splitTypeArgs(colType.Elem())
+	if len(args) == 0 {
+		return nil, fmt.Errorf("tuple type %q has no elements", colType.String())
+	}
+
+	var names []string
+	cols := make([]InsaneColInput, 0, len(args))
+	for i, arg := range args {
+		name, elemType, named := splitNamedTypeArg(arg)
+		if i > 0 && named != (names != nil) {
+			return nil, fmt.Errorf("tuple type %q mixes named and unnamed elements", colType.String())
+		}
+		if named {
+			names = append(names, name)
+		}
+
+		col, err := inferInsaneColInput(elemType)
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, col)
+	}
+	return NewColTuple(names, cols), nil
+}
+
+func isDecimal(colType proto.ColumnType) bool {
+	return strings.HasPrefix(colType.String(), columnTypeDecimal.String())
+}
+
+// inferDecimal parses the precision and the scale of `Decimal(P, S)` or `DecimalN(S)` types.
+func inferDecimal(colType proto.ColumnType, nullable bool) (InsaneColInput, error) {
+	args := splitTypeArgs(colType.Elem())
+	params := make([]int, len(args))
+	for i, arg := range args {
+		v, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid decimal type %q: %w", colType.String(), err)
+		}
+		params[i] = v
+	}
+
+	var precision, scale int
+	switch base := colType.Base(); {
+	case base == columnTypeDecimal && len(params) <= 2:
+		// Decimal is Decimal(10, 0) and Decimal(P) is Decimal(P, 0)
+		precision = 10
+		if len(params) > 0 {
+			precision = params[0]
+		}
+		if len(params) > 1 {
+			scale = params[1]
+		}
+	case base == proto.ColumnTypeDecimal32 && len(params) == 1:
+		precision, scale = 9, params[0]
+	case base == proto.ColumnTypeDecimal64 && len(params) == 1:
+		precision, scale = 18, params[0]
+	case base == proto.ColumnTypeDecimal128 && len(params) == 1:
+		precision, scale = 38, params[0]
+	case base == proto.ColumnTypeDecimal256 && len(params) == 1:
+		precision, scale = 76, params[0]
+	default:
+		return nil, fmt.Errorf("invalid decimal type %q", colType.String())
+	}
+
+	if precision < 1 || precision > 76 || scale < 0 || scale > precision {
+		return nil, fmt.Errorf("invalid precision or scale of the decimal type %q", colType.String())
+	}
+	return NewColDecimal(precision, scale, nullable), nil
+}
+
 func insaneInfer(auto proto.ColAuto) (InsaneColInput, error) {
 	parent := auto.Type().Base()
 	nullable := parent == proto.ColumnTypeNullable
@@ -66,12 +262,6 @@ func insaneInfer(auto proto.ColAuto) (InsaneColInput, error) {
 	case proto.ColumnTypeDateTime64:
 		col := auto.Data.(*proto.ColDateTime64)
 		return NewColDateTime64(col, col.Precision.Scale()), nil
-	case proto.ColumnTypeArray:
-		child := child.Elem()
-		if child == proto.ColumnTypeString {
-			return NewColStringArray(), nil
-		}
-		return nil, fmt.Errorf("array of type %q is not supported", child.String())
 	default:
 		switch child {
 		case proto.ColumnTypeBool:
@@ -0,0 +1,219 @@
+package clickhouse
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/ClickHouse/ch-go/proto"
+	"github.com/stretchr/testify/require"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+func TestInferInsaneColInputs(t *testing.T) {
+	columns, err := inferInsaneColInputs([]Column{
+		{Name: "ids", Type: "Array(UInt64)"},
+		{Name: "labels", Type: "Map(String,String)"},
+		{Name: "counters", Type: "Map(String, Array(Nullable(Int32)))"},
+		{Name: "point", Type: "Tuple(Float64, Float64)"},
+		{Name: "user", Type: "Tuple(name LowCardinality(String), ip IPv4)"},
+		{Name: "price", Type: "Decimal(18, 4)"},
+		{Name: "discount", Type: "Nullable(Decimal32(2))"},
+		{Name: "items", Type: "Nested(sku String, created DateTime64(3, 'UTC'))"},
+	})
+	require.NoError(t, err)
+
+	want := []struct {
+		name  string
+		field string
+		typ   proto.ColumnType
+	}{
+		{"ids", "ids", "Array(UInt64)"},
+		{"labels", "labels", "Map(String, String)"},
+		{"counters", "counters", "Map(String, Array(Nullable(Int32)))"},
+		{"point", "point", "Tuple(Float64, Float64)"},
+		{"user", "user", "Tuple(name LowCardinality(String), ip IPv4)"},
+		{"price", "price", "Decimal(18, 4)"},
+		{"discount", "discount", "Nullable(Decimal(9, 2))"},
+		{"items.sku", "items", "Array(String)"},
+		{"items.created", "items", "Array(DateTime64(3, 'UTC'))"},
+	}
+	require.Len(t, columns, len(want))
+	for i, w := range want {
+		require.Equal(t, w.name, columns[i].Name)
+		require.Equal(t, w.field, columns[i].Field)
+		require.Equal(t, w.typ, columns[i].ColInput.Type())
+	}
+
+	for _, typ := range []string{
+		"Map(UInt64, String)",
+		"Tuple(a String, UInt64)",
+		"Nested(String)",
+		"Decimal(77, 2)",
+		"Array(Unknown)",
+	} {
+		_, err := inferInsaneColInputs([]Column{{Name: "c", Type: typ}})
+		require.Error(t, err, "type %s", typ)
+	}
+}
+
+// encodeDecode encodes the column and decodes it by the ch-go column to check the values.
+func encodeDecode(t *testing.T, col InsaneColInput, result proto.Column) {
+	t.Helper()
+
+	if col, ok := col.(proto.Preparable); ok {
+		require.NoError(t, col.Prepare())
+	}
+	buf := new(proto.Buffer)
+	col.EncodeColumn(buf)
+	require.NoError(t, result.DecodeColumn(proto.NewReader(bytes.NewReader(buf.Buf)), col.Rows()))
+}
+
+func appendNodes(t *testing.T, col InsaneColInput, strict bool, values ...string) {
+	t.Helper()
+
+	for _, value := range values {
+		root, err := insaneJSON.DecodeString(`{"v":` + value + `}`)
+		require.NoError(t, err)
+
+		var node InsaneNode
+		if n := root.Dig("v"); strict {
+			node = StrictNode{n.MutateToStrict()}
+		} else {
+			node = NonStrictNode{n}
+		}
+		if err := col.Append(node); err != nil {
+			require.NoError(t, col.Append(ZeroValueNode{}))
+		}
+		insaneJSON.Release(root)
+	}
+}
+
+func TestColTypedArray(t *testing.T) {
+	for _, strict := range []bool{true, false} {
+		col := NewColUInt64Array()
+		appendNodes(t, col, strict, `[1,2,3]`, `[]`, `null`, `[4,"5"]`, `7`)
+
+		result := new(proto.ColUInt64).Array()
+		encodeDecode(t, col, result)
+
+		want := [][]uint64{{1, 2, 3}, {}, {}, {4, 5}, {7}}
+		if strict {
+			// the strings and the non-arrays aren't converted in the strict mode
+			want = [][]uint64{{1, 2, 3}, {}, {}, {}, {}}
+		}
+		require.Len(t, want, result.Rows())
+		for i := range want {
+			require.ElementsMatch(t, want[i], result.Row(i), "strict=%t row=%d", strict, i)
+		}
+	}
+}
+
+func TestColMap(t *testing.T) {
+	for _, strict := range []bool{true, false} {
+		col := NewColMap(NewColString(false, false))
+		appendNodes(t, col, strict, `{"app":"api","env":"prod"}`, `{}`, `"not map"`, `{"port":80}`)
+
+		result := proto.NewMap[string, string](new(proto.ColStr), new(proto.ColStr))
+		encodeDecode(t, col, result)
+
+		want := []map[string]string{{"app": "api", "env": "prod"}, {}, {}, {"port": "80"}}
+		if strict {
+			want[3] = map[string]string{"port": ""}
+		}
+		require.Equal(t, len(want), result.Rows())
+		for i := range want {
+			require.Equal(t, want[i], result.Row(i), "strict=%t row=%d", strict, i)
+		}
+	}
+}
+
+func TestColTuple(t *testing.T) {
+	col := NewColTuple([]string{"name", "port"}, []InsaneColInput{NewColString(false, false), NewColUInt16(true)})
+	appendNodes(t, col, true, `{"name":"a","port":80}`, `{"name":"b"}`, `null`)
+
+	name, port := new(proto.ColStr), new(proto.ColUInt16).Nullable()
+	encodeDecode(t, col, proto.ColTuple{name, port})
+
+	require.Equal(t, 3, name.Rows())
+	require.Equal(t, []string{"a", "b", ""}, []string{name.Row(0), name.Row(1), name.Row(2)})
+	require.Equal(t, []proto.Nullable[uint16]{proto.NewNullable[uint16](80), proto.Null[uint16](), proto.Null[uint16]()},
+		[]proto.Nullable[uint16]{port.Row(0), port.Row(1), port.Row(2)})
+
+	col = NewColTuple(nil, []InsaneColInput{NewColFloat64(false), NewColFloat64(false)})
+	appendNodes(t, col, false, `[1.5,2]`, `[3]`)
+
+	x, y := new(proto.ColFloat64), new(proto.ColFloat64)
+	encodeDecode(t, col, proto.ColTuple{x, y})
+	require.Equal(t, proto.ColFloat64{1.5, 3}, *x)
+	require.Equal(t, proto.ColFloat64{2, 0}, *y)
+}
+
+func TestColNestedArray(t *testing.T) {
+	columns, err := inferInsaneColInputs([]Column{{Name: "items", Type: "Nested(sku String, qty UInt32)"}})
+	require.NoError(t, err)
+	require.Len(t, columns, 2)
+
+	for _, col := range columns {
+		appendNodes(t, col.ColInput, true, `[{"sku":"a","qty":2},{"sku":"b"}]`, `[]`)
+	}
+
+	sku, qty := new(proto.ColStr).Array(), new(proto.ColUInt32).Array()
+	encodeDecode(t, columns[0].ColInput, sku)
+	encodeDecode(t, columns[1].ColInput, qty)
+	require.Equal(t, []string{"a", "b"}, sku.Row(0))
+	require.Equal(t, []uint32{2, 0}, qty.Row(0))
+	require.Empty(t, sku.Row(1))
+	require.Empty(t, qty.Row(1))
+}
+
+func TestColDecimal(t *testing.T) {
+	col := NewColDecimal(18, 2, true)
+	appendNodes(t, col, true, `12.345`, `"-0.5"`, `null`, `1e2`, `{}`)
+
+	result := new(proto.ColDecimal64).Nullable()
+	encodeDecode(t, col, result)
+
+	want := []proto.Nullable[proto.Decimal64]{
+		proto.NewNullable[proto.Decimal64](1234),
+		proto.NewNullable[proto.Decimal64](-50),
+		proto.Null[proto.Decimal64](),
+		proto.NewNullable[proto.Decimal64](10000),
+		proto.NewNullable[proto.Decimal64](0),
+	}
+	require.Equal(t, len(want), result.Rows())
+	for i := range want {
+		require.Equal(t, want[i], result.Row(i), "row=%d", i)
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	cases := []struct {
+		value string
+		scale int64
+		want  proto.Int256
+	}{
+		{value: "12.345", scale: 2, want: proto.Int256FromInt(1234)},
+		{value: "-12.345", scale: 4, want: proto.Int256FromInt(-123450)},
+		{value: "0.001", scale: 2, want: proto.Int256FromInt(0)},
+		{value: "+1.5E-1", scale: 3, want: proto.Int256FromInt(150)},
+		{value: "-0", scale: 3, want: proto.Int256FromInt(0)},
+		{value: "12345678901234567890", scale: 0, want: proto.Int256{Low: proto.UInt128{Low: 12345678901234567890}}},
+		{value: "-12345678901234567890", scale: 0, want: proto.Int256{
+			// two's complement of the value
+			Low:  proto.UInt128{Low: ^uint64(12345678901234567890) + 1, High: math.MaxUint64},
+			High: proto.UInt128{Low: math.MaxUint64, High: math.MaxUint64},
+		}},
+	}
+	for _, tc := range cases {
+		got, err := parseDecimal(tc.value, tc.scale)
+		require.NoError(t, err, tc.value)
+		require.Equal(t, tc.want, got, tc.value)
+	}
+
+	for _, value := range []string{"", ".", "1.2.3", "abc", "1e", strings.Repeat("1", 80)} {
+		_, err := parseDecimal(value, 2)
+		require.Error(t, err, value)
+	}
+}
@@ -1,9 +1,13 @@
 package clickhouse
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"math/big"
 	"net/netip"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ClickHouse/ch-go/proto"
@@ -12,7 +16,8 @@ import (
 )
 
 var (
-	ErrInvalidTimeType = errors.New("invalid node type for the time")
+	ErrInvalidTimeType    = errors.New("invalid node type for the time")
+	ErrInvalidDecimalType = errors.New("invalid node type for the decimal")
 )
 
 type InsaneNode interface {
@@ -27,10 +32,19 @@ type InsaneNode interface {
 	AsIPv4() (proto.IPv4, error)
 	AsIPv6() (proto.IPv6, error)
 	AsTime(scale int64) (time.Time, error)
+	AsDecimal(scale int64) (proto.Int256, error)
+	AsNodeArray() ([]InsaneNode, error)
+	AsNodeMap() ([]InsaneField, error)
 
 	IsNull() bool
 }
 
+// InsaneField is the field of the JSON object.
+type InsaneField struct {
+	Name  string
+	Value InsaneNode
+}
+
 var (
 	_ InsaneNode = NonStrictNode{}
 	_ InsaneNode = StrictNode{}
@@ -94,6 +108,40 @@ func (s StrictNode) AsTime(scale int64) (time.Time, error) {
 	return nodeAsTime(s.StrictNode, scale)
 }
 
+func (s StrictNode) AsDecimal(scale int64) (proto.Int256, error) {
+	if !s.IsNumber() && !s.IsString() {
+		return proto.Int256{}, ErrInvalidDecimalType
+	}
+	return parseDecimal(s.Node.AsString(), scale)
+}
+
+func (s StrictNode) AsNodeArray() ([]InsaneNode, error) {
+	arr, err := s.StrictNode.AsArray()
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]InsaneNode, len(arr))
+	for i, n := range arr {
+		nodes[i] = StrictNode{n.MutateToStrict()}
+	}
+	return nodes, nil
+}
+
+func (s StrictNode) AsNodeMap() ([]InsaneField, error) {
+	fields, err := s.StrictNode.AsFields()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]InsaneField, len(fields))
+	for i, f := range fields {
+		result[i] = InsaneField{
+			Name:  f.AsString(),
+			Value: StrictNode{f.AsFieldValue().MutateToStrict()},
+		}
+	}
+	return result, nil
+}
+
 func (s StrictNode) AsStringArray() ([]string, error) {
 	if s.StrictNode == nil || s.IsNull() {
 		return nil, nil
@@ -135,6 +183,40 @@ func (n NonStrictNode) AsStringArray() ([]string, error) {
 	return vals, nil
 }
 
+// AsNodeArray returns the array elements or the node itself as the only element if it isn't array.
+func (n NonStrictNode) AsNodeArray() ([]InsaneNode, error) {
+	if n.Node == nil || n.Node.IsNull() {
+		return nil, nil
+	}
+
+	if !n.IsArray() {
+		return []InsaneNode{n}, nil
+	}
+	arr := n.AsArray()
+	nodes := make([]InsaneNode, len(arr))
+	for i, n := range arr {
+		nodes[i] = NonStrictNode{n}
+	}
+	return nodes, nil
+}
+
+// AsNodeMap returns the object fields or nothing if the node isn't object.
+func (n NonStrictNode) AsNodeMap() ([]InsaneField, error) {
+	if n.Node == nil || !n.IsObject() {
+		return nil, nil
+	}
+
+	fields := n.AsFields()
+	result := make([]InsaneField, len(fields))
+	for i, f := range fields {
+		result[i] = InsaneField{
+			Name:  f.AsString(),
+			Value: NonStrictNode{f.AsFieldValue()},
+		}
+	}
+	return result, nil
+}
+
 func (n NonStrictNode) AsInt() (int, error) {
 	return n.Node.AsInt(), nil
 }
@@ -210,6 +292,14 @@ func (n NonStrictNode) AsTime(scale int64) (time.Time, error) {
 	return t, nil
 }
 
+func (n NonStrictNode) AsDecimal(scale int64) (proto.Int256, error) {
+	val, err := parseDecimal(n.Node.AsString(), scale)
+	if err != nil {
+		return proto.Int256{}, nil
+	}
+	return val, nil
+}
+
 // ZeroValueNode returns a null-value for all called methods.
 // It is usually used to insert a zero-value into a column
 // if the field type of the event does not match the column type.
@@ -259,6 +349,18 @@ func (z ZeroValueNode) AsTime(_ int64) (time.Time, error) {
 	return time.Time{}, nil
 }
 
+func (z ZeroValueNode) AsDecimal(_ int64) (proto.Int256, error) {
+	return proto.Int256{}, nil
+}
+
+func (z ZeroValueNode) AsNodeArray() ([]InsaneNode, error) {
+	return nil, nil
+}
+
+func (z ZeroValueNode) AsNodeMap() ([]InsaneField, error) {
+	return nil, nil
+}
+
 func (z ZeroValueNode) IsNull() bool {
 	return false
 }
@@ -306,3 +408,102 @@ func parseRFC3339Nano(node *insaneJSON.StrictNode) (time.Time, error) {
 	}
 	return t, nil
 }
+
+// parseDecimal parses the decimal number to the integer scaled by 10^scale,
+// the digits which don't fit into the scale are truncated.
+func parseDecimal(s string, scale int64) (proto.Int256, error) {
+	neg, digits, exp, ok := splitDecimal(s)
+	if !ok {
+		return proto.Int256{}, fmt.Errorf("invalid decimal %q", s)
+	}
+
+	shift := scale + exp
+	if shift < 0 {
+		if int64(len(digits)) <= -shift {
+			return proto.Int256{}, nil
+		}
+		digits = digits[:int64(len(digits))+shift]
+		shift = 0
+	}
+	if int64(len(digits))+shift > maxDecimalDigits {
+		return proto.Int256{}, fmt.Errorf("decimal %q is out of range", s)
+	}
+	digits += strings.Repeat("0", int(shift))
+
+	if len(digits) <= maxInt64Digits {
+		v, err := strconv.ParseInt(digits, 10, 64)
+		if err != nil {
+			return proto.Int256{}, err
+		}
+		if neg {
+			v = -v
+		}
+		return proto.Int256FromInt(int(v)), nil
+	}
+
+	v, _ := new(big.Int).SetString(digits, 10)
+	if neg {
+		v.Neg(v)
+	}
+	return bigToInt256(v), nil
+}
+
+const (
+	// maxDecimalDigits is the precision of the Decimal256
+	maxDecimalDigits = 76
+	// maxInt64Digits is the count of the digits which always fit into int64
+	maxInt64Digits = 18
+)
+
+// splitDecimal splits the number like `-12.5e3` to the sign, the digits `125` and the exponent `2`.
+func splitDecimal(s string) (bool, string, int64, bool) {
+	var exp int64
+	if pos := strings.IndexAny(s, "eE"); pos != -1 {
+		var err error
+		exp, err = strconv.ParseInt(s[pos+1:], 10, 32)
+		if err != nil {
+			return false, "", 0, false
+		}
+		s = s[:pos]
+	}
+
+	neg := false
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" || !isDigits(intPart) || !isDigits(fracPart) {
+		return false, "", 0, false
+	}
+
+	digits := strings.TrimLeft(intPart+fracPart, "0")
+	if digits == "" {
+		return false, "0", 0, true
+	}
+	return neg, digits, exp - int64(len(fracPart)), true
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// bigToInt256 converts the integer to the two's complement representation.
+func bigToInt256(v *big.Int) proto.Int256 {
+	if v.Sign() < 0 {
+		v = new(big.Int).Add(v, new(big.Int).Lsh(big.NewInt(1), 256))
+	}
+
+	var b [32]byte
+	v.FillBytes(b[:])
+	return proto.Int256{
+		High: proto.UInt128{High: binary.BigEndian.Uint64(b[0:8]), Low: binary.BigEndian.Uint64(b[8:16])},
+		Low:  proto.UInt128{High: binary.BigEndian.Uint64(b[16:24]), Low: binary.BigEndian.Uint64(b[24:32])},
+	}
+}
@@ -28,6 +28,12 @@ func TestZeroValueNode(t *testing.T) {
 		NewColEnum8(enum8),
 		NewColEnum16(enum16),
 		NewColStringArray(),
+		NewColUInt64Array(),
+		NewColIPv4Array(),
+		NewColArray(NewColInt32(true)),
+		NewColMap(NewColString(false, false)),
+		NewColTuple(nil, []InsaneColInput{NewColString(false, false), NewColUInt64(false)}),
+		NewColTuple([]string{"a"}, []InsaneColInput{NewColFloat64(false)}),
 	}
 
 	for _, nullable := range []bool{true, false} {
@@ -46,6 +52,8 @@ func TestZeroValueNode(t *testing.T) {
 			NewColUInt128(nullable),
 			NewColIPv4(nullable),
 			NewColIPv6(nullable),
+			NewColDecimal(9, 2, nullable),
+			NewColDecimal(76, 10, nullable),
 		}...)
 	}
 
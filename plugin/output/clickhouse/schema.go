@@ -0,0 +1,262 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/ch-go"
+	"github.com/ClickHouse/ch-go/proto"
+	"github.com/ozontech/file.d/pipeline"
+	"go.uber.org/zap"
+)
+
+const (
+	addColumnBackoffMin = 10 * time.Second
+	addColumnBackoffMax = 10 * time.Minute
+)
+
+// columnBackoff delays the next attempt to add the column after the failed one.
+type columnBackoff struct {
+	delay   time.Duration
+	retryAt time.Time
+}
+
+// schema is the table columns used to insert the batches.
+// It's replaced as a whole when the table structure is changed, so the workers compare the pointers to detect the change.
+type schema struct {
+	columns []Column
+	query   string
+	// missingFields contains the fields of add_columns which the table doesn't have.
+	missingFields []string
+}
+
+func (p *Plugin) newSchema(columns []Column) (*schema, error) {
+	insaneColumns, err := p.inferColumns(columns)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &schema{
+		columns: columns,
+		query:   inputFromColumns(insaneColumns).Into(p.config.Table),
+	}
+	for _, field := range p.config.AddColumns {
+		if !slices.ContainsFunc(insaneColumns, func(col InsaneColumn) bool { return col.Field == field }) {
+			s.missingFields = append(s.missingFields, field)
+		}
+	}
+	return s, nil
+}
+
+// inferColumns infers the columns and applies field_renames to them.
+func (p *Plugin) inferColumns(columns []Column) ([]InsaneColumn, error) {
+	insaneColumns, err := inferInsaneColInputs(columns)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range insaneColumns {
+		col := &insaneColumns[i]
+		if field, ok := p.columnFields[col.Name]; ok && col.Field == col.Name {
+			col.Field = field
+		}
+	}
+	return insaneColumns, nil
+}
+
+// discoverColumns reads the insertable columns of the table from the system.columns.
+func (p *Plugin) discoverColumns(ctx context.Context) ([]Column, error) {
+	database := "currentDatabase()"
+	table := p.config.Table
+	if db, t, found := strings.Cut(table, "."); found {
+		database, table = quoteString(db), t
+	}
+	query := fmt.Sprintf("SELECT name, type FROM system.columns WHERE database = %s AND table = %s "+
+		"AND default_kind NOT IN ('MATERIALIZED', 'ALIAS', 'EPHEMERAL') ORDER BY position",
+		database, quoteString(table),
+	)
+
+	var (
+		columns []Column
+		names   proto.ColStr
+		types   proto.ColStr
+	)
+	err := p.doQuery(ctx, ch.Query{
+		Body: query,
+		Result: proto.Results{
+			{Name: "name", Data: &names},
+			{Name: "type", Data: &types},
+		},
+		OnResult: func(_ context.Context, _ proto.Block) error {
+			for i := 0; i < names.Rows(); i++ {
+				columns = append(columns, Column{Name: names.Row(i), Type: types.Row(i)})
+			}
+			names.Reset()
+			types.Reset()
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't read columns of table %q: %w", p.config.Table, err)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %q doesn't exist or has no columns", p.config.Table)
+	}
+
+	// the columns of the unsupported types are skipped, so clickhouse inserts the default values to them
+	supported := columns[:0]
+	for _, col := range columns {
+		if _, err := inferInsaneColInputs([]Column{col}); err != nil {
+			p.logger.Warn("column is skipped", zap.String("column", col.Name), zap.Error(err))
+			continue
+		}
+		supported = append(supported, col)
+	}
+	return supported, nil
+}
+
+// refreshSchema replaces the schema if the table structure is changed.
+func (p *Plugin) refreshSchema(ctx context.Context) error {
+	p.schemaMu.Lock()
+	defer p.schemaMu.Unlock()
+
+	columns, err := p.discoverColumns(ctx)
+	if err != nil {
+		return err
+	}
+
+	if current := p.schema.Load(); current != nil && slices.Equal(current.columns, columns) {
+		return nil
+	}
+
+	s, err := p.newSchema(columns)
+	if err != nil {
+		return err
+	}
+	p.schema.Store(s)
+	p.logger.Info("table schema is updated", zap.String("table", p.config.Table), zap.Any("columns", columns))
+	return nil
+}
+
+func (p *Plugin) refreshSchemaLoop() {
+	ticker := time.NewTicker(p.config.SchemaRefreshInterval_)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.refreshSchema(p.ctx); err != nil {
+				p.logger.Error("can't refresh table schema", zap.Error(err))
+			}
+		}
+	}
+}
+
+// addMissingColumns adds the columns for the fields of add_columns which are present in the batch
+// and returns the actual schema.
+func (p *Plugin) addMissingColumns(s *schema, batch *pipeline.Batch) *schema {
+	p.addColumnsMu.Lock()
+	defer p.addColumnsMu.Unlock()
+
+	// the columns may be added by another worker
+	if current := p.schema.Load(); current != s {
+		return current
+	}
+
+	now := time.Now()
+	var fields []string
+	batch.ForEach(func(event *pipeline.Event) {
+		for _, field := range s.missingFields {
+			if backoff, ok := p.failedColumns[field]; ok && now.Before(backoff.retryAt) {
+				continue
+			}
+			if !slices.Contains(fields, field) && event.Root.Dig(field) != nil {
+				fields = append(fields, field)
+			}
+		}
+	})
+	if len(fields) == 0 {
+		return s
+	}
+
+	added := false
+	for _, field := range fields {
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s",
+			p.config.Table, quoteIdentifier(field), p.config.AddColumnsType,
+		)
+		if err := p.doQueryAll(p.ctx, ch.Query{Body: query}); err != nil {
+			backoff := p.backoffColumn(field, now)
+			p.logger.Error("can't add column",
+				zap.String("column", field), zap.Duration("retry_in", backoff.delay), zap.Error(err),
+			)
+			continue
+		}
+		delete(p.failedColumns, field)
+		added = true
+		p.logger.Info("column is added", zap.String("column", field), zap.String("type", p.config.AddColumnsType))
+	}
+	if !added {
+		return s
+	}
+
+	if err := p.refreshSchema(p.ctx); err != nil {
+		p.logger.Error("can't refresh table schema", zap.Error(err))
+	}
+	return p.schema.Load()
+}
+
+// backoffColumn doubles the delay of the next attempt to add the column.
+func (p *Plugin) backoffColumn(field string, now time.Time) *columnBackoff {
+	if p.failedColumns == nil {
+		p.failedColumns = make(map[string]*columnBackoff)
+	}
+
+	backoff, ok := p.failedColumns[field]
+	if !ok {
+		backoff = &columnBackoff{delay: addColumnBackoffMin}
+		p.failedColumns[field] = backoff
+	} else {
+		backoff.delay = min(backoff.delay*2, addColumnBackoffMax)
+	}
+	backoff.retryAt = now.Add(backoff.delay)
+	return backoff
+}
+
+// doQuery runs the query on the instances in order until it succeeds.
+func (p *Plugin) doQuery(ctx context.Context, query ch.Query) error {
+	var err error
+	for _, clickhouse := range p.instances {
+		queryCtx, cancel := context.WithTimeout(ctx, p.config.InsertTimeout_)
+		err = clickhouse.Do(queryCtx, query)
+		cancel()
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// doQueryAll runs the query on every instance, since the instances may have their own tables.
+func (p *Plugin) doQueryAll(ctx context.Context, query ch.Query) error {
+	var errs []error
+	for _, clickhouse := range p.instances {
+		queryCtx, cancel := context.WithTimeout(ctx, p.config.InsertTimeout_)
+		errs = append(errs, clickhouse.Do(queryCtx, query))
+		cancel()
+	}
+	return errors.Join(errs...)
+}
+
+func quoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+func quoteIdentifier(s string) string {
+	return "`" + strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(s) + "`"
+}
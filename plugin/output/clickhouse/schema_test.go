@@ -0,0 +1,188 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/ch-go"
+	"github.com/ClickHouse/ch-go/proto"
+	"github.com/golang/mock/gomock"
+	"github.com/ozontech/file.d/pipeline"
+	mockclickhouse "github.com/ozontech/file.d/plugin/output/clickhouse/mock"
+	"github.com/stretchr/testify/require"
+	insaneJSON "github.com/vitkovskii/insane-json"
+	"go.uber.org/zap"
+)
+
+// expectColumns expects the query of system.columns and returns the columns in the result.
+func expectColumns(instance *mockclickhouse.MockClickhouse, columns ...Column) *gomock.Call {
+	return instance.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, q ch.Query) error {
+		if !strings.Contains(q.Body, "FROM system.columns WHERE database = currentDatabase() AND table = 'logs'") {
+			return errUnexpectedQuery(q.Body)
+		}
+
+		results := q.Result.(proto.Results)
+		names, types := results[0].Data.(*proto.ColStr), results[1].Data.(*proto.ColStr)
+		for _, col := range columns {
+			names.Append(col.Name)
+			types.Append(col.Type)
+		}
+		return q.OnResult(ctx, proto.Block{Rows: len(columns)})
+	})
+}
+
+type errUnexpectedQuery string
+
+func (e errUnexpectedQuery) Error() string {
+	return "unexpected query: " + string(e)
+}
+
+func newSchemaTestPlugin(t *testing.T, config *Config) (*Plugin, *mockclickhouse.MockClickhouse) {
+	t.Helper()
+
+	ctrl := gomock.NewController(t)
+	instance := mockclickhouse.NewMockClickhouse(ctrl)
+
+	config.Table = "logs"
+	config.AutoSchema = true
+	config.InsertTimeout_ = time.Second
+	if config.AddColumnsType == "" {
+		config.AddColumnsType = "Nullable(String)"
+	}
+
+	p := &Plugin{
+		config:       config,
+		logger:       zap.NewExample(),
+		ctx:          context.Background(),
+		instances:    []Clickhouse{instance},
+		columnFields: map[string]string{},
+	}
+	for field, column := range config.FieldRenames {
+		p.columnFields[column] = field
+	}
+	return p, instance
+}
+
+func TestDiscoverSchema(t *testing.T) {
+	p, instance := newSchemaTestPlugin(t, &Config{
+		FieldRenames: map[string]string{"@timestamp": "ts"},
+	})
+
+	expectColumns(instance,
+		Column{Name: "ts", Type: "DateTime64(3, 'UTC')"},
+		Column{Name: "message", Type: "String"},
+		Column{Name: "count", Type: "AggregateFunction(count)"},
+	)
+	require.NoError(t, p.refreshSchema(p.ctx))
+
+	s := p.schema.Load()
+	require.Equal(t, []Column{
+		{Name: "ts", Type: "DateTime64(3, 'UTC')"},
+		{Name: "message", Type: "String"},
+	}, s.columns, "the column of the unsupported type must be skipped")
+	require.Contains(t, s.query, `"ts","message"`)
+
+	columns, err := p.inferColumns(s.columns)
+	require.NoError(t, err)
+	require.Equal(t, "@timestamp", columns[0].Field)
+	require.Equal(t, "message", columns[1].Field)
+
+	expectColumns(instance,
+		Column{Name: "ts", Type: "DateTime64(3, 'UTC')"},
+		Column{Name: "message", Type: "String"},
+	)
+	require.NoError(t, p.refreshSchema(p.ctx))
+	require.Same(t, s, p.schema.Load(), "the schema mustn't be replaced if the columns aren't changed")
+
+	expectColumns(instance)
+	require.Error(t, p.refreshSchema(p.ctx), "the table without columns doesn't exist")
+}
+
+func TestAddMissingColumns(t *testing.T) {
+	p, instance := newSchemaTestPlugin(t, &Config{
+		AddColumns: []string{"user", "host"},
+	})
+
+	expectColumns(instance, Column{Name: "message", Type: "String"})
+	require.NoError(t, p.refreshSchema(p.ctx))
+	s := p.schema.Load()
+	require.Equal(t, []string{"user", "host"}, s.missingFields)
+
+	root, err := insaneJSON.DecodeString(`{"message":"hello","user":"bob","other":1}`)
+	require.NoError(t, err)
+	defer insaneJSON.Release(root)
+	batch := pipeline.NewPreparedBatch([]*pipeline.Event{{Root: root}})
+
+	gomock.InOrder(
+		instance.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, q ch.Query) error {
+			require.Equal(t, "ALTER TABLE logs ADD COLUMN IF NOT EXISTS `user` Nullable(String)", q.Body)
+			return nil
+		}),
+		expectColumns(instance,
+			Column{Name: "message", Type: "String"},
+			Column{Name: "user", Type: "Nullable(String)"},
+		),
+	)
+	s = p.addMissingColumns(s, batch)
+	require.Equal(t, []string{"host"}, s.missingFields)
+	require.Contains(t, s.query, `"message","user"`)
+
+	// the batch without the missing fields doesn't change the schema
+	require.Same(t, s, p.addMissingColumns(s, batch))
+}
+
+func TestAddMissingColumnsBackoff(t *testing.T) {
+	p, instance := newSchemaTestPlugin(t, &Config{
+		AddColumns: []string{"user"},
+	})
+	second := mockclickhouse.NewMockClickhouse(gomock.NewController(t))
+	p.instances = append(p.instances, second)
+
+	expectColumns(instance, Column{Name: "message", Type: "String"})
+	require.NoError(t, p.refreshSchema(p.ctx))
+	s := p.schema.Load()
+
+	root, err := insaneJSON.DecodeString(`{"message":"hello","user":"bob"}`)
+	require.NoError(t, err)
+	defer insaneJSON.Release(root)
+	batch := pipeline.NewPreparedBatch([]*pipeline.Event{{Root: root}})
+	errTimeout := errors.New("timeout")
+
+	expectAlter := func(instance *mockclickhouse.MockClickhouse, err error) *gomock.Call {
+		return instance.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, q ch.Query) error {
+			require.Equal(t, "ALTER TABLE logs ADD COLUMN IF NOT EXISTS `user` Nullable(String)", q.Body)
+			return err
+		})
+	}
+
+	// the column is added on every instance, so the failure on any of them is retried
+	expectAlter(instance, nil)
+	expectAlter(second, errTimeout)
+	require.Same(t, s, p.addMissingColumns(s, batch))
+	require.Equal(t, addColumnBackoffMin, p.failedColumns["user"].delay)
+
+	// the failed column isn't added until the backoff is passed
+	require.Same(t, s, p.addMissingColumns(s, batch))
+
+	p.failedColumns["user"].retryAt = time.Now()
+	expectAlter(instance, errTimeout)
+	expectAlter(second, errTimeout)
+	require.Same(t, s, p.addMissingColumns(s, batch))
+	require.Equal(t, 2*addColumnBackoffMin, p.failedColumns["user"].delay)
+
+	p.failedColumns["user"].retryAt = time.Now()
+	gomock.InOrder(
+		expectAlter(instance, nil),
+		expectColumns(instance,
+			Column{Name: "message", Type: "String"},
+			Column{Name: "user", Type: "Nullable(String)"},
+		),
+	)
+	expectAlter(second, nil)
+	s = p.addMissingColumns(s, batch)
+	require.Empty(t, s.missingFields)
+	require.Empty(t, p.failedColumns)
+}
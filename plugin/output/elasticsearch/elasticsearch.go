@@ -1,14 +1,17 @@
 package elasticsearch
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"fmt"
 	"math/rand"
 	"net/http"
+	"net/url"
 	"sync"
 	"time"
 
+	"github.com/klauspost/compress/gzip"
 	"github.com/ozontech/file.d/cfg"
 	"github.com/ozontech/file.d/fd"
 	"github.com/ozontech/file.d/logger"
@@ -25,15 +28,24 @@ import (
 /*{ introduction
 It sends events into Elasticsearch. It uses `_bulk` API to send events in batches.
 If a network error occurs, the batch will infinitely try to be delivered to the random endpoint.
+
+If some items of the `_bulk` request are failed with `429` or `5xx` statuses, only these items are sent again.
+The items failed with other statuses aren't retried, they are counted in `output_elasticsearch_index_error` metric.
+
+In the data stream mode the events are sent to the [data stream](https://www.elastic.co/guide/en/elasticsearch/reference/current/data-streams.html)
+named by `index_format` using the `create` operation. Every event must have the `@timestamp` field.
 }*/
 
 const (
 	outPluginType     = "elasticsearch"
 	NDJSONContentType = "application/x-ndjson"
+
+	compressionGzip = "gzip"
 )
 
 var (
 	strAuthorization = []byte(fasthttp.HeaderAuthorization)
+	strGzip          = []byte(compressionGzip)
 )
 
 type Plugin struct {
@@ -53,6 +65,7 @@ type Plugin struct {
 	// plugin metrics
 	sendErrorMetric      prometheus.Counter
 	indexingErrorsMetric prometheus.Counter
+	retriedItemsMetric   prometheus.Counter
 }
 
 // ! config-params
@@ -140,6 +153,32 @@ type Config struct {
 	// > > Check out [_bulk API doc](https://www.elastic.co/guide/en/elasticsearch/reference/current/docs-bulk.html) for details.
 	BatchOpType string `json:"batch_op_type" default:"index" options:"index|create"` // *
 
+	// > @3@4@5@6
+	// >
+	// > If set, the events are sent to the data stream named by `index_format`.
+	// > The `create` operation is always used in this mode, so `batch_op_type` is ignored.
+	DataStream bool `json:"data_stream" default:"false"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The name of the ingest pipeline to preprocess the events with.
+	// > > Check out [ingest pipelines doc](https://www.elastic.co/guide/en/elasticsearch/reference/current/ingest.html) for details.
+	Pipeline string `json:"pipeline"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The event field which value is used as the document `_id`.
+	// > It makes the retries idempotent: a retried document overwrites the written one or is rejected by the `create` operation.
+	// > If it's empty or the event doesn't have the field, elasticsearch generates the `_id`.
+	IDField  cfg.FieldSelector `json:"id_field" parse:"selector"` // *
+	IDField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The compression of the `_bulk` request bodies.
+	// > Set `none` if the cluster doesn't accept the compressed requests.
+	Compression string `json:"compression" default:"gzip" options:"none|gzip"` // *
+
 	// > @3@4@5@6
 	// >
 	// > Retries of insertion. If File.d cannot insert for this number of attempts,
@@ -166,6 +205,18 @@ type Config struct {
 
 type data struct {
 	outBuf []byte
+
+	gzipBuf    *bytes.Buffer
+	gzipWriter *gzip.Writer
+
+	// events are the events of the current request, the items of the response have the same order
+	events []*pipeline.Event
+
+	// retryEvents are the events to send again when the batch is retried,
+	// retryBatch and retrySeq identify that batch
+	retryEvents []*pipeline.Event
+	retryBatch  *pipeline.Batch
+	retrySeq    int64
 }
 
 func init() {
@@ -186,7 +237,12 @@ func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.OutputPluginP
 	p.config = config.(*Config)
 	p.registerMetrics(params.MetricCtl)
 	p.mu = &sync.Mutex{}
-	p.headerPrefix = `{"` + p.config.BatchOpType + `":{"_index":"`
+	opType := p.config.BatchOpType
+	if p.config.DataStream {
+		// data streams accept only the create operation
+		opType = "create"
+	}
+	p.headerPrefix = `{"` + opType + `":{"_index":"`
 
 	if len(p.config.IndexValues) == 0 {
 		p.config.IndexValues = append(p.config.IndexValues, "@time")
@@ -197,8 +253,13 @@ func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.OutputPluginP
 			endpoint = endpoint[:len(endpoint)-1]
 		}
 
+		endpoint += "/_bulk?_source=false"
+		if p.config.Pipeline != "" {
+			endpoint += "&pipeline=" + url.QueryEscape(p.config.Pipeline)
+		}
+
 		uri := &fasthttp.URI{}
-		if err := uri.Parse(nil, []byte(endpoint+"/_bulk?_source=false")); err != nil {
+		if err := uri.Parse(nil, []byte(endpoint)); err != nil {
 			logger.Fatalf("can't parse ES endpoint %s: %s", endpoint, err.Error())
 		}
 
@@ -284,12 +345,14 @@ func (p *Plugin) Out(event *pipeline.Event) {
 func (p *Plugin) registerMetrics(ctl *metric.Ctl) {
 	p.sendErrorMetric = ctl.RegisterCounter("output_elasticsearch_send_error", "Total elasticsearch send errors")
 	p.indexingErrorsMetric = ctl.RegisterCounter("output_elasticsearch_index_error", "Number of elasticsearch indexing errors")
+	p.retriedItemsMetric = ctl.RegisterCounter("output_elasticsearch_retried_items", "Number of elasticsearch items sent again because of the retriable statuses")
 }
 
 func (p *Plugin) out(workerData *pipeline.WorkerData, batch *pipeline.Batch) error {
 	if *workerData == nil {
 		*workerData = &data{
-			outBuf: make([]byte, 0, p.config.BatchSize_*p.avgEventSize),
+			outBuf:  make([]byte, 0, p.config.BatchSize_*p.avgEventSize),
+			gzipBuf: &bytes.Buffer{},
 		}
 	}
 
@@ -299,20 +362,42 @@ func (p *Plugin) out(workerData *pipeline.WorkerData, batch *pipeline.Batch) err
 		data.outBuf = make([]byte, 0, p.config.BatchSize_*p.avgEventSize)
 	}
 
+	data.events = data.events[:0]
+	if data.retryBatch == batch && data.retrySeq == batch.Seq() {
+		// the batch is retried, so only the failed events are sent again
+		data.events = append(data.events, data.retryEvents...)
+	} else {
+		batch.ForEach(func(event *pipeline.Event) {
+			data.events = append(data.events, event)
+		})
+	}
+	data.retryEvents = data.retryEvents[:0]
+	data.retryBatch = nil
+
 	data.outBuf = data.outBuf[:0]
-	batch.ForEach(func(event *pipeline.Event) {
+	for _, event := range data.events {
 		data.outBuf = p.appendEvent(data.outBuf, event)
-	})
+	}
 
-	err := p.send(data.outBuf)
+	err := p.send(data)
 	if err != nil {
 		p.sendErrorMetric.Inc()
 		p.logger.Error("can't send to the elastic, will try other endpoint", zap.Error(err))
+		data.retryEvents = append(data.retryEvents[:0], data.events...)
+	} else if len(data.retryEvents) > 0 {
+		p.retriedItemsMetric.Add(float64(len(data.retryEvents)))
+		err = fmt.Errorf("%d of %d events are failed with the retriable statuses", len(data.retryEvents), len(data.events))
+		p.logger.Warn("some events will be sent again", zap.Error(err))
+	}
+
+	if err != nil {
+		data.retryBatch = batch
+		data.retrySeq = batch.Seq()
 	}
 	return err
 }
 
-func (p *Plugin) send(body []byte) error {
+func (p *Plugin) send(data *data) error {
 	req := fasthttp.AcquireRequest()
 	defer fasthttp.ReleaseRequest(req)
 	resp := fasthttp.AcquireResponse()
@@ -320,7 +405,12 @@ func (p *Plugin) send(body []byte) error {
 
 	endpoint := p.endpoints[rand.Int()%len(p.endpoints)]
 	req.SetURI(endpoint)
-	req.SetBodyRaw(body)
+	if p.config.Compression == compressionGzip {
+		req.SetBodyRaw(p.compress(data))
+		req.Header.SetContentEncodingBytes(strGzip)
+	} else {
+		req.SetBodyRaw(data.outBuf)
+	}
 	req.Header.SetMethod(fasthttp.MethodPost)
 	req.Header.SetContentType(NDJSONContentType)
 	p.setAuthHeader(req)
@@ -341,15 +431,30 @@ func (p *Plugin) send(body []byte) error {
 	}
 	defer insaneJSON.Release(root)
 
-	p.reportESErrors(root)
+	p.processItems(root, data)
 
 	return nil
 }
 
+func (p *Plugin) compress(data *data) []byte {
+	data.gzipBuf.Reset()
+	if data.gzipWriter == nil {
+		data.gzipWriter = gzip.NewWriter(data.gzipBuf)
+	} else {
+		data.gzipWriter.Reset(data.gzipBuf)
+	}
+
+	// writing to the bytes.Buffer never fails
+	_, _ = data.gzipWriter.Write(data.outBuf)
+	_ = data.gzipWriter.Close()
+	return data.gzipBuf.Bytes()
+}
+
 func (p *Plugin) appendEvent(outBuf []byte, event *pipeline.Event) []byte {
 	// index command
 	outBuf = p.appendIndexName(outBuf, event)
-	outBuf = append(outBuf, '\n')
+	outBuf = p.appendID(outBuf, event)
+	outBuf = append(outBuf, "}}\n"...)
 
 	// document
 	outBuf, _ = event.Encode(outBuf)
@@ -383,10 +488,32 @@ func (p *Plugin) appendIndexName(outBuf []byte, event *pipeline.Event) []byte {
 			outBuf = append(outBuf, value...)
 		}
 	}
-	outBuf = append(outBuf, "\"}}"...)
+	outBuf = append(outBuf, '"')
 	return outBuf
 }
 
+func (p *Plugin) appendID(outBuf []byte, event *pipeline.Event) []byte {
+	if len(p.config.IDField_) == 0 {
+		return outBuf
+	}
+
+	node := event.Root.Dig(p.config.IDField_...)
+	switch {
+	case node == nil:
+		return outBuf
+	case node.IsString():
+		outBuf = append(outBuf, `,"_id":`...)
+		// the string node is encoded with the quotes and the escaping
+		return node.Encode(outBuf)
+	case node.IsNumber():
+		outBuf = append(outBuf, `,"_id":"`...)
+		outBuf = append(outBuf, node.AsString()...)
+		return append(outBuf, '"')
+	default:
+		return outBuf
+	}
+}
+
 func (p *Plugin) maintenance(_ *pipeline.WorkerData) {
 	p.mu.Lock()
 	p.time = time.Now().Format(p.config.TimeFormat)
@@ -412,6 +539,9 @@ func (p *Plugin) setAuthHeader(req *fasthttp.Request) {
 	}
 }
 
+// processItems collects the events of the items failed with the retriable statuses into the retry events,
+// the items failed with other statuses are dropped.
+//
 // example of an ElasticSearch response that returned an indexing error for the first log:
 //
 //	{
@@ -453,7 +583,7 @@ func (p *Plugin) setAuthHeader(req *fasthttp.Request) {
 //	   }
 //	 ]
 //	}
-func (p *Plugin) reportESErrors(root *insaneJSON.Root) {
+func (p *Plugin) processItems(root *insaneJSON.Root, data *data) {
 	if !root.Dig("errors").AsBool() {
 		return
 	}
@@ -465,34 +595,48 @@ func (p *Plugin) reportESErrors(root *insaneJSON.Root) {
 		)
 		return
 	}
+	if len(items) != len(data.events) {
+		p.logger.Error("unknown elasticsearch error, count of items in the response doesn't match count of events",
+			zap.Int("items", len(items)), zap.Int("events", len(data.events)),
+		)
+		return
+	}
 
 	indexingErrors := 0
-	for _, node := range items {
-		indexNode := node.Dig("index")
-		if indexNode == nil {
-			p.logger.Error("unknown elasticsearch response, 'index' field in the response is empty",
+	for i, node := range items {
+		// the item is an object with the operation type as the only key
+		fields := node.AsFields()
+		if len(fields) == 0 {
+			p.logger.Error("unknown elasticsearch response, operation field in the response is empty",
 				zap.String("response", node.EncodeToString()),
 			)
 			continue
 		}
+		itemNode := fields[0].AsFieldValue()
 
-		if errNode := indexNode.Dig("error"); errNode != nil {
+		statusCode := itemNode.Dig("status").AsInt()
+		if statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError {
+			data.retryEvents = append(data.retryEvents, data.events[i])
+			continue
+		}
+
+		if errNode := itemNode.Dig("error"); errNode != nil {
 			indexingErrors++
 			p.logger.Error("elasticsearch indexing error",
 				zap.String("response", errNode.EncodeToString()))
 			continue
 		}
 
-		if statusCode := indexNode.Dig("status"); statusCode.AsInt() < http.StatusBadRequest {
+		if statusCode < http.StatusBadRequest {
 			continue
 		}
 
+		indexingErrors++
 		p.logger.Error("unknown elasticsearch error", zap.String("response", node.EncodeToString()))
 	}
 
 	if indexingErrors != 0 {
 		p.indexingErrorsMetric.Add(float64(indexingErrors))
+		p.logger.Error("some events from batch aren't written, check previous logs for more information")
 	}
-
-	p.logger.Error("some events from batch aren't written, check previous logs for more information")
 }
@@ -2,8 +2,13 @@ package elasticsearch
 
 import (
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/klauspost/compress/gzip"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	insaneJSON "github.com/vitkovskii/insane-json"
@@ -108,3 +113,121 @@ func TestConfig(t *testing.T) {
 		assert.Equal(t, results[i], p.endpoints[i].String())
 	}
 }
+
+func TestAppendEventWithDataStream(t *testing.T) {
+	p := &Plugin{}
+	config := &Config{
+		Endpoints:   []string{"http://endpoint:9000"},
+		IndexFormat: "logs-%-default",
+		IndexValues: []string{"service"},
+		BatchSize:   "1",
+		DataStream:  true,
+		Pipeline:    "my pipeline",
+		IDField:     "meta.id",
+	}
+	test.NewConfig(config, map[string]int{"gomaxprocs": 1})
+
+	p.Start(config, test.NewEmptyOutputPluginParams())
+
+	assert.Equal(t, "http://endpoint:9000/_bulk?_source=false&pipeline=my+pipeline", p.endpoints[0].String())
+
+	cases := []struct {
+		event  string
+		header string
+	}{
+		{
+			event:  `{"service":"api","meta":{"id":"a\"b"}}`,
+			header: `{"create":{"_index":"logs-api-default","_id":"a\"b"}}`,
+		},
+		{
+			event:  `{"service":"api","meta":{"id":42}}`,
+			header: `{"create":{"_index":"logs-api-default","_id":"42"}}`,
+		},
+		{
+			event:  `{"service":"api","meta":{"id":{"k":"v"}}}`,
+			header: `{"create":{"_index":"logs-api-default"}}`,
+		},
+		{
+			event:  `{"service":"api"}`,
+			header: `{"create":{"_index":"logs-api-default"}}`,
+		},
+	}
+	for _, tc := range cases {
+		root, err := insaneJSON.DecodeString(tc.event)
+		require.NoError(t, err)
+
+		result := p.appendEvent(nil, &pipeline.Event{Root: root})
+		assert.Equal(t, tc.header+"\n"+tc.event+"\n", string(result), "wrong request content")
+		insaneJSON.Release(root)
+	}
+}
+
+func TestRetryFailedItems(t *testing.T) {
+	var (
+		requests [][]string
+		response string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		reader, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(reader)
+		require.NoError(t, err)
+
+		var docs []string
+		lines := strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")
+		for i := 1; i < len(lines); i += 2 {
+			docs = append(docs, lines[i])
+		}
+		requests = append(requests, docs)
+
+		_, _ = w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	p := &Plugin{}
+	config := &Config{
+		Endpoints: []string{server.URL},
+		BatchSize: "1",
+	}
+	test.NewConfig(config, map[string]int{"gomaxprocs": 1})
+	p.Start(config, test.NewEmptyOutputPluginParams())
+	defer p.Stop()
+
+	newBatch := func() *pipeline.Batch {
+		events := make([]*pipeline.Event, 0)
+		for _, e := range []string{`{"n":1}`, `{"n":2}`, `{"n":3}`, `{"n":4}`} {
+			root, err := insaneJSON.DecodeString(e)
+			require.NoError(t, err)
+			t.Cleanup(func() { insaneJSON.Release(root) })
+			events = append(events, &pipeline.Event{Root: root})
+		}
+		return pipeline.NewPreparedBatch(events)
+	}
+
+	data := pipeline.WorkerData(nil)
+	batch := newBatch()
+
+	response = `{"errors":true,"items":[
+		{"index":{"status":201}},
+		{"index":{"status":429,"error":{"type":"es_rejected_execution_exception"}}},
+		{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}},
+		{"index":{"status":503,"error":{"type":"unavailable_shards_exception"}}}
+	]}`
+	require.Error(t, p.out(&data, batch), "retriable items must be retried")
+
+	response = `{"errors":true,"items":[{"index":{"status":201}},{"index":{"status":500}}]}`
+	require.Error(t, p.out(&data, batch), "retriable items must be retried")
+
+	response = `{"errors":false,"items":[{"index":{"status":201}}]}`
+	require.NoError(t, p.out(&data, batch))
+
+	require.NoError(t, p.out(&data, newBatch()))
+
+	require.Equal(t, [][]string{
+		{`{"n":1}`, `{"n":2}`, `{"n":3}`, `{"n":4}`},
+		{`{"n":2}`, `{"n":4}`},
+		{`{"n":4}`},
+		{`{"n":1}`, `{"n":2}`, `{"n":3}`, `{"n":4}`},
+	}, requests)
+}
@@ -19,6 +19,10 @@ import (
 
 /*{ introduction
 It sends event batches into files.
+
+In the `parquet` format the events are buffered in the file as usual,
+and the file is converted into the parquet file with the declared columns on sealing up.
+The sealed parquet files get the `.parquet` extension in addition to the extension of `target_file`.
 }*/
 
 type Plugable interface {
@@ -43,6 +47,8 @@ type Plugin struct {
 	fileName      string
 	tsFileName    string
 
+	parquetSchema *parquetSchema
+
 	SealUpCallback func(string)
 
 	mu *sync.RWMutex
@@ -111,6 +117,33 @@ type Config struct {
 	// > File mode for log files
 	FileMode  cfg.Base8 `json:"file_mode" default:"0666" parse:"base8"` // *
 	FileMode_ int64
+
+	// > @3@4@5@6
+	// >
+	// > Format of the sealed files:
+	// > * `json` – the events are written as NDJSON
+	// > * `parquet` – the events are converted into parquet with `parquet_columns` on sealing up
+	Format string `json:"format" default:"json" options:"json|parquet"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Columns of the parquet files. Each column has the name of the event field and the type:
+	// > `string`, `json`, `bool`, `int32`, `int64`, `float`, `double`, `timestamp`.
+	// >
+	// > The `json` columns contain the encoded field value, e.g. an object.
+	// > The `timestamp` columns have microsecond precision and are filled from unix timestamps in seconds or RFC3339 strings.
+	// > All the columns are nullable, the missing fields, the fields of a wrong type and the `int32` values out of its range are written as null.
+	ParquetColumns []ParquetColumn `json:"parquet_columns" slice:"true"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Maximum number of rows in a parquet row group.
+	ParquetRowGroupSize int `json:"parquet_row_group_size" default:"100000"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Compression codec of the parquet files.
+	ParquetCompression string `json:"parquet_compression" default:"snappy" options:"snappy|zstd"` // *
 }
 
 func init() {
@@ -135,6 +168,23 @@ func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.OutputPluginP
 	p.fileName = file[0 : len(file)-len(p.fileExtension)]
 	p.tsFileName = "%s" + "-" + p.fileName
 
+	if p.config.Format == formatParquet {
+		if p.fileExtension == parquetExtension {
+			p.logger.Fatalf("target file can't have %s extension in the parquet format, it's added to the sealed files", parquetExtension)
+		}
+		if p.config.ParquetRowGroupSize < 1 {
+			p.logger.Fatal("'parquet_row_group_size' can't be <1")
+		}
+		if _, ok := parquetCodecs[p.config.ParquetCompression]; !ok {
+			p.logger.Fatalf("parquet compression %q is not supported", p.config.ParquetCompression)
+		}
+		schema, err := newParquetSchema(p.config.ParquetColumns)
+		if err != nil {
+			p.logger.Fatalf("can't create parquet schema: %s", err.Error())
+		}
+		p.parquetSchema = schema
+	}
+
 	p.batcher = pipeline.NewBatcher(pipeline.BatcherOptions{
 		PipelineName:   params.PipelineName,
 		OutputType:     outPluginType,
@@ -155,6 +205,10 @@ func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.OutputPluginP
 		p.logger.Fatalf("could not create target dir: %s, error: %s", p.targetDir, err.Error())
 	}
 
+	if p.parquetSchema != nil {
+		p.convertSealedFiles()
+	}
+
 	p.idx = p.getStartIdx()
 	p.createNew()
 	p.setNextSealUpTime()
@@ -277,6 +331,9 @@ func (p *Plugin) sealUp() {
 	if err := oldFile.Close(); err != nil {
 		p.logger.Panicf("could not close file: %s, error: %s", oldFile.Name(), err.Error())
 	}
+	if p.parquetSchema != nil {
+		newFileName = p.convertToParquet(newFileName)
+	}
 	logger.Infof("sealing file, newFileName=%s", newFileName)
 	if p.SealUpCallback != nil {
 		go p.SealUpCallback(newFileName)
@@ -291,16 +348,49 @@ func (p *Plugin) rename(newFileName string) {
 	p.idx++
 }
 
-func (p *Plugin) getStartIdx() int {
+// sealedExtension returns the extension of the sealed files.
+func (p *Plugin) sealedExtension() string {
+	if p.config.Format == formatParquet {
+		return p.fileExtension + parquetExtension
+	}
+	return p.fileExtension
+}
+
+// convertToParquet replaces the sealed file with the parquet file and returns its name.
+func (p *Plugin) convertToParquet(fileName string) string {
+	parquetFileName := fileName + parquetExtension
+	if err := p.writeParquet(parquetFileName, fileName); err != nil {
+		p.logger.Panicf("could not write parquet file: %s, error: %s", parquetFileName, err.Error())
+	}
+	if err := os.Remove(fileName); err != nil {
+		p.logger.Panicf("could not delete file: %s, error: %s", fileName, err.Error())
+	}
+	return parquetFileName
+}
+
+// convertSealedFiles converts the files which were sealed up but weren't converted to parquet before the restart.
+func (p *Plugin) convertSealedFiles() {
 	pattern := fmt.Sprintf("%s/%s%s*%s*%s", p.targetDir, p.fileName, fileNameSeparator, fileNameSeparator, p.fileExtension)
 	matches, err := filepath.Glob(pattern)
 	if err != nil {
 		p.logger.Panic(err.Error())
 	}
+	for _, fileName := range matches {
+		logger.Infof("converting sealed file to parquet, fileName=%s", fileName)
+		p.convertToParquet(fileName)
+	}
+}
+
+func (p *Plugin) getStartIdx() int {
+	pattern := fmt.Sprintf("%s/%s%s*%s*%s", p.targetDir, p.fileName, fileNameSeparator, fileNameSeparator, p.sealedExtension())
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		p.logger.Panic(err.Error())
+	}
 	idx := -1
 	for _, v := range matches {
 		file := filepath.Base(v)
-		i := file[len(p.fileName)+len(fileNameSeparator) : len(file)-len(p.fileExtension)-len(p.config.Layout)-len(fileNameSeparator)]
+		i := file[len(p.fileName)+len(fileNameSeparator) : len(file)-len(p.sealedExtension())-len(p.config.Layout)-len(fileNameSeparator)]
 		maxIdx, err := strconv.Atoi(i)
 		if err != nil {
 			break
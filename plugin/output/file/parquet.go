@@ -0,0 +1,240 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+const (
+	formatJSON    = "json"
+	formatParquet = "parquet"
+
+	parquetExtension = ".parquet"
+
+	// parquetRowsPerWrite is the number of rows passed to the parquet writer at once.
+	parquetRowsPerWrite = 1024
+)
+
+const (
+	parquetTypeString    = "string"
+	parquetTypeJSON      = "json"
+	parquetTypeBool      = "bool"
+	parquetTypeInt32     = "int32"
+	parquetTypeInt64     = "int64"
+	parquetTypeFloat     = "float"
+	parquetTypeDouble    = "double"
+	parquetTypeTimestamp = "timestamp"
+)
+
+var parquetCodecs = map[string]compress.Codec{
+	"snappy": &parquet.Snappy,
+	"zstd":   &parquet.Zstd,
+}
+
+type ParquetColumn struct {
+	Name string `json:"name" required:"true"`
+	Type string `json:"type" required:"true" options:"string|json|bool|int32|int64|float|double|timestamp"`
+}
+
+// parquetSchema is the schema of the parquet files.
+// All the columns are optional, so the events without a field or with a field of a wrong type
+// are written with null in the column.
+type parquetSchema struct {
+	schema *parquet.Schema
+	// types contains the column types in order of the schema leaf columns.
+	types []string
+	names []string
+}
+
+func newParquetSchema(columns []ParquetColumn) (*parquetSchema, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("no parquet columns in config")
+	}
+
+	group := make(parquet.Group, len(columns))
+	types := make(map[string]string, len(columns))
+	for _, col := range columns {
+		if _, ok := group[col.Name]; ok {
+			return nil, fmt.Errorf("duplicated parquet column %q", col.Name)
+		}
+
+		var node parquet.Node
+		switch col.Type {
+		case parquetTypeString:
+			node = parquet.String()
+		case parquetTypeJSON:
+			node = parquet.JSON()
+		case parquetTypeBool:
+			node = parquet.Leaf(parquet.BooleanType)
+		case parquetTypeInt32:
+			node = parquet.Int(32)
+		case parquetTypeInt64:
+			node = parquet.Int(64)
+		case parquetTypeFloat:
+			node = parquet.Leaf(parquet.FloatType)
+		case parquetTypeDouble:
+			node = parquet.Leaf(parquet.DoubleType)
+		case parquetTypeTimestamp:
+			node = parquet.Timestamp(parquet.Microsecond)
+		default:
+			return nil, fmt.Errorf("invalid parquet type %q of column %q", col.Type, col.Name)
+		}
+		group[col.Name] = parquet.Optional(node)
+		types[col.Name] = col.Type
+	}
+
+	s := &parquetSchema{
+		schema: parquet.NewSchema("file.d", group),
+	}
+	// the group fields are sorted by name, so the leaf column indexes are the indexes of the fields
+	for _, field := range s.schema.Fields() {
+		s.names = append(s.names, field.Name())
+		s.types = append(s.types, types[field.Name()])
+	}
+	return s, nil
+}
+
+// appendRow appends the parquet row built from the event to the rows.
+func (s *parquetSchema) appendRow(rows []parquet.Row, root *insaneJSON.Root) []parquet.Row {
+	row := make(parquet.Row, len(s.names))
+	for i, name := range s.names {
+		value, ok := parquetValue(s.types[i], root.Dig(name))
+		if !ok {
+			row[i] = parquet.Value{}.Level(0, 0, i)
+			continue
+		}
+		row[i] = value.Level(0, 1, i)
+	}
+	return append(rows, row)
+}
+
+func parquetValue(typ string, node *insaneJSON.Node) (parquet.Value, bool) {
+	if node == nil || node.IsNull() {
+		return parquet.Value{}, false
+	}
+
+	switch typ {
+	case parquetTypeString:
+		if node.IsObject() || node.IsArray() {
+			return parquet.ByteArrayValue(node.EncodeToByte()), true
+		}
+		return parquet.ByteArrayValue([]byte(node.AsString())), true
+	case parquetTypeJSON:
+		return parquet.ByteArrayValue(node.EncodeToByte()), true
+	case parquetTypeBool:
+		if !node.IsTrue() && !node.IsFalse() {
+			return parquet.Value{}, false
+		}
+		return parquet.BooleanValue(node.IsTrue()), true
+	case parquetTypeInt32:
+		if !node.IsNumber() {
+			return parquet.Value{}, false
+		}
+		// the values out of the range would wrap around
+		value := node.AsInt64()
+		if value < math.MinInt32 || value > math.MaxInt32 {
+			return parquet.Value{}, false
+		}
+		return parquet.Int32Value(int32(value)), true
+	case parquetTypeInt64:
+		if !node.IsNumber() {
+			return parquet.Value{}, false
+		}
+		return parquet.Int64Value(node.AsInt64()), true
+	case parquetTypeFloat:
+		if !node.IsNumber() {
+			return parquet.Value{}, false
+		}
+		return parquet.FloatValue(float32(node.AsFloat())), true
+	case parquetTypeDouble:
+		if !node.IsNumber() {
+			return parquet.Value{}, false
+		}
+		return parquet.DoubleValue(node.AsFloat()), true
+	case parquetTypeTimestamp:
+		// numbers are unix timestamps in seconds, strings are RFC3339 times
+		if node.IsNumber() {
+			return parquet.Int64Value(int64(node.AsFloat() * float64(time.Second/time.Microsecond))), true
+		}
+		t, err := time.Parse(time.RFC3339Nano, node.AsString())
+		if err != nil {
+			return parquet.Value{}, false
+		}
+		return parquet.Int64Value(t.UnixMicro()), true
+	default:
+		return parquet.Value{}, false
+	}
+}
+
+// writeParquet converts the file with the encoded events into the parquet file.
+// The lines which aren't valid JSON are skipped.
+func (p *Plugin) writeParquet(dst, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(p.config.FileMode_))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = out.Close() }()
+
+	writer := parquet.NewWriter(out,
+		p.parquetSchema.schema,
+		parquet.Compression(parquetCodecs[p.config.ParquetCompression]),
+		parquet.MaxRowsPerRowGroup(int64(p.config.ParquetRowGroupSize)),
+	)
+
+	root := insaneJSON.Spawn()
+	defer insaneJSON.Release(root)
+
+	reader := bufio.NewReader(in)
+	rows := make([]parquet.Row, 0, parquetRowsPerWrite)
+	skipped := 0
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return readErr
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if err := root.DecodeBytes(line); err != nil {
+				skipped++
+			} else {
+				rows = p.parquetSchema.appendRow(rows, root)
+			}
+		}
+
+		if len(rows) == parquetRowsPerWrite || (readErr != nil && len(rows) > 0) {
+			if _, err := writer.WriteRows(rows); err != nil {
+				return err
+			}
+			rows = rows[:0]
+		}
+
+		if readErr != nil {
+			break
+		}
+	}
+
+	if skipped > 0 {
+		p.logger.Warnf("%d invalid lines are skipped while writing parquet file: %s", skipped, dst)
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,187 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ozontech/file.d/logger"
+	"github.com/ozontech/file.d/test"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+type parquetTestRow struct {
+	Msg     *string  `parquet:"msg,optional"`
+	Level   *int32   `parquet:"level,optional"`
+	Size    *int64   `parquet:"size,optional"`
+	Ratio   *float64 `parquet:"ratio,optional"`
+	Ok      *bool    `parquet:"ok,optional"`
+	Payload *string  `parquet:"payload,optional,json"`
+	TS      *int64   `parquet:"ts,optional"`
+}
+
+var parquetTestColumns = []ParquetColumn{
+	{Name: "msg", Type: parquetTypeString},
+	{Name: "level", Type: parquetTypeInt32},
+	{Name: "size", Type: parquetTypeInt64},
+	{Name: "ratio", Type: parquetTypeDouble},
+	{Name: "ok", Type: parquetTypeBool},
+	{Name: "payload", Type: parquetTypeJSON},
+	{Name: "ts", Type: parquetTypeTimestamp},
+}
+
+func TestNewParquetSchema(t *testing.T) {
+	_, err := newParquetSchema(parquetTestColumns)
+	require.NoError(t, err)
+
+	_, err = newParquetSchema(nil)
+	require.Error(t, err)
+
+	_, err = newParquetSchema([]ParquetColumn{{Name: "a", Type: parquetTypeString}, {Name: "a", Type: parquetTypeInt64}})
+	require.Error(t, err)
+
+	_, err = newParquetSchema([]ParquetColumn{{Name: "a", Type: "uuid"}})
+	require.Error(t, err)
+}
+
+func TestWriteParquet(t *testing.T) {
+	schema, err := newParquetSchema(parquetTestColumns)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "log.log")
+	dst := src + parquetExtension
+
+	data := `{"msg":"first","level":3,"size":12345678901,"ratio":0.5,"ok":true,"payload":{"a":[1,2]},"ts":1700000000}
+not a json
+{"msg":{"nested":1},"level":"3","ok":"true","ts":"2023-11-14T22:13:20.5Z"}
+{}
+`
+	require.NoError(t, os.WriteFile(src, []byte(data), 0o666))
+
+	for _, compression := range []string{"snappy", "zstd"} {
+		t.Run(compression, func(t *testing.T) {
+			p := &Plugin{
+				config: &Config{
+					FileMode_:           0o666,
+					ParquetRowGroupSize: 2,
+					ParquetCompression:  compression,
+				},
+				logger:        logger.Instance,
+				parquetSchema: schema,
+			}
+			require.NoError(t, p.writeParquet(dst, src))
+
+			rows, err := parquet.ReadFile[parquetTestRow](dst)
+			require.NoError(t, err)
+			require.Len(t, rows, 3)
+
+			ts := time.Unix(1700000000, 0).UnixMicro()
+			assert.Equal(t, "first", *rows[0].Msg)
+			assert.EqualValues(t, 3, *rows[0].Level)
+			assert.EqualValues(t, 12345678901, *rows[0].Size)
+			assert.Equal(t, 0.5, *rows[0].Ratio)
+			assert.True(t, *rows[0].Ok)
+			assert.Equal(t, `{"a":[1,2]}`, *rows[0].Payload)
+			assert.Equal(t, ts, *rows[0].TS)
+
+			// the fields of a wrong type are written as null
+			assert.Equal(t, `{"nested":1}`, *rows[1].Msg)
+			assert.Nil(t, rows[1].Level)
+			assert.Nil(t, rows[1].Ok)
+			assert.Equal(t, ts+500_000, *rows[1].TS)
+
+			assert.Equal(t, parquetTestRow{}, rows[2])
+		})
+	}
+}
+
+func TestParquetValueInt32(t *testing.T) {
+	for _, tc := range []struct {
+		in string
+		ok bool
+	}{
+		{in: `2147483647`, ok: true},
+		{in: `-2147483648`, ok: true},
+		{in: `2147483648`, ok: false},
+		{in: `-2147483649`, ok: false},
+	} {
+		root, err := insaneJSON.DecodeString(`{"v":` + tc.in + `}`)
+		require.NoError(t, err)
+
+		value, ok := parquetValue(parquetTypeInt32, root.Dig("v"))
+		require.Equal(t, tc.ok, ok, tc.in)
+		if ok {
+			require.Equal(t, tc.in, fmt.Sprint(value.Int32()))
+		}
+		insaneJSON.Release(root)
+	}
+}
+
+func TestSealUpParquet(t *testing.T) {
+	schema, err := newParquetSchema(parquetTestColumns)
+	require.NoError(t, err)
+
+	config := Config{
+		TargetFile:          targetFile,
+		RetentionInterval_:  200 * time.Millisecond,
+		Layout:              "01",
+		FileMode_:           0o666,
+		Format:              formatParquet,
+		ParquetRowGroupSize: 100,
+		ParquetCompression:  "snappy",
+	}
+
+	dir, file := filepath.Split(config.TargetFile)
+	extension := filepath.Ext(file)
+	test.ClearDir(t, dir)
+	createDir(t, dir)
+	defer test.ClearDir(t, dir)
+
+	d := []byte(`{"msg":"first"}` + "\n" + `{"msg":"second"}` + "\n")
+	testFileName := fmt.Sprintf(targetFileThreshold, time.Now().Unix(), fileNameSeparator)
+	f := createFile(t, testFileName, &d)
+	defer f.Close()
+
+	var sealed string
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	p := Plugin{
+		config:        &config,
+		logger:        logger.Instance,
+		mu:            &sync.RWMutex{},
+		file:          f,
+		targetDir:     dir,
+		fileExtension: extension,
+		fileName:      file[0 : len(file)-len(extension)],
+		tsFileName:    path.Base(testFileName),
+		parquetSchema: schema,
+		SealUpCallback: func(fileName string) {
+			sealed = fileName
+			wg.Done()
+		},
+	}
+
+	p.sealUp()
+	wg.Wait()
+
+	// the sealed file is replaced with the parquet file
+	matches := test.GetMatches(t, fmt.Sprintf("%s/*%s%s", dir, extension, parquetExtension))
+	require.Equal(t, []string{sealed}, matches)
+	assert.Len(t, test.GetMatches(t, fmt.Sprintf("%s/*%s", dir, extension)), 1)
+
+	rows, err := parquet.ReadFile[parquetTestRow](sealed)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "first", *rows[0].Msg)
+	assert.Equal(t, "second", *rows[1].Msg)
+
+	assert.Equal(t, 1, p.getStartIdx())
+}
@@ -2,29 +2,58 @@ package gelf
 
 import (
 	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"math/rand"
 	"net"
 	"time"
 )
 
-const transportTCP string = "tcp"
+const (
+	transportTCP string = "tcp"
+	transportUDP string = "udp"
+
+	// chunk header is the magic bytes, the message id, the sequence number and the sequence count
+	chunkHeaderSize = 2 + 8 + 1 + 1
+	maxChunks       = 128
+)
+
+var (
+	chunkMagic = []byte{0x1e, 0x0f}
+
+	errTooManyChunks = errors.New("message is too big, it requires more than 128 chunks")
+)
 
 type client struct {
 	conn    net.Conn
 	timeout time.Duration
+
+	// chunkSize is the maximum size of the UDP datagram
+	chunkSize int
+	chunkBuf  []byte
 }
 
-func newClient(address string, connTimeout, writeTimeout time.Duration, useTLS bool, tlsConfig *tls.Config) (c *client, err error) {
+func newClient(transport, address string, connTimeout, writeTimeout time.Duration, useTLS bool, tlsConfig *tls.Config) (c *client, err error) {
 	c = &client{timeout: writeTimeout}
 
 	if useTLS {
 		c.conn, err = tls.DialWithDialer(&net.Dialer{Timeout: connTimeout}, transportTCP, address, tlsConfig)
 	} else {
-		c.conn, err = net.DialTimeout(transportTCP, address, connTimeout)
+		c.conn, err = net.DialTimeout(transport, address, connTimeout)
 	}
 
 	return c, err
 }
 
+func newUDPClient(address string, connTimeout, writeTimeout time.Duration, chunkSize int) (*client, error) {
+	c, err := newClient(transportUDP, address, connTimeout, writeTimeout, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.chunkSize = chunkSize
+	return c, nil
+}
+
 func (g *client) send(data []byte) (int, error) {
 	if err := g.conn.SetWriteDeadline(time.Now().Add(g.timeout)); err != nil {
 		return 0, err
@@ -32,6 +61,40 @@ func (g *client) send(data []byte) (int, error) {
 	return g.conn.Write(data)
 }
 
+// sendChunked sends the message in one datagram or splits it into the GELF chunks if it doesn't fit.
+func (g *client) sendChunked(message []byte) error {
+	if len(message) <= g.chunkSize {
+		_, err := g.send(message)
+		return err
+	}
+
+	payloadSize := g.chunkSize - chunkHeaderSize
+	count := (len(message) + payloadSize - 1) / payloadSize
+	if count > maxChunks {
+		return errTooManyChunks
+	}
+
+	// the id only has to be unique among the messages being reassembled by the server
+	var id [8]byte
+	binary.BigEndian.PutUint64(id[:], rand.Uint64())
+
+	for seq := 0; seq < count; seq++ {
+		payload := message[seq*payloadSize:]
+		if len(payload) > payloadSize {
+			payload = payload[:payloadSize]
+		}
+
+		g.chunkBuf = append(g.chunkBuf[:0], chunkMagic...)
+		g.chunkBuf = append(g.chunkBuf, id[:]...)
+		g.chunkBuf = append(g.chunkBuf, byte(seq), byte(count))
+		g.chunkBuf = append(g.chunkBuf, payload...)
+		if _, err := g.send(g.chunkBuf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (g *client) close() error {
 	return g.conn.Close()
 }
@@ -0,0 +1,56 @@
+package gelf
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendChunked(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	const chunkSize = chunkHeaderSize + 10
+	c, err := newUDPClient(conn.LocalAddr().String(), time.Second, time.Second, chunkSize)
+	require.NoError(t, err)
+	defer c.close()
+
+	buf := make([]byte, 1024)
+	read := func() []byte {
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		return append([]byte(nil), buf[:n]...)
+	}
+
+	small := bytes.Repeat([]byte("x"), chunkSize)
+	require.NoError(t, c.sendChunked(small))
+	require.Equal(t, small, read())
+
+	message := bytes.Repeat([]byte("0123456789"), 3)
+	message = append(message, "end"...)
+	require.NoError(t, c.sendChunked(message))
+
+	var (
+		id        []byte
+		assembled []byte
+	)
+	for seq := 0; seq < 4; seq++ {
+		chunk := read()
+		require.LessOrEqual(t, len(chunk), chunkSize)
+		require.Equal(t, chunkMagic, chunk[:2])
+		if id == nil {
+			id = chunk[2:10]
+		}
+		require.Equal(t, id, chunk[2:10], "chunks must have the same id")
+		require.Equal(t, []byte{byte(seq), 4}, chunk[10:12])
+		assembled = append(assembled, chunk[chunkHeaderSize:]...)
+	}
+	require.Equal(t, message, assembled)
+
+	require.ErrorIs(t, c.sendChunked(make([]byte, 10*maxChunks+1)), errTooManyChunks)
+}
@@ -1,10 +1,15 @@
 package gelf
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io"
 	"strings"
 	"time"
 
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
 	"github.com/ozontech/file.d/cfg"
 	"github.com/ozontech/file.d/fd"
 	"github.com/ozontech/file.d/metric"
@@ -17,9 +22,13 @@ import (
 
 /*{ introduction
 It sends event batches to the GELF endpoint. Transport level protocol TCP or UDP is configurable.
-> It doesn't support UDP chunking. So don't use UDP if event size may be greater than 8192.
 
-GELF messages are separated by null byte. Each message is a JSON with the following fields:
+Every message is sent in its own datagram over UDP. The messages which are bigger than `chunk_size` are split into
+the [chunks](https://go2docs.graylog.org/current/getting_in_log_data/gelf.html#GELFviaUDP), the messages which require more than 128 chunks are dropped.
+The messages can be compressed with gzip or zlib over UDP.
+If sending of the batch fails over UDP, the retry sends only the messages which aren't sent yet.
+
+GELF messages are separated by null byte over TCP. Each message is a JSON with the following fields:
 * `version` *`string=1.1`*
 * `host` *`string`*
 * `short_message` *`string`*
@@ -36,8 +45,18 @@ Allowed characters in field names are letters, numbers, underscores, dashes, and
 
 const (
 	outPluginType = "gelf"
+
+	compressionNone = "none"
+	compressionGzip = "gzip"
+	compressionZlib = "zlib"
 )
 
+type compressor interface {
+	io.Writer
+	Close() error
+	Reset(w io.Writer)
+}
+
 type Plugin struct {
 	config       *Config
 	logger       *zap.SugaredLogger
@@ -57,6 +76,22 @@ type Config struct {
 	// > An address of gelf endpoint. Format: `HOST:PORT`. E.g. `localhost:12201`.
 	Endpoint string `json:"endpoint" required:"true"` // *
 
+	// > @3@4@5@6
+	// >
+	// > Transport level protocol.
+	Transport string `json:"transport" default:"tcp" options:"tcp|udp"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The compression of the messages. It's supported only for the `udp` transport.
+	Compression string `json:"compression" default:"none" options:"none|gzip|zlib"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The maximum size of the UDP datagram, the bigger messages are split into the chunks.
+	// > Use `1420` if the messages are sent over WAN.
+	ChunkSize int `json:"chunk_size" default:"8154"` // *
+
 	// > @3@4@5@6
 	// >
 	// > The plugin reconnects to endpoint periodically using this interval. It is useful if an endpoint is a load balancer.
@@ -182,6 +217,17 @@ type data struct {
 	outBuf    []byte
 	encodeBuf []byte
 	gelf      *client
+
+	// ends of the messages in outBuf for the udp transport
+	messageEnds []int
+	compressor  compressor
+	compressBuf *bytes.Buffer
+
+	// sent is the number of the sent messages of the failed batch,
+	// retryBatch and retrySeq identify that batch
+	sent       int
+	retryBatch *pipeline.Batch
+	retrySeq   int64
 }
 
 func init() {
@@ -214,6 +260,13 @@ func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.OutputPluginP
 	p.config.timestampFieldFormat = format
 	p.config.levelField = pipeline.ByteToStringUnsafe(p.formatExtraField(nil, p.config.LevelField))
 
+	if p.config.Transport == transportTCP && p.config.Compression != compressionNone {
+		p.logger.Fatalf("compression is supported only for the udp transport")
+	}
+	if p.config.Transport == transportUDP && p.config.ChunkSize <= chunkHeaderSize {
+		p.logger.Fatalf("chunk_size must be greater than %d", chunkHeaderSize)
+	}
+
 	batcherOpts := pipeline.BatcherOptions{
 		PipelineName:        params.PipelineName,
 		OutputType:          outPluginType,
@@ -284,11 +337,16 @@ func (p *Plugin) out(workerData *pipeline.WorkerData, batch *pipeline.Batch) err
 
 	outBuf := data.outBuf[:0]
 	encodeBuf := data.encodeBuf[:0]
+	data.messageEnds = data.messageEnds[:0]
 
 	batch.ForEach(func(event *pipeline.Event) {
 		encodeBuf = p.formatEvent(encodeBuf, event)
 		outBuf, _ = event.Encode(outBuf)
-		outBuf = append(outBuf, byte(0))
+		if p.config.Transport == transportUDP {
+			data.messageEnds = append(data.messageEnds, len(outBuf))
+		} else {
+			outBuf = append(outBuf, byte(0))
+		}
 	})
 
 	data.outBuf = outBuf
@@ -297,7 +355,15 @@ func (p *Plugin) out(workerData *pipeline.WorkerData, batch *pipeline.Batch) err
 	if data.gelf == nil {
 		p.logger.Infof("connecting to gelf address=%s", p.config.Endpoint)
 
-		gelf, err := newClient(p.config.Endpoint, p.config.ConnectionTimeout_, p.config.WriteTimeout_, false, nil)
+		var (
+			gelf *client
+			err  error
+		)
+		if p.config.Transport == transportUDP {
+			gelf, err = newUDPClient(p.config.Endpoint, p.config.ConnectionTimeout_, p.config.WriteTimeout_, p.config.ChunkSize)
+		} else {
+			gelf, err = newClient(transportTCP, p.config.Endpoint, p.config.ConnectionTimeout_, p.config.WriteTimeout_, false, nil)
+		}
 		if err != nil {
 			p.sendErrorMetric.Inc()
 			p.logger.Errorf("can't connect to gelf endpoint address=%s: %s", p.config.Endpoint, err.Error())
@@ -307,7 +373,12 @@ func (p *Plugin) out(workerData *pipeline.WorkerData, batch *pipeline.Batch) err
 		data.gelf = gelf
 	}
 
-	_, err := data.gelf.send(outBuf)
+	var err error
+	if p.config.Transport == transportUDP {
+		err = p.sendUDP(data, batch)
+	} else {
+		_, err = data.gelf.send(outBuf)
+	}
 	if err != nil {
 		p.sendErrorMetric.Inc()
 		p.logger.Errorf("can't send data to gelf address=%s, err: %s", p.config.Endpoint, err.Error())
@@ -320,13 +391,76 @@ func (p *Plugin) out(workerData *pipeline.WorkerData, batch *pipeline.Batch) err
 	return nil
 }
 
+// sendUDP sends every message of the batch in its own datagram or chunks.
+// The retry of the failed batch starts from the first message which isn't sent,
+// so the receiver doesn't get the duplicates.
+func (p *Plugin) sendUDP(data *data, batch *pipeline.Batch) error {
+	if data.retryBatch != batch || data.retrySeq != batch.Seq() {
+		data.sent = 0
+	}
+	data.retryBatch = nil
+
+	start := 0
+	if data.sent > 0 {
+		start = data.messageEnds[data.sent-1]
+	}
+	for i := data.sent; i < len(data.messageEnds); i++ {
+		end := data.messageEnds[i]
+		message := p.compress(data, data.outBuf[start:end])
+		start = end
+
+		err := data.gelf.sendChunked(message)
+		if errors.Is(err, errTooManyChunks) {
+			// the message can't be sent anyway, so the rest of the batch is sent
+			p.sendErrorMetric.Inc()
+			p.logger.Errorf("can't send message to gelf address=%s: %s, size=%d", p.config.Endpoint, err.Error(), len(message))
+			continue
+		}
+		if err != nil {
+			data.sent = i
+			data.retryBatch = batch
+			data.retrySeq = batch.Seq()
+			return err
+		}
+	}
+	data.sent = 0
+	return nil
+}
+
+func (p *Plugin) compress(data *data, message []byte) []byte {
+	if p.config.Compression == compressionNone {
+		return message
+	}
+
+	if data.compressor == nil {
+		data.compressBuf = &bytes.Buffer{}
+		switch p.config.Compression {
+		case compressionGzip:
+			data.compressor = gzip.NewWriter(data.compressBuf)
+		case compressionZlib:
+			data.compressor = zlib.NewWriter(data.compressBuf)
+		}
+	}
+
+	data.compressBuf.Reset()
+	data.compressor.Reset(data.compressBuf)
+	// writing to the bytes.Buffer never fails
+	_, _ = data.compressor.Write(message)
+	_ = data.compressor.Close()
+	return data.compressBuf.Bytes()
+}
+
 func (p *Plugin) maintenance(workerData *pipeline.WorkerData) {
 	if *workerData == nil {
 		return
 	}
 
-	p.logger.Infof("reconnecting worker...")
 	data := (*workerData).(*data)
+	if data.gelf == nil {
+		return
+	}
+
+	p.logger.Infof("reconnecting worker...")
 	_ = data.gelf.close()
 	data.gelf = nil
 }
@@ -1,14 +1,22 @@
 package gelf
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
+	"net"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
 	"github.com/ozontech/file.d/logger"
 	"github.com/ozontech/file.d/pipeline"
 	"github.com/ozontech/file.d/test"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	insaneJSON "github.com/vitkovskii/insane-json"
 )
 
@@ -117,3 +125,79 @@ func TestFormatEvent(t *testing.T) {
 		assert.Equal(t, expected, resultJSON, "wrong formatted event")
 	}
 }
+
+func TestCompress(t *testing.T) {
+	message := []byte(`{"version":"1.1","host":"h","short_message":"compressed"}`)
+	decompressors := map[string]func(r io.Reader) (io.Reader, error){
+		compressionGzip: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		compressionZlib: func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) },
+	}
+	for compression, decompress := range decompressors {
+		t.Run(compression, func(t *testing.T) {
+			p := &Plugin{config: &Config{Compression: compression}}
+			data := &data{}
+
+			// the compressor is reused between the messages
+			for i := 0; i < 2; i++ {
+				r, err := decompress(bytes.NewReader(p.compress(data, message)))
+				require.NoError(t, err)
+				got, err := io.ReadAll(r)
+				require.NoError(t, err)
+				require.Equal(t, message, got)
+			}
+		})
+	}
+
+	p := &Plugin{config: &Config{Compression: compressionNone}}
+	require.Equal(t, message, p.compress(&data{}, message))
+}
+
+// failingConn fails the writes after the limit is reached.
+type failingConn struct {
+	net.Conn
+	limit   int
+	written []string
+}
+
+func (c *failingConn) SetWriteDeadline(_ time.Time) error {
+	return nil
+}
+
+func (c *failingConn) Write(b []byte) (int, error) {
+	if len(c.written) >= c.limit {
+		return 0, errors.New("write failed")
+	}
+	c.written = append(c.written, string(b))
+	return len(b), nil
+}
+
+func TestSendUDPRetry(t *testing.T) {
+	p := &Plugin{
+		config: &Config{Compression: compressionNone},
+		logger: logger.Instance,
+	}
+
+	conn := &failingConn{limit: 2}
+	data := &data{
+		gelf:        &client{conn: conn, chunkSize: 1024},
+		outBuf:      []byte("abc"),
+		messageEnds: []int{1, 2, 3},
+	}
+	batch := pipeline.NewPreparedBatch(nil)
+
+	require.Error(t, p.sendUDP(data, batch))
+	require.Equal(t, []string{"a", "b"}, conn.written)
+
+	// the retry of the batch sends only the rest of the messages
+	conn.limit = 10
+	require.NoError(t, p.sendUDP(data, batch))
+	require.Equal(t, []string{"a", "b", "c"}, conn.written)
+
+	// the other batch is sent from the start after the failure
+	conn.limit = 4
+	require.Error(t, p.sendUDP(data, batch))
+	data.outBuf = []byte("xyz")
+	conn.limit = 10
+	require.NoError(t, p.sendUDP(data, pipeline.NewPreparedBatch(nil)))
+	require.Equal(t, []string{"a", "b", "c", "a", "x", "y", "z"}, conn.written)
+}
@@ -0,0 +1,463 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/ozontech/file.d/cfg"
+	"github.com/ozontech/file.d/fd"
+	"github.com/ozontech/file.d/metric"
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/xhttp"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+/*{ introduction
+It sends the batches of events to an HTTP endpoint, e.g. to an alerting webhook or to an ingestion API of some service.
+
+The batch is encoded into the request body depending on `encoding`:
+* `ndjson` – the events delimited by a new line,
+* `json_array` – the JSON array of the events,
+* `template` – the result of the [Go template](https://pkg.go.dev/text/template) `body_template`.
+
+The template gets the batch as `.Events`, the list of the decoded events, and `.Count`, the number of the events.
+The `json` function of the template encodes the value as JSON.
+
+The batch is retried with the exponential backoff on the network errors and on `retry_status_codes` responses.
+File.d exits on `fatal_status_codes` responses, the batches with other non `2xx` responses are dropped.
+
+**Example of sending alerts to Slack:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    output:
+      type: http
+      endpoint: https://hooks.slack.com/services/T000/B000/XXXX
+      encoding: template
+      body_template: '{"text": {{ json (printf "%d errors, the first one: %s" .Count (index .Events 0).message) }}}'
+      batch_size: 100
+      batch_flush_timeout: 10s
+    ...
+```
+}*/
+
+const (
+	outPluginType = "http"
+
+	encodingNDJSON    = "ndjson"
+	encodingJSONArray = "json_array"
+	encodingTemplate  = "template"
+
+	compressionGzip = "gzip"
+)
+
+var (
+	defaultRetryStatusCodes = []int{
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	}
+
+	contentTypes = map[string]string{
+		encodingNDJSON:    "application/x-ndjson",
+		encodingJSONArray: "application/json",
+		encodingTemplate:  "application/json",
+	}
+)
+
+type Plugin struct {
+	config     *Config
+	client     *xhttp.Client
+	logger     *zap.SugaredLogger
+	batcher    *pipeline.RetriableBatcher
+	controller pipeline.OutputPluginController
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	template   *template.Template
+	header     http.Header
+	retryCodes map[int]bool
+	fatalCodes map[int]bool
+
+	// plugin metrics
+	sendErrorMetric *prometheus.CounterVec
+}
+
+// ! config-params
+// ^ config-params
+type Config struct {
+	// > @3@4@5@6
+	// >
+	// > A full URI of the endpoint. Format: `https://example.com/api/v1/events`.
+	Endpoint string `json:"endpoint" required:"true"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The method of the requests.
+	Method string `json:"method" default:"POST" options:"POST|PUT|PATCH"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The headers of the requests. They override the headers set by the plugin, e.g. `Content-Type`.
+	Headers map[string]string `json:"headers"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Username for HTTP Basic Authentication.
+	Username string `json:"username"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Password for HTTP Basic Authentication.
+	Password string `json:"password"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Token for HTTP Bearer Authentication. It takes precedence over `username` and `password`.
+	BearerToken string `json:"bearer_token"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Path or content of a PEM-encoded CA file.
+	CACert string `json:"ca_cert"` // *
+
+	// > @3@4@5@6
+	// >
+	// > How to encode the batch into the request body.
+	Encoding string `json:"encoding" default:"ndjson" options:"ndjson|json_array|template"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The Go template of the request body. Required for the `template` encoding.
+	BodyTemplate string `json:"body_template"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The compression of the request body.
+	Compression string `json:"compression" default:"none" options:"none|gzip"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The response status codes to retry the batch on. Defaults to `[429, 500, 502, 503, 504]`.
+	RetryStatusCodes []int `json:"retry_status_codes"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The response status codes to exit file.d on, e.g. `[401, 403]` if the batches mustn't be lost because of the wrong credentials.
+	FatalStatusCodes []int `json:"fatal_status_codes"` // *
+
+	// > @3@4@5@6
+	// >
+	// > How many workers will be instantiated to send batches.
+	WorkersCount  cfg.Expression `json:"workers_count" default:"gomaxprocs*4" parse:"expression"` // *
+	WorkersCount_ int
+
+	// > @3@4@5@6
+	// >
+	// > Client timeout when sends requests.
+	RequestTimeout  cfg.Duration `json:"request_timeout" default:"5s" parse:"duration"` // *
+	RequestTimeout_ time.Duration
+
+	// > @3@4@5@6
+	// >
+	// > A maximum quantity of events to pack into one batch.
+	BatchSize  cfg.Expression `json:"batch_size" default:"capacity/4" parse:"expression"` // *
+	BatchSize_ int
+
+	// > @3@4@5@6
+	// >
+	// > A minimum size of events in a batch to send.
+	// > If both batch_size and batch_size_bytes are set, they will work together.
+	BatchSizeBytes  cfg.Expression `json:"batch_size_bytes" default:"0" parse:"expression"` // *
+	BatchSizeBytes_ int
+
+	// > @3@4@5@6
+	// >
+	// > After this timeout the batch will be sent even if batch isn't completed.
+	BatchFlushTimeout  cfg.Duration `json:"batch_flush_timeout" default:"200ms" parse:"duration"` // *
+	BatchFlushTimeout_ time.Duration
+
+	// > @3@4@5@6
+	// >
+	// > Retries of sending. If File.d cannot send for this number of attempts,
+	// > File.d will fall with non-zero exit code or skip message (see fatal_on_failed_insert).
+	Retry int `json:"retry" default:"10"` // *
+
+	// > @3@4@5@6
+	// >
+	// > After a send error, fall with a non-zero exit code or not
+	// > **Experimental feature**
+	FatalOnFailedInsert bool `json:"fatal_on_failed_insert" default:"false"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Retention milliseconds for retry.
+	Retention  cfg.Duration `json:"retention" default:"1s" parse:"duration"` // *
+	Retention_ time.Duration
+
+	// > @3@4@5@6
+	// >
+	// > Multiplier for exponential increase of retention between retries
+	RetentionExponentMultiplier int `json:"retention_exponentially_multiplier" default:"2"` // *
+}
+
+type templateData struct {
+	Events []any
+	Count  int
+}
+
+type data struct {
+	outBuf     []byte
+	gzipBuf    *bytes.Buffer
+	gzipWriter *gzip.Writer
+	tplBuf     *bytes.Buffer
+	tplData    templateData
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterOutput(&pipeline.PluginStaticInfo{
+		Type:    outPluginType,
+		Factory: Factory,
+	})
+}
+
+func Factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.OutputPluginParams) {
+	p.controller = params.Controller
+	p.logger = params.Logger
+	p.config = config.(*Config)
+	p.registerMetrics(params.MetricCtl)
+
+	p.prepare()
+
+	batcherOpts := pipeline.BatcherOptions{
+		PipelineName:   params.PipelineName,
+		OutputType:     outPluginType,
+		MaintenanceFn:  p.maintenance,
+		Controller:     p.controller,
+		Workers:        p.config.WorkersCount_,
+		BatchSizeCount: p.config.BatchSize_,
+		BatchSizeBytes: p.config.BatchSizeBytes_,
+		FlushTimeout:   p.config.BatchFlushTimeout_,
+		MetricCtl:      params.MetricCtl,
+	}
+
+	backoffOpts := pipeline.BackoffOpts{
+		MinRetention: p.config.Retention_,
+		Multiplier:   float64(p.config.RetentionExponentMultiplier),
+		AttemptNum:   p.config.Retry,
+	}
+
+	p.batcher = pipeline.NewRetriableBatcher(
+		&batcherOpts,
+		p.out,
+		backoffOpts,
+		xhttp.OnError(p.logger, "can't send data to http endpoint", p.config.FatalOnFailedInsert, p.config.Retry),
+	)
+
+	p.batcher.Start(p.ctx)
+}
+
+// prepare parses the body template and the status codes and creates the client.
+func (p *Plugin) prepare() {
+	if p.config.Encoding == encodingTemplate {
+		if p.config.BodyTemplate == "" {
+			p.logger.Fatal("body_template is required for the template encoding")
+		}
+
+		tpl, err := template.New("body").Funcs(template.FuncMap{"json": toJSON}).Parse(p.config.BodyTemplate)
+		if err != nil {
+			p.logger.Fatalf("can't parse body_template: %s", err.Error())
+		}
+		p.template = tpl
+	}
+
+	retryCodes := p.config.RetryStatusCodes
+	if len(retryCodes) == 0 {
+		retryCodes = defaultRetryStatusCodes
+	}
+	p.retryCodes = make(map[int]bool, len(retryCodes))
+	for _, code := range retryCodes {
+		p.retryCodes[code] = true
+	}
+	p.fatalCodes = make(map[int]bool, len(p.config.FatalStatusCodes))
+	for _, code := range p.config.FatalStatusCodes {
+		if p.retryCodes[code] {
+			p.logger.Fatalf("status code %d is both in retry_status_codes and fatal_status_codes", code)
+		}
+		p.fatalCodes[code] = true
+	}
+
+	client, err := xhttp.NewClient(&xhttp.ClientConfig{
+		Timeout:     p.config.RequestTimeout_,
+		CACert:      p.config.CACert,
+		BearerToken: p.config.BearerToken,
+		Username:    p.config.Username,
+		Password:    p.config.Password,
+	})
+	if err != nil {
+		p.logger.Fatalf("can't create http client: %s", err.Error())
+	}
+	p.client = client
+
+	p.header = http.Header{}
+	p.header.Set("Content-Type", contentTypes[p.config.Encoding])
+	if p.config.Compression == compressionGzip {
+		p.header.Set("Content-Encoding", compressionGzip)
+	}
+	for name, value := range p.config.Headers {
+		p.header.Set(name, value)
+	}
+
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+}
+
+func (p *Plugin) registerMetrics(ctl *metric.Ctl) {
+	p.sendErrorMetric = ctl.RegisterCounterVec(
+		"output_http_send_error",
+		"Total http send errors",
+		"status_code",
+	)
+}
+
+func (p *Plugin) Stop() {
+	p.batcher.Stop()
+	p.cancel()
+}
+
+func (p *Plugin) Out(event *pipeline.Event) {
+	p.batcher.Add(event)
+}
+
+func (p *Plugin) out(workerData *pipeline.WorkerData, batch *pipeline.Batch) error {
+	if *workerData == nil {
+		*workerData = &data{
+			gzipBuf: &bytes.Buffer{},
+			tplBuf:  &bytes.Buffer{},
+		}
+	}
+	data := (*workerData).(*data)
+
+	body, err := p.encode(data, batch)
+	if err != nil {
+		// the same batch can't be encoded on retry
+		p.logger.Errorf("can't encode batch: %s", err.Error())
+		return nil
+	}
+
+	if p.config.Compression == compressionGzip {
+		body = p.compress(data, body)
+	}
+
+	code, _, err := p.client.Send(p.ctx, p.config.Method, p.config.Endpoint, p.header, body)
+	if err == nil {
+		return nil
+	}
+
+	p.sendErrorMetric.WithLabelValues(strconv.Itoa(code)).Inc()
+	p.logger.Errorf("can't send data to http endpoint=%s: %s", p.config.Endpoint, err.Error())
+
+	switch {
+	case code == 0 || p.retryCodes[code]:
+		return err
+	case p.fatalCodes[code]:
+		p.logger.Fatalf("fatal response from http endpoint=%s: %s", p.config.Endpoint, err.Error())
+	}
+	return nil
+}
+
+func (p *Plugin) encode(data *data, batch *pipeline.Batch) ([]byte, error) {
+	outBuf := data.outBuf[:0]
+
+	switch p.config.Encoding {
+	case encodingNDJSON:
+		batch.ForEach(func(event *pipeline.Event) {
+			outBuf, _ = event.Encode(outBuf)
+			outBuf = append(outBuf, '\n')
+		})
+	case encodingJSONArray:
+		outBuf = append(outBuf, '[')
+		batch.ForEach(func(event *pipeline.Event) {
+			if len(outBuf) > 1 {
+				outBuf = append(outBuf, ',')
+			}
+			outBuf, _ = event.Encode(outBuf)
+		})
+		outBuf = append(outBuf, ']')
+	case encodingTemplate:
+		return p.render(data, batch)
+	}
+
+	data.outBuf = outBuf
+	return outBuf, nil
+}
+
+func (p *Plugin) render(data *data, batch *pipeline.Batch) ([]byte, error) {
+	events := data.tplData.Events[:0]
+	var err error
+	batch.ForEach(func(event *pipeline.Event) {
+		if err != nil {
+			return
+		}
+
+		data.outBuf, _ = event.Encode(data.outBuf[:0])
+		decoder := json.NewDecoder(bytes.NewReader(data.outBuf))
+		decoder.UseNumber()
+		var e any
+		err = decoder.Decode(&e)
+		events = append(events, e)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't decode event: %w", err)
+	}
+
+	data.tplData.Events = events
+	data.tplData.Count = len(events)
+	data.tplBuf.Reset()
+	err = p.template.Execute(data.tplBuf, &data.tplData)
+
+	// the decoded events aren't needed anymore
+	for i := range events {
+		events[i] = nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't execute body_template: %w", err)
+	}
+	return data.tplBuf.Bytes(), nil
+}
+
+func (p *Plugin) compress(data *data, body []byte) []byte {
+	data.gzipBuf.Reset()
+	if data.gzipWriter == nil {
+		data.gzipWriter = gzip.NewWriter(data.gzipBuf)
+	} else {
+		data.gzipWriter.Reset(data.gzipBuf)
+	}
+
+	// writing to the bytes.Buffer never fails
+	_, _ = data.gzipWriter.Write(body)
+	_ = data.gzipWriter.Close()
+	return data.gzipBuf.Bytes()
+}
+
+func toJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func (p *Plugin) maintenance(_ *pipeline.WorkerData) {}
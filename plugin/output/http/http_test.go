@@ -0,0 +1,160 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/ozontech/file.d/metric"
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/test"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	insaneJSON "github.com/vitkovskii/insane-json"
+	"go.uber.org/zap"
+)
+
+func newTestPlugin(t *testing.T, config *Config) *Plugin {
+	t.Helper()
+
+	test.NewConfig(config, map[string]int{"gomaxprocs": 1, "capacity": 64})
+	p := &Plugin{
+		config: config,
+		logger: zap.NewExample().Sugar(),
+	}
+	p.registerMetrics(metric.NewCtl("test", prometheus.NewRegistry()))
+	p.prepare()
+	return p
+}
+
+func newTestBatch(t *testing.T, events ...string) *pipeline.Batch {
+	t.Helper()
+
+	batch := make([]*pipeline.Event, 0, len(events))
+	for _, e := range events {
+		root, err := insaneJSON.DecodeString(e)
+		require.NoError(t, err)
+		t.Cleanup(func() { insaneJSON.Release(root) })
+		batch = append(batch, &pipeline.Event{Root: root})
+	}
+	return pipeline.NewPreparedBatch(batch)
+}
+
+type request struct {
+	method  string
+	headers http.Header
+	body    string
+}
+
+func newTestServer(t *testing.T, status int, req *request) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := io.Reader(r.Body)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			body = zr
+		}
+		b, err := io.ReadAll(body)
+		require.NoError(t, err)
+
+		*req = request{method: r.Method, headers: r.Header, body: string(b)}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTP(t *testing.T) {
+	events := []string{`{"level":"error","message":"first"}`, `{"level":"warn","message":"second"}`}
+
+	cases := []struct {
+		name   string
+		config Config
+
+		wantBody        string
+		wantContentType string
+	}{
+		{
+			name:            "ndjson",
+			config:          Config{Encoding: encodingNDJSON},
+			wantBody:        events[0] + "\n" + events[1] + "\n",
+			wantContentType: "application/x-ndjson",
+		},
+		{
+			name:            "json_array",
+			config:          Config{Encoding: encodingJSONArray, Compression: compressionGzip},
+			wantBody:        "[" + events[0] + "," + events[1] + "]",
+			wantContentType: "application/json",
+		},
+		{
+			name: "template",
+			config: Config{
+				Encoding:     encodingTemplate,
+				BodyTemplate: `{"text":{{ json (printf "%d events, first: %s" .Count (index .Events 0).message) }},"levels":[{{ range $i, $e := .Events }}{{ if $i }},{{ end }}{{ json $e.level }}{{ end }}]}`,
+				Headers:      map[string]string{"Content-Type": "application/vnd.api+json"},
+			},
+			wantBody:        `{"text":"2 events, first: first","levels":["error","warn"]}`,
+			wantContentType: "application/vnd.api+json",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var req request
+			server := newTestServer(t, http.StatusOK, &req)
+
+			tc.config.Endpoint = server.URL
+			tc.config.Method = http.MethodPut
+			tc.config.BearerToken = "secret"
+			p := newTestPlugin(t, &tc.config)
+
+			data := pipeline.WorkerData(nil)
+			require.NoError(t, p.out(&data, newTestBatch(t, events...)))
+
+			require.Equal(t, http.MethodPut, req.method)
+			require.Equal(t, tc.wantBody, req.body)
+			require.Equal(t, tc.wantContentType, req.headers.Get("Content-Type"))
+			require.Equal(t, "Bearer secret", req.headers.Get("Authorization"))
+		})
+	}
+}
+
+func TestHTTPStatusCodes(t *testing.T) {
+	cases := []struct {
+		name       string
+		status     int
+		retryCodes []int
+		wantRetry  bool
+	}{
+		{name: "ok", status: http.StatusAccepted},
+		{name: "default_retry", status: http.StatusServiceUnavailable, wantRetry: true},
+		{name: "dropped", status: http.StatusBadRequest},
+		{name: "custom_retry", status: http.StatusConflict, retryCodes: []int{http.StatusConflict}, wantRetry: true},
+		{name: "custom_not_retry", status: http.StatusServiceUnavailable, retryCodes: []int{http.StatusConflict}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var req request
+			server := newTestServer(t, tc.status, &req)
+
+			p := newTestPlugin(t, &Config{
+				Endpoint:         server.URL,
+				Username:         "user",
+				Password:         "pass",
+				RetryStatusCodes: tc.retryCodes,
+			})
+
+			data := pipeline.WorkerData(nil)
+			err := p.out(&data, newTestBatch(t, `{"message":"test"}`))
+			if tc.wantRetry {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, "Basic dXNlcjpwYXNz", req.headers.Get("Authorization"))
+		})
+	}
+}
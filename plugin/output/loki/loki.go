@@ -0,0 +1,627 @@
+package loki
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/ozontech/file.d/cfg"
+	"github.com/ozontech/file.d/fd"
+	"github.com/ozontech/file.d/metric"
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/xhttp"
+	"github.com/prometheus/client_golang/prometheus"
+	insaneJSON "github.com/vitkovskii/insane-json"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+/*{ introduction
+It sends events to [Grafana Loki](https://grafana.com/oss/loki/) with the push API.
+The batches are sent as the snappy compressed protobuf `PushRequest` to `/loki/api/v1/push`.
+
+The labels of the stream of the event are taken from the fields of the event (`labels`) and from the config (`static_labels`).
+Loki performs poorly with high cardinality labels, so the number of the values of every label is limited with `max_label_values`.
+The values above the limit are replaced with `__overflow__`, the label of the event with no label fields gets the `job="file.d"` label.
+The values which aren't seen for `label_values_ttl` are forgotten and don't count in the limit anymore.
+
+The line of the entry is the whole event or the value of `message_field`.
+The timestamp of the entry is taken from `time_field`, the events without it get the time of sending.
+
+The batch is retried with the exponential backoff on the network errors and on the error responses.
+The batches rejected by Loki with `400 Bad Request` because of the out of order or too far behind entries aren't retried since they would be rejected again.
+Such batches are dropped and counted by the `output_loki_dropped_batches_total` metric with the `reason` label.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    output:
+      type: loki
+      address: http://loki:3100
+      tenant_id: team-a
+      labels:
+        service: k8s_label_app
+        namespace: k8s_namespace
+        level: level
+      static_labels:
+        cluster: prod-1
+      message_field: message
+      time_field: time
+    ...
+```
+}*/
+
+const (
+	outPluginType = "loki"
+
+	pushPath = "/loki/api/v1/push"
+
+	overflowLabelValue = "__overflow__"
+	noLabels           = `{job="file.d"}`
+
+	// guardSweepInterval limits how often the expired values of the full label are removed,
+	// since the full label is checked for every new value.
+	guardSweepInterval = time.Second
+)
+
+var labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+type Plugin struct {
+	config     *Config
+	client     *xhttp.Client
+	logger     *zap.SugaredLogger
+	batcher    *pipeline.RetriableBatcher
+	controller pipeline.OutputPluginController
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	pushURL string
+	header  http.Header
+	labels  []label
+	guard   *labelGuard
+
+	// plugin metrics
+	sendErrorMetric     *prometheus.CounterVec
+	droppedBatchMetric  *prometheus.CounterVec
+	labelOverflowMetric *prometheus.CounterVec
+}
+
+type label struct {
+	name  string
+	field []string // nil for the static labels
+	value string
+}
+
+// ! config-params
+// ^ config-params
+type Config struct {
+	// > @3@4@5@6
+	// >
+	// > The address of Loki. Format: `http://127.0.0.1:3100`.
+	Address string `json:"address" required:"true"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The tenant ID which is sent in the `X-Scope-OrgID` header. Isn't sent if empty.
+	TenantID string `json:"tenant_id"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Username for HTTP Basic Authentication.
+	Username string `json:"username"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Password for HTTP Basic Authentication.
+	Password string `json:"password"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Token for HTTP Bearer Authentication. It takes precedence over `username` and `password`.
+	BearerToken string `json:"bearer_token"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Path or content of a PEM-encoded CA file.
+	CACert string `json:"ca_cert"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The labels of the stream taken from the event: the label name to the event field.
+	// > The label is omitted if the event has no field.
+	Labels map[string]string `json:"labels"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The labels of the stream which are the same for all the events.
+	StaticLabels map[string]string `json:"static_labels"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The maximum number of the values of every label from `labels`.
+	// > The values above the limit are replaced with `__overflow__`. Zero means no limit.
+	MaxLabelValues int `json:"max_label_values" default:"1000"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The values of the label from `labels` which aren't seen for this time are forgotten,
+	// > so the new values can take their place within `max_label_values`. Zero means the values are never forgotten.
+	LabelValuesTTL  cfg.Duration `json:"label_values_ttl" default:"1h" parse:"duration"` // *
+	LabelValuesTTL_ time.Duration
+
+	// > @3@4@5@6
+	// >
+	// > The event field to use as the line of the entry. The whole event is sent if empty or the event has no field.
+	MessageField  cfg.FieldSelector `json:"message_field" parse:"selector"` // *
+	MessageField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The event field with the time of the entry.
+	TimeField  cfg.FieldSelector `json:"time_field" default:"time" parse:"selector"` // *
+	TimeField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The format of `time_field`. It can be the name of the predefined format, e.g. `rfc3339nano`, or the Go time layout.
+	TimeFormat string `json:"time_format" default:"rfc3339nano"` // *
+
+	// > @3@4@5@6
+	// >
+	// > How many workers will be instantiated to send batches.
+	WorkersCount  cfg.Expression `json:"workers_count" default:"gomaxprocs*4" parse:"expression"` // *
+	WorkersCount_ int
+
+	// > @3@4@5@6
+	// >
+	// > Client timeout when sends requests to Loki.
+	RequestTimeout  cfg.Duration `json:"request_timeout" default:"5s" parse:"duration"` // *
+	RequestTimeout_ time.Duration
+
+	// > @3@4@5@6
+	// >
+	// > A maximum quantity of events to pack into one batch.
+	BatchSize  cfg.Expression `json:"batch_size" default:"capacity/4" parse:"expression"` // *
+	BatchSize_ int
+
+	// > @3@4@5@6
+	// >
+	// > A minimum size of events in a batch to send.
+	// > If both batch_size and batch_size_bytes are set, they will work together.
+	BatchSizeBytes  cfg.Expression `json:"batch_size_bytes" default:"0" parse:"expression"` // *
+	BatchSizeBytes_ int
+
+	// > @3@4@5@6
+	// >
+	// > After this timeout the batch will be sent even if batch isn't completed.
+	BatchFlushTimeout  cfg.Duration `json:"batch_flush_timeout" default:"200ms" parse:"duration"` // *
+	BatchFlushTimeout_ time.Duration
+
+	// > @3@4@5@6
+	// >
+	// > Retries of insertion. If File.d cannot insert for this number of attempts,
+	// > File.d will fall with non-zero exit code or skip message (see fatal_on_failed_insert).
+	Retry int `json:"retry" default:"10"` // *
+
+	// > @3@4@5@6
+	// >
+	// > After an insert error, fall with a non-zero exit code or not
+	// > **Experimental feature**
+	FatalOnFailedInsert bool `json:"fatal_on_failed_insert" default:"false"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Retention milliseconds for retry to Loki.
+	Retention  cfg.Duration `json:"retention" default:"1s" parse:"duration"` // *
+	Retention_ time.Duration
+
+	// > @3@4@5@6
+	// >
+	// > Multiplier for exponential increase of retention between retries
+	RetentionExponentMultiplier int `json:"retention_exponentially_multiplier" default:"2"` // *
+}
+
+type entry struct {
+	time time.Time
+	// start and end of the line in data.lines
+	start, end int
+}
+
+type stream struct {
+	labels  string
+	entries []entry
+}
+
+type data struct {
+	streams    map[string]*stream
+	streamList []*stream
+	labelsBuf  []byte
+	lines      []byte
+	entriesBuf []byte
+	outBuf     []byte
+	snappyBuf  []byte
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterOutput(&pipeline.PluginStaticInfo{
+		Type:    outPluginType,
+		Factory: Factory,
+	})
+}
+
+func Factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.OutputPluginParams) {
+	p.controller = params.Controller
+	p.logger = params.Logger
+	p.config = config.(*Config)
+	p.registerMetrics(params.MetricCtl)
+
+	p.prepare()
+
+	batcherOpts := pipeline.BatcherOptions{
+		PipelineName:   params.PipelineName,
+		OutputType:     outPluginType,
+		MaintenanceFn:  p.maintenance,
+		Controller:     p.controller,
+		Workers:        p.config.WorkersCount_,
+		BatchSizeCount: p.config.BatchSize_,
+		BatchSizeBytes: p.config.BatchSizeBytes_,
+		FlushTimeout:   p.config.BatchFlushTimeout_,
+		MetricCtl:      params.MetricCtl,
+	}
+
+	backoffOpts := pipeline.BackoffOpts{
+		MinRetention: p.config.Retention_,
+		Multiplier:   float64(p.config.RetentionExponentMultiplier),
+		AttemptNum:   p.config.Retry,
+	}
+
+	p.batcher = pipeline.NewRetriableBatcher(
+		&batcherOpts,
+		p.out,
+		backoffOpts,
+		xhttp.OnError(p.logger, "can't send data to loki", p.config.FatalOnFailedInsert, p.config.Retry),
+	)
+
+	p.batcher.Start(p.ctx)
+}
+
+// prepare parses the labels and creates the client of the push API.
+func (p *Plugin) prepare() {
+	p.pushURL = strings.TrimRight(p.config.Address, "/") + pushPath
+
+	format, err := pipeline.ParseFormatName(p.config.TimeFormat)
+	if err != nil {
+		format = p.config.TimeFormat
+	}
+	p.config.TimeFormat = format
+
+	p.labels = make([]label, 0, len(p.config.Labels)+len(p.config.StaticLabels))
+	for name, field := range p.config.Labels {
+		p.labels = append(p.labels, label{name: name, field: cfg.ParseFieldSelector(field)})
+	}
+	for name, value := range p.config.StaticLabels {
+		if _, has := p.config.Labels[name]; has {
+			p.logger.Fatalf("label %q is both in labels and static_labels", name)
+		}
+		p.labels = append(p.labels, label{name: name, value: value})
+	}
+	for _, l := range p.labels {
+		if !labelNameRe.MatchString(l.name) {
+			p.logger.Fatalf("wrong label name %q, it must match %s", l.name, labelNameRe.String())
+		}
+	}
+	// the labels of the stream are sorted by the name to have the same stream for the same labels
+	sort.Slice(p.labels, func(i, j int) bool {
+		return p.labels[i].name < p.labels[j].name
+	})
+
+	if p.config.MaxLabelValues < 0 {
+		p.logger.Fatalf("max_label_values must be >= 0, got %d", p.config.MaxLabelValues)
+	}
+	if p.config.LabelValuesTTL_ < 0 {
+		p.logger.Fatalf("label_values_ttl must be >= 0, got %s", p.config.LabelValuesTTL_)
+	}
+	p.guard = newLabelGuard(p.config.MaxLabelValues, p.config.LabelValuesTTL_)
+
+	p.client, err = xhttp.NewClient(&xhttp.ClientConfig{
+		Timeout:     p.config.RequestTimeout_,
+		CACert:      p.config.CACert,
+		BearerToken: p.config.BearerToken,
+		Username:    p.config.Username,
+		Password:    p.config.Password,
+	})
+	if err != nil {
+		p.logger.Fatalf("can't create http client: %s", err.Error())
+	}
+
+	p.header = http.Header{}
+	p.header.Set("Content-Type", "application/x-protobuf")
+	if p.config.TenantID != "" {
+		p.header.Set("X-Scope-OrgID", p.config.TenantID)
+	}
+
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+}
+
+func (p *Plugin) registerMetrics(ctl *metric.Ctl) {
+	p.sendErrorMetric = ctl.RegisterCounterVec(
+		"output_loki_send_error",
+		"Total loki send errors",
+		"status_code",
+	)
+	p.droppedBatchMetric = ctl.RegisterCounterVec(
+		"output_loki_dropped_batches_total",
+		"Total batches rejected by loki without retries",
+		"reason",
+	)
+	p.labelOverflowMetric = ctl.RegisterCounterVec(
+		"output_loki_label_overflow_total",
+		"Total label values replaced because of max_label_values",
+		"label",
+	)
+}
+
+func (p *Plugin) Stop() {
+	p.batcher.Stop()
+	p.cancel()
+}
+
+func (p *Plugin) Out(event *pipeline.Event) {
+	p.batcher.Add(event)
+}
+
+func (p *Plugin) out(workerData *pipeline.WorkerData, batch *pipeline.Batch) error {
+	if *workerData == nil {
+		*workerData = &data{
+			streams: make(map[string]*stream),
+		}
+	}
+	data := (*workerData).(*data)
+
+	p.group(data, batch)
+	data.outBuf = appendPushRequest(data.outBuf[:0], data)
+	data.snappyBuf = s2.EncodeSnappy(data.snappyBuf[:cap(data.snappyBuf)], data.outBuf)
+
+	code, _, err := p.client.Send(p.ctx, http.MethodPost, p.pushURL, p.header, data.snappyBuf)
+	if err == nil {
+		return nil
+	}
+
+	p.sendErrorMetric.WithLabelValues(strconv.Itoa(code)).Inc()
+	p.logger.Errorf("can't send data to loki address=%s: %s", p.config.Address, err.Error())
+
+	// the entries which are out of order would be rejected again, so the batch is dropped,
+	// the other errors may be fixed on the loki side, e.g. the credentials or the limits
+	if code == http.StatusBadRequest && isOutOfOrder(err.Error()) {
+		p.droppedBatchMetric.WithLabelValues("out_of_order").Inc()
+		return nil
+	}
+	return err
+}
+
+// group splits the events of the batch into the streams.
+func (p *Plugin) group(data *data, batch *pipeline.Batch) {
+	for key := range data.streams {
+		delete(data.streams, key)
+	}
+	data.streamList = data.streamList[:0]
+	data.lines = data.lines[:0]
+
+	now := time.Now()
+	batch.ForEach(func(event *pipeline.Event) {
+		data.labelsBuf = p.appendLabels(data.labelsBuf[:0], event.Root, now)
+		s, has := data.streams[string(data.labelsBuf)]
+		if !has {
+			s = &stream{labels: string(data.labelsBuf)}
+			data.streams[s.labels] = s
+			data.streamList = append(data.streamList, s)
+		}
+
+		start := len(data.lines)
+		node := event.Root.Node
+		if len(p.config.MessageField_) > 0 {
+			if field := event.Root.Dig(p.config.MessageField_...); field != nil {
+				node = field
+			}
+		}
+		if node.IsString() {
+			data.lines = append(data.lines, node.AsString()...)
+		} else {
+			data.lines = node.Encode(data.lines)
+		}
+		s.entries = append(s.entries, entry{
+			time:  p.eventTime(event.Root, now),
+			start: start,
+			end:   len(data.lines),
+		})
+	})
+
+	// Loki rejects the entries which are older than the last entry of the stream
+	for _, s := range data.streamList {
+		sort.SliceStable(s.entries, func(i, j int) bool {
+			return s.entries[i].time.Before(s.entries[j].time)
+		})
+	}
+}
+
+// appendLabels appends the labels of the stream of the event in the `{name="value", ...}` form.
+func (p *Plugin) appendLabels(buf []byte, root *insaneJSON.Root, now time.Time) []byte {
+	buf = append(buf, '{')
+	empty := true
+	for i := range p.labels {
+		l := &p.labels[i]
+		value := l.value
+		if l.field != nil {
+			node := root.Dig(l.field...)
+			if node == nil {
+				continue
+			}
+			value = node.AsString()
+			if !p.guard.allow(l.name, value, now) {
+				p.labelOverflowMetric.WithLabelValues(l.name).Inc()
+				value = overflowLabelValue
+			}
+		}
+
+		if !empty {
+			buf = append(buf, ", "...)
+		}
+		empty = false
+		buf = append(buf, l.name...)
+		buf = append(buf, '=')
+		buf = strconv.AppendQuote(buf, value)
+	}
+	if empty {
+		return append(buf[:0], noLabels...)
+	}
+	return append(buf, '}')
+}
+
+func (p *Plugin) eventTime(root *insaneJSON.Root, now time.Time) time.Time {
+	node := root.Dig(p.config.TimeField_...)
+	if node == nil {
+		return now
+	}
+	t, err := pipeline.ParseTime(p.config.TimeFormat, node.AsString())
+	if err != nil {
+		return now
+	}
+	return t
+}
+
+// appendPushRequest encodes the streams as the logproto.PushRequest message.
+func appendPushRequest(buf []byte, data *data) []byte {
+	for _, s := range data.streamList {
+		data.entriesBuf = data.entriesBuf[:0]
+		for _, e := range s.entries {
+			data.entriesBuf = appendEntry(data.entriesBuf, e.time, data.lines[e.start:e.end])
+		}
+
+		// PushRequest.streams
+		buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+		buf = protowire.AppendVarint(buf, uint64(protowire.SizeTag(1)+protowire.SizeBytes(len(s.labels))+len(data.entriesBuf)))
+		// StreamAdapter.labels
+		buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+		buf = protowire.AppendString(buf, s.labels)
+		// StreamAdapter.entries
+		buf = append(buf, data.entriesBuf...)
+	}
+	return buf
+}
+
+// appendEntry encodes the entry as the repeated entries field of the logproto.StreamAdapter message.
+func appendEntry(buf []byte, t time.Time, line []byte) []byte {
+	seconds, nanos := uint64(t.Unix()), uint64(t.Nanosecond())
+
+	tsSize := 0
+	if seconds != 0 {
+		tsSize += protowire.SizeTag(1) + protowire.SizeVarint(seconds)
+	}
+	if nanos != 0 {
+		tsSize += protowire.SizeTag(2) + protowire.SizeVarint(nanos)
+	}
+	entrySize := protowire.SizeTag(1) + protowire.SizeBytes(tsSize) + protowire.SizeTag(2) + protowire.SizeBytes(len(line))
+
+	buf = protowire.AppendTag(buf, 2, protowire.BytesType)
+	buf = protowire.AppendVarint(buf, uint64(entrySize))
+
+	// EntryAdapter.timestamp
+	buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+	buf = protowire.AppendVarint(buf, uint64(tsSize))
+	if seconds != 0 {
+		buf = protowire.AppendTag(buf, 1, protowire.VarintType)
+		buf = protowire.AppendVarint(buf, seconds)
+	}
+	if nanos != 0 {
+		buf = protowire.AppendTag(buf, 2, protowire.VarintType)
+		buf = protowire.AppendVarint(buf, nanos)
+	}
+
+	// EntryAdapter.line
+	buf = protowire.AppendTag(buf, 2, protowire.BytesType)
+	return protowire.AppendBytes(buf, line)
+}
+
+func isOutOfOrder(message string) bool {
+	return strings.Contains(message, "out of order") || strings.Contains(message, "too far behind")
+}
+
+func (p *Plugin) maintenance(_ *pipeline.WorkerData) {}
+
+// labelGuard limits the number of the values of every label.
+type labelGuard struct {
+	maxValues int
+	ttl       time.Duration
+
+	mu     sync.Mutex
+	labels map[string]*labelValues
+}
+
+type labelValues struct {
+	// lastSeen is the time when the value is seen last time
+	lastSeen map[string]time.Time
+	swept    time.Time
+}
+
+func newLabelGuard(maxValues int, ttl time.Duration) *labelGuard {
+	return &labelGuard{
+		maxValues: maxValues,
+		ttl:       ttl,
+		labels:    make(map[string]*labelValues),
+	}
+}
+
+// allow returns false if the value is new and the label already has the maximum number of the values.
+func (g *labelGuard) allow(name, value string, now time.Time) bool {
+	if g.maxValues == 0 {
+		return true
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	l, has := g.labels[name]
+	if !has {
+		l = &labelValues{lastSeen: make(map[string]time.Time)}
+		g.labels[name] = l
+	}
+	if _, has := l.lastSeen[value]; has {
+		l.lastSeen[value] = now
+		return true
+	}
+	if len(l.lastSeen) >= g.maxValues && !g.expire(l, now) {
+		return false
+	}
+	// the value may reference the event
+	l.lastSeen[strings.Clone(value)] = now
+	return true
+}
+
+// expire removes the values of the label which aren't seen for ttl
+// and reports whether the label can take a new value.
+func (g *labelGuard) expire(l *labelValues, now time.Time) bool {
+	if g.ttl == 0 || now.Sub(l.swept) < guardSweepInterval {
+		return false
+	}
+	l.swept = now
+
+	for value, seen := range l.lastSeen {
+		if now.Sub(seen) >= g.ttl {
+			delete(l.lastSeen, value)
+		}
+	}
+	return len(l.lastSeen) < g.maxValues
+}
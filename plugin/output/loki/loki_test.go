@@ -0,0 +1,272 @@
+package loki
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/ozontech/file.d/metric"
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/test"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	insaneJSON "github.com/vitkovskii/insane-json"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type testEntry struct {
+	time time.Time
+	line string
+}
+
+type testStream struct {
+	labels  string
+	entries []testEntry
+}
+
+// consumeFields calls fn for every field of the protobuf message.
+func consumeFields(t *testing.T, b []byte, fn func(num protowire.Number, value []byte, varint uint64)) {
+	t.Helper()
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			require.GreaterOrEqual(t, n, 0)
+			b = b[n:]
+			fn(num, nil, v)
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			require.GreaterOrEqual(t, n, 0)
+			b = b[n:]
+			fn(num, v, 0)
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+	}
+}
+
+func decodePushRequest(t *testing.T, body []byte) []testStream {
+	t.Helper()
+
+	b, err := s2.Decode(nil, body)
+	require.NoError(t, err)
+
+	var streams []testStream
+	consumeFields(t, b, func(_ protowire.Number, streamMsg []byte, _ uint64) {
+		s := testStream{}
+		consumeFields(t, streamMsg, func(num protowire.Number, value []byte, _ uint64) {
+			if num == 1 {
+				s.labels = string(value)
+				return
+			}
+
+			e := testEntry{}
+			var seconds, nanos uint64
+			consumeFields(t, value, func(num protowire.Number, value []byte, _ uint64) {
+				if num == 2 {
+					e.line = string(value)
+					return
+				}
+				consumeFields(t, value, func(num protowire.Number, _ []byte, v uint64) {
+					if num == 1 {
+						seconds = v
+					} else {
+						nanos = v
+					}
+				})
+			})
+			e.time = time.Unix(int64(seconds), int64(nanos))
+			s.entries = append(s.entries, e)
+		})
+		streams = append(streams, s)
+	})
+	return streams
+}
+
+func newTestPlugin(t *testing.T, config *Config) *Plugin {
+	t.Helper()
+
+	test.NewConfig(config, map[string]int{"gomaxprocs": 1, "capacity": 64})
+	p := &Plugin{
+		config: config,
+		logger: zap.NewExample().Sugar(),
+	}
+	p.registerMetrics(metric.NewCtl("test", prometheus.NewRegistry()))
+	p.prepare()
+	return p
+}
+
+func newTestBatch(t *testing.T, events ...string) *pipeline.Batch {
+	t.Helper()
+
+	batch := make([]*pipeline.Event, 0, len(events))
+	for _, e := range events {
+		root, err := insaneJSON.DecodeString(e)
+		require.NoError(t, err)
+		t.Cleanup(func() { insaneJSON.Release(root) })
+		batch = append(batch, &pipeline.Event{Root: root})
+	}
+	return pipeline.NewPreparedBatch(batch)
+}
+
+func TestLoki(t *testing.T) {
+	var (
+		body    []byte
+		headers http.Header
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, pushPath, r.URL.Path)
+		headers = r.Header
+
+		var err error
+		body, err = io.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	p := newTestPlugin(t, &Config{
+		Address:      server.URL + "/",
+		TenantID:     "team-a",
+		BearerToken:  "secret",
+		Labels:       map[string]string{"service": "k8s.app", "level": "level"},
+		StaticLabels: map[string]string{"cluster": "prod-1"},
+		MessageField: "message",
+	})
+
+	batch := newTestBatch(t,
+		`{"k8s":{"app":"api"},"level":"error","message":"second","time":"2023-10-19T10:00:02.5Z"}`,
+		`{"k8s":{"app":"api"},"level":"info","message":"other stream","time":"2023-10-19T10:00:00Z"}`,
+		`{"k8s":{"app":"api"},"level":"error","message":"first","time":"2023-10-19T10:00:01Z"}`,
+		`{"k8s":{"app":"api"},"level":"error","time":"2023-10-19T10:00:03Z"}`,
+	)
+
+	data := pipeline.WorkerData(nil)
+	require.NoError(t, p.out(&data, batch))
+
+	require.Equal(t, "application/x-protobuf", headers.Get("Content-Type"))
+	require.Equal(t, "team-a", headers.Get("X-Scope-OrgID"))
+	require.Equal(t, "Bearer secret", headers.Get("Authorization"))
+
+	require.Equal(t, []testStream{
+		{
+			labels: `{cluster="prod-1", level="error", service="api"}`,
+			entries: []testEntry{
+				{time: time.Date(2023, 10, 19, 10, 0, 1, 0, time.UTC), line: "first"},
+				{time: time.Date(2023, 10, 19, 10, 0, 2, 5e8, time.UTC), line: "second"},
+				{time: time.Date(2023, 10, 19, 10, 0, 3, 0, time.UTC), line: `{"k8s":{"app":"api"},"level":"error","time":"2023-10-19T10:00:03Z"}`},
+			},
+		},
+		{
+			labels: `{cluster="prod-1", level="info", service="api"}`,
+			entries: []testEntry{
+				{time: time.Date(2023, 10, 19, 10, 0, 0, 0, time.UTC), line: "other stream"},
+			},
+		},
+	}, normalizeTime(decodePushRequest(t, body)))
+}
+
+func normalizeTime(streams []testStream) []testStream {
+	for _, s := range streams {
+		for i := range s.entries {
+			s.entries[i].time = s.entries[i].time.UTC()
+		}
+	}
+	return streams
+}
+
+func TestLokiLabels(t *testing.T) {
+	p := newTestPlugin(t, &Config{
+		Address:        "http://loki:3100",
+		Labels:         map[string]string{"app": "app"},
+		MaxLabelValues: 2,
+	})
+
+	labels := func(e string) string {
+		root, err := insaneJSON.DecodeString(e)
+		require.NoError(t, err)
+		defer insaneJSON.Release(root)
+		return string(p.appendLabels(nil, root, time.Now()))
+	}
+
+	require.Equal(t, `{app="a"}`, labels(`{"app":"a"}`))
+	require.Equal(t, `{app="b\"\n"}`, labels(`{"app":"b\"\n"}`))
+	require.Equal(t, `{app="__overflow__"}`, labels(`{"app":"c"}`))
+	require.Equal(t, `{app="a"}`, labels(`{"app":"a"}`))
+	require.Equal(t, noLabels, labels(`{}`))
+}
+
+func TestLabelGuardExpire(t *testing.T) {
+	g := newLabelGuard(2, time.Minute)
+	now := time.Now()
+
+	require.True(t, g.allow("app", "a", now))
+	require.True(t, g.allow("app", "b", now))
+	require.False(t, g.allow("app", "c", now))
+
+	// "a" is seen again, so only "b" expires
+	require.True(t, g.allow("app", "a", now.Add(30*time.Second)))
+	require.True(t, g.allow("app", "c", now.Add(time.Minute)))
+	require.False(t, g.allow("app", "d", now.Add(time.Minute+guardSweepInterval)))
+
+	// the other labels are limited separately
+	require.True(t, g.allow("env", "prod", now))
+
+	// zero ttl never forgets the values
+	g = newLabelGuard(1, 0)
+	require.True(t, g.allow("app", "a", now))
+	require.False(t, g.allow("app", "b", now.Add(time.Hour)))
+}
+
+func TestLokiRetries(t *testing.T) {
+	cases := []struct {
+		name      string
+		status    int
+		body      string
+		wantRetry bool
+		dropped   string
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "too_many_requests", status: http.StatusTooManyRequests, body: "ingestion rate limit exceeded", wantRetry: true},
+		{name: "server_error", status: http.StatusServiceUnavailable, wantRetry: true},
+		{name: "out_of_order", status: http.StatusBadRequest, body: "entry out of order for stream", dropped: "out_of_order"},
+		{name: "too_far_behind", status: http.StatusBadRequest, body: "entry too far behind", dropped: "out_of_order"},
+		{name: "bad_request", status: http.StatusBadRequest, body: "invalid labels", wantRetry: true},
+		{name: "unauthorized", status: http.StatusUnauthorized, wantRetry: true},
+		{name: "forbidden", status: http.StatusForbidden, wantRetry: true},
+		{name: "not_found", status: http.StatusNotFound, wantRetry: true},
+		{name: "too_large", status: http.StatusRequestEntityTooLarge, wantRetry: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			p := newTestPlugin(t, &Config{Address: server.URL, Username: "user", Password: "pass"})
+
+			data := pipeline.WorkerData(nil)
+			err := p.out(&data, newTestBatch(t, `{"message":"test"}`))
+			if tc.wantRetry {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			if tc.dropped != "" {
+				require.Equal(t, 1.0, testutil.ToFloat64(p.droppedBatchMetric.WithLabelValues(tc.dropped)))
+			}
+		})
+	}
+}
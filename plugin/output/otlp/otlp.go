@@ -0,0 +1,679 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/ozontech/file.d/cfg"
+	"github.com/ozontech/file.d/fd"
+	"github.com/ozontech/file.d/metric"
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/xhttp"
+	"github.com/ozontech/file.d/xtls"
+	"github.com/prometheus/client_golang/prometheus"
+	insaneJSON "github.com/vitkovskii/insane-json"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcgzip "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+/*{ introduction
+It sends events as [OpenTelemetry](https://opentelemetry.io/docs/specs/otlp/) logs
+to OpenTelemetry Collector or to any other backend which accepts OTLP over gRPC or HTTP.
+
+The fields of the event are mapped to the log record:
+* `resource_attributes` and `static_resource_attributes` – to the attributes of the resource, the events with the same resource are sent in the same `ResourceLogs`,
+* `body_field` – to the body,
+* `severity_field` – to the severity text and number, the number is detected by the severity text, e.g. `warn` is `WARN`,
+* `time_field` – to the time of the record,
+* `trace_id_field` and `span_id_field` – to the trace context, the values are hex encoded ids,
+* the remaining fields – to the attributes of the record, the objects and the arrays are kept as is.
+
+The batch is retried with the exponential backoff on the network errors and on the errors which are retryable by the OTLP specification:
+`UNAVAILABLE`, `RESOURCE_EXHAUSTED` and alike gRPC codes, `429`, `502`, `503` and `504` HTTP codes.
+The batches with other errors are dropped since they would be rejected again.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    output:
+      type: otlp
+      protocol: grpc
+      endpoint: otel-collector:4317
+      static_resource_attributes:
+        service.name: payments
+      resource_attributes:
+        k8s.namespace.name: k8s_namespace
+        k8s.pod.name: k8s_pod
+      body_field: message
+      severity_field: level
+      trace_id_field: trace_id
+      span_id_field: span_id
+    ...
+```
+}*/
+
+const (
+	outPluginType = "otlp"
+
+	protocolGRPC = "grpc"
+	protocolHTTP = "http"
+
+	compressionGzip = "gzip"
+
+	scopeName = "file.d"
+)
+
+type Plugin struct {
+	config     *Config
+	logger     *zap.SugaredLogger
+	batcher    *pipeline.RetriableBatcher
+	controller pipeline.OutputPluginController
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	grpcConn   *grpc.ClientConn
+	grpcClient collogspb.LogsServiceClient
+	httpClient *xhttp.Client
+	httpHeader http.Header
+
+	resourceAttrs []attribute
+	staticAttrs   []*commonpb.KeyValue
+
+	// plugin metrics
+	sendErrorMetric       *prometheus.CounterVec
+	rejectedRecordsMetric prometheus.Counter
+}
+
+type attribute struct {
+	key   string
+	field []string
+}
+
+// ! config-params
+// ^ config-params
+type Config struct {
+	// > @3@4@5@6
+	// >
+	// > The address of the receiver.
+	// > Format for gRPC: `127.0.0.1:4317`, for HTTP: `http://127.0.0.1:4318/v1/logs`.
+	Endpoint string `json:"endpoint" required:"true"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The protocol of the receiver.
+	Protocol string `json:"protocol" default:"grpc" options:"grpc|http"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The headers of the requests, the metadata for gRPC, e.g. the authentication headers of the vendor backend.
+	Headers map[string]string `json:"headers"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Path or content of a PEM-encoded CA file. gRPC uses TLS only if it's set.
+	CACert string `json:"ca_cert"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The compression of the requests.
+	Compression string `json:"compression" default:"none" options:"none|gzip"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The attributes of the resource taken from the event: the attribute key to the event field.
+	// > The attribute is omitted if the event has no field.
+	ResourceAttributes map[string]string `json:"resource_attributes"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The attributes of the resource which are the same for all the events, e.g. `service.name`.
+	StaticResourceAttributes map[string]string `json:"static_resource_attributes"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The event field with the body of the record.
+	BodyField  cfg.FieldSelector `json:"body_field" default:"message" parse:"selector"` // *
+	BodyField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The event field with the severity of the record.
+	SeverityField  cfg.FieldSelector `json:"severity_field" default:"level" parse:"selector"` // *
+	SeverityField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The event field with the time of the record.
+	TimeField  cfg.FieldSelector `json:"time_field" default:"time" parse:"selector"` // *
+	TimeField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The format of `time_field`. It can be the name of the predefined format, e.g. `rfc3339nano`, or the Go time layout.
+	TimeFormat string `json:"time_format" default:"rfc3339nano"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The event field with the hex encoded trace id of the record.
+	TraceIDField  cfg.FieldSelector `json:"trace_id_field" default:"trace_id" parse:"selector"` // *
+	TraceIDField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The event field with the hex encoded span id of the record.
+	SpanIDField  cfg.FieldSelector `json:"span_id_field" default:"span_id" parse:"selector"` // *
+	SpanIDField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > How many workers will be instantiated to send batches.
+	WorkersCount  cfg.Expression `json:"workers_count" default:"gomaxprocs*4" parse:"expression"` // *
+	WorkersCount_ int
+
+	// > @3@4@5@6
+	// >
+	// > Client timeout when sends requests.
+	RequestTimeout  cfg.Duration `json:"request_timeout" default:"5s" parse:"duration"` // *
+	RequestTimeout_ time.Duration
+
+	// > @3@4@5@6
+	// >
+	// > A maximum quantity of events to pack into one batch.
+	BatchSize  cfg.Expression `json:"batch_size" default:"capacity/4" parse:"expression"` // *
+	BatchSize_ int
+
+	// > @3@4@5@6
+	// >
+	// > A minimum size of events in a batch to send.
+	// > If both batch_size and batch_size_bytes are set, they will work together.
+	BatchSizeBytes  cfg.Expression `json:"batch_size_bytes" default:"0" parse:"expression"` // *
+	BatchSizeBytes_ int
+
+	// > @3@4@5@6
+	// >
+	// > After this timeout the batch will be sent even if batch isn't completed.
+	BatchFlushTimeout  cfg.Duration `json:"batch_flush_timeout" default:"200ms" parse:"duration"` // *
+	BatchFlushTimeout_ time.Duration
+
+	// > @3@4@5@6
+	// >
+	// > Retries of sending. If File.d cannot send for this number of attempts,
+	// > File.d will fall with non-zero exit code or skip message (see fatal_on_failed_insert).
+	Retry int `json:"retry" default:"10"` // *
+
+	// > @3@4@5@6
+	// >
+	// > After a send error, fall with a non-zero exit code or not
+	// > **Experimental feature**
+	FatalOnFailedInsert bool `json:"fatal_on_failed_insert" default:"false"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Retention milliseconds for retry.
+	Retention  cfg.Duration `json:"retention" default:"1s" parse:"duration"` // *
+	Retention_ time.Duration
+
+	// > @3@4@5@6
+	// >
+	// > Multiplier for exponential increase of retention between retries
+	RetentionExponentMultiplier int `json:"retention_exponentially_multiplier" default:"2"` // *
+}
+
+type data struct {
+	resources map[string]*logspb.ResourceLogs
+	keyBuf    []byte
+	mapped    []*insaneJSON.Node
+	gzipBuf   *bytes.Buffer
+	gzipW     *gzip.Writer
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterOutput(&pipeline.PluginStaticInfo{
+		Type:    outPluginType,
+		Factory: Factory,
+	})
+}
+
+func Factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.OutputPluginParams) {
+	p.controller = params.Controller
+	p.logger = params.Logger
+	p.config = config.(*Config)
+	p.registerMetrics(params.MetricCtl)
+
+	p.prepare()
+
+	batcherOpts := pipeline.BatcherOptions{
+		PipelineName:   params.PipelineName,
+		OutputType:     outPluginType,
+		MaintenanceFn:  p.maintenance,
+		Controller:     p.controller,
+		Workers:        p.config.WorkersCount_,
+		BatchSizeCount: p.config.BatchSize_,
+		BatchSizeBytes: p.config.BatchSizeBytes_,
+		FlushTimeout:   p.config.BatchFlushTimeout_,
+		MetricCtl:      params.MetricCtl,
+	}
+
+	backoffOpts := pipeline.BackoffOpts{
+		MinRetention: p.config.Retention_,
+		Multiplier:   float64(p.config.RetentionExponentMultiplier),
+		AttemptNum:   p.config.Retry,
+	}
+
+	p.batcher = pipeline.NewRetriableBatcher(
+		&batcherOpts,
+		p.out,
+		backoffOpts,
+		xhttp.OnError(p.logger, "can't send data to otlp endpoint", p.config.FatalOnFailedInsert, p.config.Retry),
+	)
+
+	p.batcher.Start(p.ctx)
+}
+
+// prepare maps the resource attributes and connects to the receiver with the configured protocol.
+func (p *Plugin) prepare() {
+	format, err := pipeline.ParseFormatName(p.config.TimeFormat)
+	if err != nil {
+		format = p.config.TimeFormat
+	}
+	p.config.TimeFormat = format
+
+	p.resourceAttrs = make([]attribute, 0, len(p.config.ResourceAttributes))
+	for key, field := range p.config.ResourceAttributes {
+		p.resourceAttrs = append(p.resourceAttrs, attribute{key: key, field: cfg.ParseFieldSelector(field)})
+	}
+	// the attributes are sorted by the key to have the same resource for the same attributes
+	sort.Slice(p.resourceAttrs, func(i, j int) bool {
+		return p.resourceAttrs[i].key < p.resourceAttrs[j].key
+	})
+
+	p.staticAttrs = make([]*commonpb.KeyValue, 0, len(p.config.StaticResourceAttributes))
+	for key, value := range p.config.StaticResourceAttributes {
+		if _, has := p.config.ResourceAttributes[key]; has {
+			p.logger.Fatalf("attribute %q is both in resource_attributes and static_resource_attributes", key)
+		}
+		p.staticAttrs = append(p.staticAttrs, &commonpb.KeyValue{Key: key, Value: stringValue(value)})
+	}
+	sort.Slice(p.staticAttrs, func(i, j int) bool {
+		return p.staticAttrs[i].Key < p.staticAttrs[j].Key
+	})
+
+	switch p.config.Protocol {
+	case protocolGRPC:
+		creds := insecure.NewCredentials()
+		if p.config.CACert != "" {
+			b := xtls.NewConfigBuilder()
+			err := b.AppendCARoot(p.config.CACert)
+			if err != nil {
+				p.logger.Fatalf("can't append CA root: %s", err.Error())
+			}
+			creds = credentials.NewTLS(b.Build())
+		}
+		conn, err := grpc.Dial(p.config.Endpoint, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			p.logger.Fatalf("can't create grpc connection to %s: %s", p.config.Endpoint, err.Error())
+		}
+		p.grpcConn = conn
+		p.grpcClient = collogspb.NewLogsServiceClient(conn)
+	case protocolHTTP:
+		client, err := xhttp.NewClient(&xhttp.ClientConfig{
+			Timeout: p.config.RequestTimeout_,
+			CACert:  p.config.CACert,
+		})
+		if err != nil {
+			p.logger.Fatalf("can't create http client: %s", err.Error())
+		}
+		p.httpClient = client
+
+		p.httpHeader = http.Header{}
+		p.httpHeader.Set("Content-Type", "application/x-protobuf")
+		if p.config.Compression == compressionGzip {
+			p.httpHeader.Set("Content-Encoding", compressionGzip)
+		}
+		for name, value := range p.config.Headers {
+			p.httpHeader.Set(name, value)
+		}
+	}
+
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+}
+
+func (p *Plugin) registerMetrics(ctl *metric.Ctl) {
+	p.sendErrorMetric = ctl.RegisterCounterVec(
+		"output_otlp_send_error",
+		"Total otlp send errors",
+		"code",
+	)
+	p.rejectedRecordsMetric = ctl.RegisterCounter(
+		"output_otlp_rejected_records_total",
+		"Total log records rejected by the receiver with the partial success response",
+	)
+}
+
+func (p *Plugin) Stop() {
+	p.batcher.Stop()
+	p.cancel()
+	if p.grpcConn != nil {
+		_ = p.grpcConn.Close()
+	}
+}
+
+func (p *Plugin) Out(event *pipeline.Event) {
+	p.batcher.Add(event)
+}
+
+func (p *Plugin) out(workerData *pipeline.WorkerData, batch *pipeline.Batch) error {
+	if *workerData == nil {
+		*workerData = &data{
+			resources: make(map[string]*logspb.ResourceLogs),
+			gzipBuf:   &bytes.Buffer{},
+		}
+	}
+	data := (*workerData).(*data)
+
+	req := p.buildRequest(data, batch)
+
+	var (
+		code      string
+		retryable bool
+		resp      *collogspb.ExportLogsServiceResponse
+		err       error
+	)
+	if p.config.Protocol == protocolGRPC {
+		resp, code, retryable, err = p.sendGRPC(req)
+	} else {
+		resp, code, retryable, err = p.sendHTTP(data, req)
+	}
+
+	if err != nil {
+		p.sendErrorMetric.WithLabelValues(code).Inc()
+		p.logger.Errorf("can't send data to otlp endpoint=%s: %s", p.config.Endpoint, err.Error())
+		if retryable {
+			return err
+		}
+		return nil
+	}
+
+	if partial := resp.GetPartialSuccess(); partial != nil && partial.RejectedLogRecords > 0 {
+		p.rejectedRecordsMetric.Add(float64(partial.RejectedLogRecords))
+		p.logger.Warnf("otlp endpoint=%s rejected %d log records: %s", p.config.Endpoint, partial.RejectedLogRecords, partial.ErrorMessage)
+	}
+	return nil
+}
+
+// buildRequest groups the events of the batch by the resource.
+// The protobuf messages reference the events, so they must be sent before the batch is released.
+func (p *Plugin) buildRequest(data *data, batch *pipeline.Batch) *collogspb.ExportLogsServiceRequest {
+	for key := range data.resources {
+		delete(data.resources, key)
+	}
+
+	req := &collogspb.ExportLogsServiceRequest{}
+	now := uint64(time.Now().UnixNano())
+	batch.ForEach(func(event *pipeline.Event) {
+		data.mapped = data.mapped[:0]
+
+		attrs, key := p.resourceAttributes(data, event.Root)
+		resourceLogs, has := data.resources[key]
+		if !has {
+			resourceLogs = &logspb.ResourceLogs{
+				Resource:  &resourcepb.Resource{Attributes: attrs},
+				ScopeLogs: []*logspb.ScopeLogs{{Scope: &commonpb.InstrumentationScope{Name: scopeName}}},
+			}
+			data.resources[key] = resourceLogs
+			req.ResourceLogs = append(req.ResourceLogs, resourceLogs)
+		}
+
+		scopeLogs := resourceLogs.ScopeLogs[0]
+		scopeLogs.LogRecords = append(scopeLogs.LogRecords, p.logRecord(data, event.Root, now))
+	})
+	return req
+}
+
+func (p *Plugin) resourceAttributes(data *data, root *insaneJSON.Root) ([]*commonpb.KeyValue, string) {
+	data.keyBuf = data.keyBuf[:0]
+	var attrs []*commonpb.KeyValue
+	for _, attr := range p.resourceAttrs {
+		node := root.Dig(attr.field...)
+		if node == nil {
+			continue
+		}
+		data.mapped = append(data.mapped, node)
+
+		value := anyValue(node, nil)
+		attrs = append(attrs, &commonpb.KeyValue{Key: attr.key, Value: value})
+		data.keyBuf = append(data.keyBuf, attr.key...)
+		data.keyBuf = append(data.keyBuf, '=')
+		data.keyBuf = node.Encode(data.keyBuf)
+		data.keyBuf = append(data.keyBuf, ',')
+	}
+	return append(attrs, p.staticAttrs...), string(data.keyBuf)
+}
+
+func (p *Plugin) logRecord(data *data, root *insaneJSON.Root, now uint64) *logspb.LogRecord {
+	record := &logspb.LogRecord{ObservedTimeUnixNano: now}
+
+	if node := root.Dig(p.config.BodyField_...); node != nil {
+		data.mapped = append(data.mapped, node)
+		record.Body = anyValue(node, nil)
+	}
+	if node := root.Dig(p.config.SeverityField_...); node != nil {
+		data.mapped = append(data.mapped, node)
+		record.SeverityText = node.AsString()
+		record.SeverityNumber = severityNumber(record.SeverityText)
+	}
+	if node := root.Dig(p.config.TimeField_...); node != nil {
+		if t, err := pipeline.ParseTime(p.config.TimeFormat, node.AsString()); err == nil {
+			data.mapped = append(data.mapped, node)
+			record.TimeUnixNano = uint64(t.UnixNano())
+		}
+	}
+	if node := root.Dig(p.config.TraceIDField_...); node != nil {
+		if id, err := hex.DecodeString(node.AsString()); err == nil && len(id) == 16 {
+			data.mapped = append(data.mapped, node)
+			record.TraceId = id
+		}
+	}
+	if node := root.Dig(p.config.SpanIDField_...); node != nil {
+		if id, err := hex.DecodeString(node.AsString()); err == nil && len(id) == 8 {
+			data.mapped = append(data.mapped, node)
+			record.SpanId = id
+		}
+	}
+
+	if root.IsObject() {
+		record.Attributes = keyValues(root.Node, data.mapped)
+	}
+	return record
+}
+
+// anyValue converts the node to the OTLP value skipping the mapped nodes.
+func anyValue(node *insaneJSON.Node, mapped []*insaneJSON.Node) *commonpb.AnyValue {
+	switch {
+	case node.IsObject():
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{
+			KvlistValue: &commonpb.KeyValueList{Values: keyValues(node, mapped)},
+		}}
+	case node.IsArray():
+		elements := node.AsArray()
+		values := make([]*commonpb.AnyValue, 0, len(elements))
+		for _, element := range elements {
+			values = append(values, anyValue(element, mapped))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{
+			ArrayValue: &commonpb.ArrayValue{Values: values},
+		}}
+	case node.IsNumber():
+		s := node.AsString()
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: i}}
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: node.AsFloat()}}
+	case node.IsTrue(), node.IsFalse():
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: node.AsBool()}}
+	case node.IsNull():
+		return &commonpb.AnyValue{}
+	default:
+		return stringValue(node.AsString())
+	}
+}
+
+func keyValues(node *insaneJSON.Node, mapped []*insaneJSON.Node) []*commonpb.KeyValue {
+	fields := node.AsFields()
+	kvs := make([]*commonpb.KeyValue, 0, len(fields))
+	for _, field := range fields {
+		value := field.AsFieldValue()
+		if isMapped(value, mapped) {
+			continue
+		}
+		kvs = append(kvs, &commonpb.KeyValue{Key: field.AsString(), Value: anyValue(value, mapped)})
+	}
+	return kvs
+}
+
+func isMapped(node *insaneJSON.Node, mapped []*insaneJSON.Node) bool {
+	for _, m := range mapped {
+		if m == node {
+			return true
+		}
+	}
+	return false
+}
+
+func stringValue(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
+
+func severityNumber(severity string) logspb.SeverityNumber {
+	if strings.EqualFold(severity, "trace") {
+		return logspb.SeverityNumber_SEVERITY_NUMBER_TRACE
+	}
+
+	switch pipeline.ParseLevelAsNumber(severity) {
+	case pipeline.LevelEmergency:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL4
+	case pipeline.LevelAlert:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL3
+	case pipeline.LevelCritical:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL
+	case pipeline.LevelError:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR
+	case pipeline.LevelWarning:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_WARN
+	case pipeline.LevelNotice:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_INFO2
+	case pipeline.LevelInformational:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_INFO
+	case pipeline.LevelDebug:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG
+	default:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED
+	}
+}
+
+func (p *Plugin) maintenance(_ *pipeline.WorkerData) {}
+
+func (p *Plugin) sendGRPC(req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, string, bool, error) {
+	ctx, cancel := context.WithTimeout(p.ctx, p.config.RequestTimeout_)
+	defer cancel()
+
+	if len(p.config.Headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(p.config.Headers))
+	}
+	var opts []grpc.CallOption
+	if p.config.Compression == compressionGzip {
+		opts = append(opts, grpc.UseCompressor(grpcgzip.Name))
+	}
+
+	resp, err := p.grpcClient.Export(ctx, req, opts...)
+	if err != nil {
+		code := status.Code(err)
+		return nil, code.String(), isRetryableCode(code), fmt.Errorf("can't export logs: %w", err)
+	}
+	return resp, "", false, nil
+}
+
+// isRetryableCode reports whether the gRPC code is retryable by the OTLP specification.
+func isRetryableCode(code codes.Code) bool {
+	switch code {
+	case codes.Canceled, codes.DeadlineExceeded, codes.Aborted, codes.OutOfRange,
+		codes.Unavailable, codes.DataLoss, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *Plugin) sendHTTP(data *data, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, string, bool, error) {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("can't marshal request: %w", err)
+	}
+	if p.config.Compression == compressionGzip {
+		body = p.compress(data, body)
+	}
+
+	code, b, err := p.httpClient.Send(p.ctx, http.MethodPost, p.config.Endpoint, p.httpHeader, body)
+	if err != nil {
+		// the network errors and the responses which can't be read are retried as well
+		retryable := code < http.StatusMultipleChoices || isRetryableStatus(code)
+		return nil, strconv.Itoa(code), retryable, err
+	}
+
+	resp := &collogspb.ExportLogsServiceResponse{}
+	if err := proto.Unmarshal(b, resp); err != nil {
+		// the logs are accepted anyway
+		p.logger.Warnf("can't unmarshal response from otlp endpoint=%s: %s", p.config.Endpoint, err.Error())
+	}
+	return resp, "", false, nil
+}
+
+// isRetryableStatus reports whether the HTTP status is retryable by the OTLP specification.
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *Plugin) compress(data *data, body []byte) []byte {
+	data.gzipBuf.Reset()
+	if data.gzipW == nil {
+		data.gzipW = gzip.NewWriter(data.gzipBuf)
+	} else {
+		data.gzipW.Reset(data.gzipBuf)
+	}
+
+	// writing to the bytes.Buffer never fails
+	_, _ = data.gzipW.Write(body)
+	_ = data.gzipW.Close()
+	return data.gzipBuf.Bytes()
+}
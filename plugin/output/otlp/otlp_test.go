@@ -0,0 +1,236 @@
+package otlp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ozontech/file.d/metric"
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/test"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	insaneJSON "github.com/vitkovskii/insane-json"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func newTestPlugin(t *testing.T, config *Config) *Plugin {
+	t.Helper()
+
+	test.NewConfig(config, map[string]int{"gomaxprocs": 1, "capacity": 64})
+	p := &Plugin{
+		config: config,
+		logger: zap.NewExample().Sugar(),
+	}
+	p.registerMetrics(metric.NewCtl("test", prometheus.NewRegistry()))
+	p.prepare()
+	t.Cleanup(func() {
+		if p.grpcConn != nil {
+			_ = p.grpcConn.Close()
+		}
+	})
+	return p
+}
+
+func newTestBatch(t *testing.T, events ...string) *pipeline.Batch {
+	t.Helper()
+
+	batch := make([]*pipeline.Event, 0, len(events))
+	for _, e := range events {
+		root, err := insaneJSON.DecodeString(e)
+		require.NoError(t, err)
+		t.Cleanup(func() { insaneJSON.Release(root) })
+		batch = append(batch, &pipeline.Event{Root: root})
+	}
+	return pipeline.NewPreparedBatch(batch)
+}
+
+type testServer struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	err  error
+	req  *collogspb.ExportLogsServiceRequest
+	md   metadata.MD
+	resp *collogspb.ExportLogsServiceResponse
+}
+
+func (s *testServer) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	s.req = req
+	s.md, _ = metadata.FromIncomingContext(ctx)
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.resp != nil {
+		return s.resp, nil
+	}
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+func startGRPCServer(t *testing.T, s *testServer) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(server, s)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	return lis.Addr().String()
+}
+
+var testEvents = []string{
+	`{"k8s_pod":"api-1","level":"warn","message":"slow request","time":"2023-10-19T10:00:00.5Z",` +
+		`"trace_id":"0af7651916cd43dd8448eb211c80319c","span_id":"b7ad6b7169203331","duration":1.5,"request":{"path":"/","retries":2,"ok":true},"tags":["a",null]}`,
+	`{"k8s_pod":"api-2","level":"error","message":{"error":"timeout"},"trace_id":"not hex"}`,
+	`{"k8s_pod":"api-1","message":"done"}`,
+}
+
+// the expected request without the observed time, the ids are base64 encoded by protojson
+const wantRequest = `{"resourceLogs":[
+	{
+		"resource":{"attributes":[{"key":"k8s.pod.name","value":{"stringValue":"api-1"}},{"key":"service.name","value":{"stringValue":"payments"}}]},
+		"scopeLogs":[{"scope":{"name":"file.d"},"logRecords":[
+			{
+				"timeUnixNano":"1697709600500000000","severityNumber":"SEVERITY_NUMBER_WARN","severityText":"warn",
+				"body":{"stringValue":"slow request"},
+				"attributes":[
+					{"key":"duration","value":{"doubleValue":1.5}},
+					{"key":"request","value":{"kvlistValue":{"values":[
+						{"key":"path","value":{"stringValue":"/"}},
+						{"key":"retries","value":{"intValue":"2"}},
+						{"key":"ok","value":{"boolValue":true}}
+					]}}},
+					{"key":"tags","value":{"arrayValue":{"values":[{"stringValue":"a"},{}]}}}
+				],
+				"traceId":"CvdlGRbNQ92ESOshHIAxnA==","spanId":"t61rcWkgMzE="
+			},
+			{"body":{"stringValue":"done"}}
+		]}]
+	},
+	{
+		"resource":{"attributes":[{"key":"k8s.pod.name","value":{"stringValue":"api-2"}},{"key":"service.name","value":{"stringValue":"payments"}}]},
+		"scopeLogs":[{"scope":{"name":"file.d"},"logRecords":[
+			{
+				"severityNumber":"SEVERITY_NUMBER_ERROR","severityText":"error",
+				"body":{"kvlistValue":{"values":[{"key":"error","value":{"stringValue":"timeout"}}]}},
+				"attributes":[{"key":"trace_id","value":{"stringValue":"not hex"}}]
+			}
+		]}]
+	}
+]}`
+
+func requireRequest(t *testing.T, req *collogspb.ExportLogsServiceRequest) {
+	t.Helper()
+
+	for _, rl := range req.ResourceLogs {
+		for _, sl := range rl.ScopeLogs {
+			for _, record := range sl.LogRecords {
+				require.NotZero(t, record.ObservedTimeUnixNano)
+				record.ObservedTimeUnixNano = 0
+			}
+		}
+	}
+	b, err := protojson.Marshal(req)
+	require.NoError(t, err)
+	require.JSONEq(t, wantRequest, string(b))
+}
+
+func TestOTLPGRPC(t *testing.T) {
+	s := &testServer{}
+	p := newTestPlugin(t, &Config{
+		Endpoint:                 startGRPCServer(t, s),
+		Protocol:                 protocolGRPC,
+		Compression:              compressionGzip,
+		Headers:                  map[string]string{"api-key": "secret"},
+		ResourceAttributes:       map[string]string{"k8s.pod.name": "k8s_pod"},
+		StaticResourceAttributes: map[string]string{"service.name": "payments"},
+	})
+
+	data := pipeline.WorkerData(nil)
+	require.NoError(t, p.out(&data, newTestBatch(t, testEvents...)))
+	requireRequest(t, s.req)
+	require.Equal(t, []string{"secret"}, s.md.Get("api-key"))
+
+	s.resp = &collogspb.ExportLogsServiceResponse{
+		PartialSuccess: &collogspb.ExportLogsPartialSuccess{RejectedLogRecords: 1, ErrorMessage: "too old"},
+	}
+	require.NoError(t, p.out(&data, newTestBatch(t, testEvents...)))
+
+	s.resp = nil
+	s.err = status.Error(codes.Unavailable, "overloaded")
+	require.Error(t, p.out(&data, newTestBatch(t, testEvents...)), "unavailable must be retried")
+
+	s.err = status.Error(codes.InvalidArgument, "bad request")
+	require.NoError(t, p.out(&data, newTestBatch(t, testEvents...)), "invalid argument mustn't be retried")
+}
+
+func TestOTLPHTTP(t *testing.T) {
+	var (
+		req    *collogspb.ExportLogsServiceRequest
+		status = http.StatusOK
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/logs", r.URL.Path)
+		require.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		req = &collogspb.ExportLogsServiceRequest{}
+		require.NoError(t, proto.Unmarshal(b, req))
+
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	p := newTestPlugin(t, &Config{
+		Endpoint:                 server.URL + "/v1/logs",
+		Protocol:                 protocolHTTP,
+		ResourceAttributes:       map[string]string{"k8s.pod.name": "k8s_pod"},
+		StaticResourceAttributes: map[string]string{"service.name": "payments"},
+		RequestTimeout:           "1s",
+	})
+
+	data := pipeline.WorkerData(nil)
+	require.NoError(t, p.out(&data, newTestBatch(t, testEvents...)))
+	requireRequest(t, req)
+
+	status = http.StatusServiceUnavailable
+	require.Error(t, p.out(&data, newTestBatch(t, testEvents...)))
+
+	status = http.StatusBadRequest
+	require.NoError(t, p.out(&data, newTestBatch(t, testEvents...)))
+}
+
+func TestSeverityNumber(t *testing.T) {
+	require.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_TRACE, severityNumber("TRACE"))
+	require.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_INFO, severityNumber("info"))
+	require.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_FATAL4, severityNumber("panic"))
+	require.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, severityNumber("verbose"))
+}
+
+func TestAnyValue(t *testing.T) {
+	root, err := insaneJSON.DecodeString(`{"big":1e3,"neg":-5}`)
+	require.NoError(t, err)
+	defer insaneJSON.Release(root)
+
+	got := &commonpb.KeyValueList{Values: keyValues(root.Node, nil)}
+	want := &commonpb.KeyValueList{Values: []*commonpb.KeyValue{
+		{Key: "big", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: 1000}}},
+		{Key: "neg", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: -5}}},
+	}}
+	require.True(t, proto.Equal(want, got), got.String())
+}
@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/ozontech/file.d/pipeline"
+	"go.uber.org/zap"
+)
+
+const (
+	partitionSuffixDay  = "20060102"
+	partitionSuffixHour = "2006010215"
+)
+
+func validatePartitionColumn(pgFields []column, name string) error {
+	for _, field := range pgFields {
+		if field.Name != name {
+			continue
+		}
+		if field.ColType != pgTimestamp {
+			return fmt.Errorf("partition column %q must have %s type", name, colTypeTimestamp)
+		}
+		return nil
+	}
+	return fmt.Errorf("partition column %q isn't found in columns", name)
+}
+
+// createPartitions creates the partitions for the timestamps of the batch which aren't created yet.
+func (p *Plugin) createPartitions(batch *pipeline.Batch) error {
+	starts := make(map[int64]struct{})
+	batch.ForEach(func(event *pipeline.Event) {
+		// invalid events are discarded later
+		node, err := event.Root.DigStrict(p.config.PartitionColumn)
+		if err != nil {
+			return
+		}
+		ts, err := node.AsInt()
+		if err != nil || ts < 0 || ts > nineThousandYear {
+			return
+		}
+		starts[p.partitionStart(time.Unix(int64(ts), 0)).Unix()] = struct{}{}
+	})
+
+	p.partitionsMu.Lock()
+	defer p.partitionsMu.Unlock()
+
+	missing := make([]int64, 0, len(starts))
+	for start := range starts {
+		if _, ok := p.partitions[start]; !ok {
+			missing = append(missing, start)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+
+	for _, start := range missing {
+		query := p.createPartitionQuery(time.Unix(start, 0).UTC())
+
+		ctx, cancel := context.WithTimeout(p.ctx, p.config.DBRequestTimeout_)
+		_, err := p.pool.Exec(ctx, query)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("can't exec query %q: %w", query, err)
+		}
+
+		p.partitions[start] = struct{}{}
+		p.logger.Infow("partition is created", zap.String("query", query))
+	}
+	return nil
+}
+
+func (p *Plugin) partitionStart(ts time.Time) time.Time {
+	ts = ts.UTC()
+	if p.config.PartitionInterval == partitionIntervalHour {
+		return ts.Truncate(time.Hour)
+	}
+	return time.Date(ts.Year(), ts.Month(), ts.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func (p *Plugin) createPartitionQuery(start time.Time) string {
+	suffix, end := partitionSuffixDay, start.AddDate(0, 0, 1)
+	if p.config.PartitionInterval == partitionIntervalHour {
+		suffix, end = partitionSuffixHour, start.Add(time.Hour)
+	}
+
+	table := tableIdentifier(p.config.Table)
+	partition := make(pgx.Identifier, len(table))
+	copy(partition, table)
+	partition[len(partition)-1] += "_" + start.Format(suffix)
+
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')",
+		partition.Sanitize(), table.Sanitize(), start.Format(time.RFC3339), end.Format(time.RFC3339),
+	)
+}
+
+// tableIdentifier splits the optionally schema-qualified table name.
+func tableIdentifier(table string) pgx.Identifier {
+	return strings.Split(table, ".")
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/ozontech/file.d/logger"
+	"github.com/ozontech/file.d/pipeline"
+	mock_pg "github.com/ozontech/file.d/plugin/output/postgres/mock"
+	"github.com/stretchr/testify/require"
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+func TestCreatePartitionQuery(t *testing.T) {
+	start := time.Date(2023, 12, 31, 23, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		table    string
+		interval string
+		want     string
+	}{
+		{
+			name:     "day",
+			table:    "events",
+			interval: partitionIntervalDay,
+			want:     `CREATE TABLE IF NOT EXISTS "events_20231231" PARTITION OF "events" FOR VALUES FROM ('2023-12-31T23:00:00Z') TO ('2024-01-01T23:00:00Z')`,
+		},
+		{
+			name:     "hour_with_schema",
+			table:    "logs.events",
+			interval: partitionIntervalHour,
+			want:     `CREATE TABLE IF NOT EXISTS "logs"."events_2023123123" PARTITION OF "logs"."events" FOR VALUES FROM ('2023-12-31T23:00:00Z') TO ('2024-01-01T00:00:00Z')`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plugin{config: &Config{Table: tt.table, PartitionInterval: tt.interval}}
+			require.Equal(t, tt.want, p.createPartitionQuery(start))
+		})
+	}
+}
+
+func TestCreatePartitions(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	mockpool := mock_pg.NewMockPgxIface(ctl)
+
+	p := &Plugin{
+		config: &Config{
+			Table:             "events",
+			PartitionColumn:   "ts",
+			PartitionInterval: partitionIntervalDay,
+			DBRequestTimeout_: time.Second,
+		},
+		pool:       mockpool,
+		logger:     logger.Instance,
+		ctx:        context.Background(),
+		partitions: make(map[int64]struct{}),
+	}
+
+	newBatch := func(timestamps ...int) *pipeline.Batch {
+		events := make([]*pipeline.Event, 0, len(timestamps))
+		for _, ts := range timestamps {
+			root := insaneJSON.Spawn()
+			t.Cleanup(func() { insaneJSON.Release(root) })
+			root.AddField("ts").MutateToInt(ts)
+			events = append(events, &pipeline.Event{Root: root})
+		}
+		return pipeline.NewPreparedBatch(events)
+	}
+
+	var ctxMock = reflect.TypeOf((*context.Context)(nil)).Elem()
+	day1 := int(time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC).Unix())
+	day2 := int(time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC).Unix())
+
+	mockpool.EXPECT().Exec(
+		gomock.AssignableToTypeOf(ctxMock),
+		`CREATE TABLE IF NOT EXISTS "events_20230101" PARTITION OF "events" FOR VALUES FROM ('2023-01-01T00:00:00Z') TO ('2023-01-02T00:00:00Z')`,
+	).Return(nil, nil).Times(1)
+	mockpool.EXPECT().Exec(
+		gomock.AssignableToTypeOf(ctxMock),
+		`CREATE TABLE IF NOT EXISTS "events_20230102" PARTITION OF "events" FOR VALUES FROM ('2023-01-02T00:00:00Z') TO ('2023-01-03T00:00:00Z')`,
+	).Return(nil, errors.New("some error")).Times(1)
+
+	require.Error(t, p.createPartitions(newBatch(day1, day1+60, day2)))
+
+	mockpool.EXPECT().Exec(
+		gomock.AssignableToTypeOf(ctxMock),
+		`CREATE TABLE IF NOT EXISTS "events_20230102" PARTITION OF "events" FOR VALUES FROM ('2023-01-02T00:00:00Z') TO ('2023-01-03T00:00:00Z')`,
+	).Return(nil, nil).Times(1)
+
+	// the first partition is already created, so only the failed one is retried
+	require.NoError(t, p.createPartitions(newBatch(day1, day2)))
+	require.NoError(t, p.createPartitions(newBatch(day2)))
+}
+
+func TestValidatePartitionColumn(t *testing.T) {
+	fields := []column{{Name: "ts", ColType: pgTimestamp}, {Name: "id", ColType: pgInt}}
+
+	require.NoError(t, validatePartitionColumn(fields, "ts"))
+	require.Error(t, validatePartitionColumn(fields, "id"))
+	require.Error(t, validatePartitionColumn(fields, "unknown"))
+}
+
+func TestTimestampsInUTC(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("UTC-5", -5*60*60)
+	t.Cleanup(func() { time.Local = local })
+
+	// it's still 2023-12-31 in the local time
+	ts := time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)
+
+	root, err := insaneJSON.DecodeString(`{"ts":` + strconv.FormatInt(ts.Unix(), 10) + `}`)
+	require.NoError(t, err)
+	defer insaneJSON.Release(root)
+	node, err := root.DigStrict("ts")
+	require.NoError(t, err)
+
+	col := column{Name: "ts", ColType: pgTimestamp}
+
+	p := &Plugin{config: &Config{PartitionInterval: partitionIntervalDay}}
+	require.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), p.partitionStart(time.Unix(ts.Unix(), 0)))
+
+	value, err := p.addFieldToValues(col, node)
+	require.NoError(t, err)
+	require.Equal(t, "2024-01-01T02:00:00Z", value)
+
+	p.config.Mode = modeCopy
+	value, err = p.addFieldToValues(col, node)
+	require.NoError(t, err)
+	require.Equal(t, ts, value)
+}
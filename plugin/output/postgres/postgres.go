@@ -5,9 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/ozontech/file.d/cfg"
@@ -22,6 +24,13 @@ import (
 
 /*{ introduction
 It sends the event batches to postgres db using pgx.
+
+In the `insert` mode the batches are written by multi-row `INSERT ... ON CONFLICT` statements,
+the events are deduplicated by the unique columns.
+The `copy` mode uses the `COPY FROM` protocol, it's much faster for append-only tables without unique columns.
+
+If `partition_column` is set, the daily or hourly partitions of the partitioned table
+are created automatically before writing the events into them.
 }*/
 
 /*{ example
@@ -66,6 +75,8 @@ var (
 
 type PgxIface interface {
 	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
 	Close()
 }
 
@@ -94,6 +105,16 @@ const (
 	colTypeTimestamp = "timestamp"
 )
 
+const (
+	modeInsert = "insert"
+	modeCopy   = "copy"
+)
+
+const (
+	partitionIntervalDay  = "day"
+	partitionIntervalHour = "hour"
+)
+
 type Plugin struct {
 	controller pipeline.OutputPluginController
 	logger     *zap.SugaredLogger
@@ -105,6 +126,10 @@ type Plugin struct {
 	queryBuilder PgQueryBuilder
 	pool         PgxIface
 
+	// partitions contains the start times of the partitions which are known to exist.
+	partitions   map[int64]struct{}
+	partitionsMu sync.Mutex
+
 	// plugin metrics
 
 	discardedEventMetric  prometheus.Counter
@@ -154,6 +179,25 @@ type Config struct {
 	// > and nullable options.
 	Columns []ConfigColumn `json:"columns" required:"true" slice:"true"` // *
 
+	// > @3@4@5@6
+	// >
+	// > How to write the batches:
+	// > * `insert` – multi-row `INSERT` statements with deduplication of the events by the unique columns
+	// > * `copy` – `COPY FROM` protocol, the columns can't be unique in this mode
+	Mode string `json:"mode" default:"insert" options:"insert|copy"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The timestamp column by which the table is partitioned.
+	// > If it's set, the partitions are created automatically as `<table>_YYYYMMDD` or `<table>_YYYYMMDDHH`
+	// > depending on `partition_interval`. The bounds of the partitions are in UTC.
+	PartitionColumn string `json:"partition_column"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The time range of the automatically created partitions.
+	PartitionInterval string `json:"partition_interval" default:"day" options:"day|hour"` // *
+
 	// > @3@4@5@6
 	// >
 	// > Retries of insertion. If File.d cannot insert for this number of attempts,
@@ -264,6 +308,16 @@ func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.OutputPluginP
 	}
 	p.queryBuilder = queryBuilder
 
+	if p.config.Mode == modeCopy && len(queryBuilder.GetUniqueFields()) > 0 {
+		p.logger.Fatal("unique columns aren't supported in the copy mode")
+	}
+	if p.config.PartitionColumn != "" {
+		if err := validatePartitionColumn(queryBuilder.GetPgFields(), p.config.PartitionColumn); err != nil {
+			p.logger.Fatal(err)
+		}
+	}
+	p.partitions = make(map[int64]struct{})
+
 	pgCfg, err := p.parsePGConfig()
 	if err != nil {
 		p.logger.Fatalf("can't create pgsql config: %v", err)
@@ -329,7 +383,22 @@ func (p *Plugin) Out(event *pipeline.Event) {
 	p.batcher.Add(event)
 }
 
-func (p *Plugin) out(_ *pipeline.WorkerData, batch *pipeline.Batch) error {
+func (p *Plugin) out(workerData *pipeline.WorkerData, batch *pipeline.Batch) error {
+	if p.config.PartitionColumn != "" {
+		if err := p.createPartitions(batch); err != nil {
+			p.insertErrorsMetric.Inc()
+			p.logger.Errorf("can't create partitions: %s", err.Error())
+			return err
+		}
+	}
+
+	if p.config.Mode == modeCopy {
+		return p.outCopy(batch)
+	}
+	return p.outInsert(workerData, batch)
+}
+
+func (p *Plugin) outInsert(_ *pipeline.WorkerData, batch *pipeline.Batch) error {
 	// _ *pipeline.WorkerData - doesn't required in this plugin, we can't parse
 	// events for uniques through bytes.
 	builder := p.queryBuilder.GetInsertBuilder()
@@ -343,18 +412,7 @@ func (p *Plugin) out(_ *pipeline.WorkerData, batch *pipeline.Batch) error {
 	batch.ForEach(func(event *pipeline.Event) {
 		fieldValues, uniqueID, err := p.processEvent(event, pgFields, uniqFields)
 		if err != nil {
-			switch {
-			case errors.Is(err, ErrEventDoesntHaveField), errors.Is(err, ErrEventFieldHasWrongType),
-				errors.Is(err, ErrTimestampFromDistantPastOrFuture):
-				p.discardedEventMetric.Inc()
-				if p.config.StrictFields || p.config.Strict {
-					p.logger.Fatal(err)
-				}
-				p.logger.Error(err)
-			default: // protection from foolproof.
-				p.logger.Fatalf("undefined error: %w", err)
-			}
-
+			p.handleEventError(err)
 			return
 		}
 
@@ -399,6 +457,58 @@ func (p *Plugin) out(_ *pipeline.WorkerData, batch *pipeline.Batch) error {
 	return nil
 }
 
+// outCopy writes the batch by the COPY FROM protocol.
+// There are no unique columns in this mode, so the events aren't deduplicated.
+func (p *Plugin) outCopy(batch *pipeline.Batch) error {
+	pgFields := p.queryBuilder.GetPgFields()
+
+	rows := make([][]any, 0, p.config.BatchSize_)
+	batch.ForEach(func(event *pipeline.Event) {
+		fieldValues, _, err := p.processEvent(event, pgFields, nil)
+		if err != nil {
+			p.handleEventError(err)
+			return
+		}
+		rows = append(rows, fieldValues)
+	})
+
+	// no valid events passed.
+	if len(rows) == 0 {
+		return nil
+	}
+
+	columnNames := make([]string, 0, len(pgFields))
+	for _, field := range pgFields {
+		columnNames = append(columnNames, field.Name)
+	}
+
+	ctx, cancel := context.WithTimeout(p.ctx, p.config.DBRequestTimeout_)
+	defer cancel()
+
+	written, err := p.pool.CopyFrom(ctx, tableIdentifier(p.config.Table), columnNames, pgx.CopyFromRows(rows))
+	if err != nil {
+		p.insertErrorsMetric.Inc()
+		p.logger.Errorf("can't copy rows: %s", err.Error())
+		return err
+	}
+	p.writtenEventMetric.Add(float64(written))
+	return nil
+}
+
+func (p *Plugin) handleEventError(err error) {
+	switch {
+	case errors.Is(err, ErrEventDoesntHaveField), errors.Is(err, ErrEventFieldHasWrongType),
+		errors.Is(err, ErrTimestampFromDistantPastOrFuture):
+		p.discardedEventMetric.Inc()
+		if p.config.StrictFields || p.config.Strict {
+			p.logger.Fatal(err)
+		}
+		p.logger.Error(err)
+	default: // protection from foolproof.
+		p.logger.Fatalf("undefined error: %w", err)
+	}
+}
+
 func (p *Plugin) try(query string, argsSliceInterface []any) error {
 	ctx, cancel := context.WithTimeout(p.ctx, p.config.DBRequestTimeout_)
 	defer cancel()
@@ -465,7 +575,13 @@ func (p *Plugin) addFieldToValues(field column, sNode *insaneJSON.StrictNode) (a
 		if tint < 0 || tint > nineThousandYear {
 			return nil, fmt.Errorf("%w, %s", ErrTimestampFromDistantPastOrFuture, field.Name)
 		}
-		return time.Unix(int64(tint), 0).Format(time.RFC3339), nil
+		// the partition bounds are in UTC, so the values are too
+		ts := time.Unix(int64(tint), 0).UTC()
+		// COPY uses the binary format, so the timestamps are passed as is.
+		if p.config.Mode == modeCopy {
+			return ts, nil
+		}
+		return ts.Format(time.RFC3339), nil
 	default:
 		return nil, fmt.Errorf("%w, undefined col type: %d, col name: %s", ErrEventFieldHasWrongType, field.ColType, field.Name)
 	}
@@ -10,6 +10,7 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgproto3/v2"
+	"github.com/jackc/pgx/v4"
 	"github.com/ozontech/file.d/logger"
 	"github.com/ozontech/file.d/metric"
 	"github.com/ozontech/file.d/pipeline"
@@ -76,7 +77,7 @@ func TestPrivateOut(t *testing.T) {
 	mockpool.EXPECT().Query(
 		gomock.AssignableToTypeOf(ctxMock),
 		"INSERT INTO table1 (str_uni_1,int_uni_1,int_1,timestamp_1) VALUES ($1,$2,$3,$4) ON CONFLICT(str_uni_1,int_uni_1) DO UPDATE SET int_1=EXCLUDED.int_1,timestamp_1=EXCLUDED.timestamp_1",
-		[]any{preferSimpleProtocol, strUniValue, intUniValue, intValue, time.Unix(int64(timestampValue), 0).Format(time.RFC3339)},
+		[]any{preferSimpleProtocol, strUniValue, intUniValue, intValue, time.Unix(int64(timestampValue), 0).UTC().Format(time.RFC3339)},
 	).Return(&rowsForTest{}, nil).Times(1)
 
 	builder, err := NewQueryBuilder(columns, table)
@@ -146,7 +147,7 @@ func TestPrivateOutWithRetry(t *testing.T) {
 	mockpool.EXPECT().Query(
 		gomock.AssignableToTypeOf(ctxMock),
 		"INSERT INTO table1 (str_uni_1,int_1,timestamp_1) VALUES ($1,$2,$3) ON CONFLICT(str_uni_1) DO UPDATE SET int_1=EXCLUDED.int_1,timestamp_1=EXCLUDED.timestamp_1",
-		[]any{preferSimpleProtocol, strUniValue, intValue, time.Unix(int64(timestampValue), 0).Format(time.RFC3339)},
+		[]any{preferSimpleProtocol, strUniValue, intValue, time.Unix(int64(timestampValue), 0).UTC().Format(time.RFC3339)},
 	).Return(&rowsForTest{}, errors.New("someError")).Times(1)
 
 	builder, err := NewQueryBuilder(columns, table)
@@ -279,7 +280,7 @@ func TestPrivateOutDeduplicatedEvents(t *testing.T) {
 	mockpool.EXPECT().Query(
 		gomock.AssignableToTypeOf(ctxMock),
 		"INSERT INTO table1 (str_uni_1,int_uni_1,int_1,timestamp_1) VALUES ($1,$2,$3,$4) ON CONFLICT(str_uni_1,int_uni_1) DO UPDATE SET int_1=EXCLUDED.int_1,timestamp_1=EXCLUDED.timestamp_1",
-		[]any{preferSimpleProtocol, strUniValue, intUniValue, intValue, time.Unix(int64(timestampValue), 0).Format(time.RFC3339)},
+		[]any{preferSimpleProtocol, strUniValue, intUniValue, intValue, time.Unix(int64(timestampValue), 0).UTC().Format(time.RFC3339)},
 	).Return(&rowsForTest{}, nil).Times(1)
 
 	builder, err := NewQueryBuilder(columns, table)
@@ -449,8 +450,8 @@ func TestPrivateOutFewUniqueEventsYetWithDeduplicationEventsAnpooladEvents(t *te
 	mockpool.EXPECT().Query(
 		gomock.AssignableToTypeOf(ctxMock),
 		"INSERT INTO table1 (str_uni_1,int_uni_1,int_1,timestamp_1) VALUES ($1,$2,$3,$4),($5,$6,$7,$8) ON CONFLICT(str_uni_1,int_uni_1) DO UPDATE SET int_1=EXCLUDED.int_1,timestamp_1=EXCLUDED.timestamp_1",
-		[]any{preferSimpleProtocol, strUniValue, intUniValue, intValue, time.Unix(int64(timestampValue), 0).Format(time.RFC3339),
-			secStrUniValue, secIntUniValue, secIntValue, time.Unix(int64(secTimestampValue), 0).Format(time.RFC3339)},
+		[]any{preferSimpleProtocol, strUniValue, intUniValue, intValue, time.Unix(int64(timestampValue), 0).UTC().Format(time.RFC3339),
+			secStrUniValue, secIntUniValue, secIntValue, time.Unix(int64(secTimestampValue), 0).UTC().Format(time.RFC3339)},
 	).Return(&rowsForTest{}, nil).Times(1)
 
 	builder, err := NewQueryBuilder(columns, table)
@@ -476,6 +477,83 @@ func TestPrivateOutFewUniqueEventsYetWithDeduplicationEventsAnpooladEvents(t *te
 	p.out(nil, batch)
 }
 
+func TestPrivateOutCopy(t *testing.T) {
+	testLogger := logger.Instance
+
+	columns := []ConfigColumn{
+		{
+			Name:       "str_1",
+			ColumnType: "string",
+		},
+		{
+			Name:       "int_1",
+			ColumnType: "int",
+		},
+		{
+			Name:       "timestamp_1",
+			ColumnType: colTypeTimestamp,
+		},
+	}
+
+	root := insaneJSON.Spawn()
+	defer insaneJSON.Release(root)
+	root.AddField(columns[0].Name).MutateToString("str_1_value")
+	root.AddField(columns[1].Name).MutateToInt(10)
+	root.AddField(columns[2].Name).MutateToInt(100)
+
+	badRoot := insaneJSON.Spawn()
+	defer insaneJSON.Release(badRoot)
+	badRoot.AddField(columns[0].Name).MutateToString("str_1_value")
+
+	table := "public.table1"
+
+	config := Config{
+		Columns:           columns,
+		Table:             table,
+		Retry:             3,
+		Mode:              modeCopy,
+		DBRequestTimeout_: time.Second,
+	}
+
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	mockpool := mock_pg.NewMockPgxIface(ctl)
+
+	var ctxMock = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+	var rows [][]any
+	mockpool.EXPECT().CopyFrom(
+		gomock.AssignableToTypeOf(ctxMock),
+		pgx.Identifier{"public", "table1"},
+		[]string{"str_1", "int_1", "timestamp_1"},
+		gomock.Any(),
+	).DoAndReturn(func(_ context.Context, _ pgx.Identifier, _ []string, src pgx.CopyFromSource) (int64, error) {
+		for src.Next() {
+			values, err := src.Values()
+			require.NoError(t, err)
+			rows = append(rows, values)
+		}
+		return int64(len(rows)), nil
+	}).Times(1)
+
+	builder, err := NewQueryBuilder(columns, table)
+	require.NoError(t, err)
+
+	p := &Plugin{
+		config:       &config,
+		queryBuilder: builder,
+		pool:         mockpool,
+		logger:       testLogger,
+		ctx:          context.Background(),
+	}
+
+	p.registerMetrics(metric.NewCtl("test", prometheus.NewRegistry()))
+
+	batch := pipeline.NewPreparedBatch([]*pipeline.Event{{Root: root}, {Root: badRoot}})
+	require.NoError(t, p.out(nil, batch))
+	require.Equal(t, [][]any{{"str_1_value", 10, time.Unix(100, 0).UTC()}}, rows)
+}
+
 // TODO replace with gomock
 type rowsForTest struct{}
 
@@ -11,7 +11,8 @@ import (
 )
 
 const (
-	zipName = "zip"
+	zipName     = "zip"
+	parquetName = "parquet"
 )
 
 type zipCompressor struct {
@@ -84,3 +85,26 @@ func (z *zipCompressor) getObjectOptions() minio.PutObjectOptions {
 func (z *zipCompressor) getExtension() string {
 	return fmt.Sprintf(".%s", zipName)
 }
+
+// parquetCompressor uploads the parquet files as is, they are already compressed by the file plugin.
+type parquetCompressor struct{}
+
+func newParquetCompressor(_ *zap.SugaredLogger) compressor {
+	return &parquetCompressor{}
+}
+
+func (c *parquetCompressor) getName(fileName string) string {
+	return fileName
+}
+
+func (c *parquetCompressor) compress(_, _ string) {}
+
+func (c *parquetCompressor) getObjectOptions() minio.PutObjectOptions {
+	return minio.PutObjectOptions{
+		ContentType: "application/vnd.apache.parquet",
+	}
+}
+
+func (c *parquetCompressor) getExtension() string {
+	return fmt.Sprintf(".%s", parquetName)
+}
@@ -37,6 +37,9 @@ If appears we try to send event to this bucket instead of described here.
 > To send this data to s3 move bucket dir from /var/log/dynamic_buckets/bucketName to /var/log/static_buckets/bucketName (/var/log is default path)
 > and restart file.d
 
+If `file_config.format` is `parquet`, the sealed parquet files are uploaded as is without `compression_type`,
+the objects get the `.parquet` extension and can be read by Spark or ClickHouse `s3()` directly.
+
 **Example**
 Standard example:
 ```yaml
@@ -183,7 +186,7 @@ type Config struct {
 
 	// > @3@4@5@6
 	// >
-	// > Compressed files format.
+	// > Compressed files format. It's ignored in the parquet format of `file_config`.
 	CompressionType string `json:"compression_type" default:"zip" options:"zip"` // *
 
 	// s3 section
@@ -311,6 +314,10 @@ func (p *Plugin) StartWithMinio(config pipeline.AnyConfig, params *pipeline.Outp
 	if !ok {
 		p.logger.Fatalf("compression type: %s is not supported", p.config.CompressionType)
 	}
+	// parquet files are compressed by the file plugin.
+	if p.config.FileConfig.Format == parquetName {
+		newCompressor = newParquetCompressor
+	}
 	p.compressor = newCompressor(p.logger)
 
 	// dir for all bucket files.
@@ -573,10 +580,13 @@ func (p *Plugin) compressWork() {
 		p.logger.Infof("compress fileName=%s, bucketName=%s", dto.fileName, dto.bucketName)
 
 		compressedName := p.compressor.getName(dto.fileName)
-		p.compressor.compress(compressedName, dto.fileName)
-		// delete old file
-		if err := os.Remove(dto.fileName); err != nil && !os.IsNotExist(err) {
-			p.logger.Panicf("could not delete file: %s, error: %s", dto, err.Error())
+		// the file is uploaded as is if the compressor doesn't change it.
+		if compressedName != dto.fileName {
+			p.compressor.compress(compressedName, dto.fileName)
+			// delete old file
+			if err := os.Remove(dto.fileName); err != nil && !os.IsNotExist(err) {
+				p.logger.Panicf("could not delete file: %s, error: %s", dto, err.Error())
+			}
 		}
 		dto.fileName = compressedName
 		p.uploadCh <- fileDTO{fileName: dto.fileName, bucketName: dto.bucketName}
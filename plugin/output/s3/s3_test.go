@@ -18,6 +18,7 @@ import (
 	"github.com/ozontech/file.d/plugin/output/file"
 	mock_s3 "github.com/ozontech/file.d/plugin/output/s3/mock"
 	"github.com/ozontech/file.d/test"
+	"github.com/parquet-go/parquet-go"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/atomic"
@@ -431,6 +432,87 @@ func newPipeline(t *testing.T, configOutput *Config, objStoreF objStoreFactory)
 	return p
 }
 
+func TestStartParquet(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip long tests in short mode")
+	}
+
+	type parquetRow struct {
+		Message *string `parquet:"message,optional"`
+		Level   *string `parquet:"level,optional"`
+	}
+	type uploaded struct {
+		objectName  string
+		contentType string
+		rows        []parquetRow
+	}
+
+	bucketName := "some"
+	uploads := make(chan uploaded, 16)
+
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	s3MockClient := mock_s3.NewMockObjectStoreClient(ctl)
+	s3MockClient.EXPECT().BucketExists(bucketName).Return(true, nil).AnyTimes()
+	s3MockClient.EXPECT().FPutObjectWithContext(gomock.Any(), bucketName, gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _, objectName, filePath string, opts minio.PutObjectOptions) (int64, error) {
+			rows, err := parquet.ReadFile[parquetRow](filePath)
+			assert.NoError(t, err)
+			uploads <- uploaded{objectName: objectName, contentType: opts.ContentType, rows: rows}
+			return 1, nil
+		}).AnyTimes()
+
+	config := &Config{
+		FileConfig: file.Config{
+			TargetFile:        targetFile,
+			RetentionInterval: "300ms",
+			Layout:            "01",
+			BatchFlushTimeout: "100ms",
+			Format:            "parquet",
+			ParquetColumns: []file.ParquetColumn{
+				{Name: "message", Type: "string"},
+				{Name: "level", Type: "string"},
+			},
+		},
+		CompressionType: "zip",
+		Endpoint:        bucketName,
+		AccessKey:       bucketName,
+		SecretKey:       bucketName,
+		DefaultBucket:   bucketName,
+	}
+	test.ClearDir(t, dir)
+	defer test.ClearDir(t, dir)
+	test.NewConfig(config, map[string]int{"gomaxprocs": 1, "capacity": 64})
+
+	p := newPipeline(t, config, func(cfg *Config) (ObjectStoreClient, map[string]ObjectStoreClient, error) {
+		return s3MockClient, map[string]ObjectStoreClient{
+			bucketName: s3MockClient,
+		}, nil
+	})
+	p.Start()
+	defer p.Stop()
+
+	test.SendPack(t, p, []test.Msg{
+		test.Msg(`{"level":"error","message":"first"}`),
+		test.Msg(`{"level":"info","message":"second"}`),
+	})
+
+	select {
+	case u := <-uploads:
+		assert.True(t, strings.HasSuffix(u.objectName, ".parquet"), u.objectName)
+		assert.Equal(t, "application/vnd.apache.parquet", u.contentType)
+		assert.Len(t, u.rows, 2)
+		assert.Equal(t, "first", *u.rows[0].Message)
+		assert.Equal(t, "info", *u.rows[1].Level)
+	case <-time.After(5 * time.Second):
+		t.Fatal("parquet file isn't uploaded")
+	}
+
+	// the uploaded parquet file is deleted
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, test.GetMatches(t, fmt.Sprintf("%s/*.parquet", dir)))
+}
+
 func TestStartPanic(t *testing.T) {
 	test.ClearDir(t, dir)
 	fileConfig := file.Config{}
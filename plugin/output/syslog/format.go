@@ -0,0 +1,112 @@
+package syslog
+
+import (
+	"strconv"
+	"time"
+)
+
+const (
+	nilValue = "-"
+
+	rfc5424TimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+	// the maximum lengths of the header fields by RFC 5424
+	maxHostnameLen = 255
+	maxAppNameLen  = 48
+	maxProcIDLen   = 128
+	maxMsgIDLen    = 32
+
+	// the maximum length of the tag by RFC 3164
+	maxTagLen = 32
+)
+
+var facilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+func parseFacility(name string) (int, bool) {
+	for i, f := range facilities {
+		if f == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+type message struct {
+	priority int
+	time     time.Time
+	hostname string
+	appName  string
+	procID   string
+	msgID    string
+	msg      []byte
+}
+
+// appendRFC5424 appends the message in the `<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG` form.
+func appendRFC5424(buf []byte, m *message) []byte {
+	buf = appendPriority(buf, m.priority)
+	buf = append(buf, "1 "...)
+	buf = m.time.AppendFormat(buf, rfc5424TimeFormat)
+	buf = append(buf, ' ')
+	buf = appendHeaderField(buf, m.hostname, maxHostnameLen)
+	buf = append(buf, ' ')
+	buf = appendHeaderField(buf, m.appName, maxAppNameLen)
+	buf = append(buf, ' ')
+	buf = appendHeaderField(buf, m.procID, maxProcIDLen)
+	buf = append(buf, ' ')
+	buf = appendHeaderField(buf, m.msgID, maxMsgIDLen)
+	// the structured data isn't supported
+	buf = append(buf, ' ')
+	buf = append(buf, nilValue...)
+	if len(m.msg) > 0 {
+		buf = append(buf, ' ')
+		buf = append(buf, m.msg...)
+	}
+	return buf
+}
+
+// appendRFC3164 appends the message in the `<PRI>TIMESTAMP HOSTNAME TAG[PROCID]: MSG` form.
+func appendRFC3164(buf []byte, m *message) []byte {
+	buf = appendPriority(buf, m.priority)
+	buf = m.time.AppendFormat(buf, time.Stamp)
+	buf = append(buf, ' ')
+	buf = appendHeaderField(buf, m.hostname, maxHostnameLen)
+	buf = append(buf, ' ')
+	buf = appendHeaderField(buf, m.appName, maxTagLen)
+	if m.procID != "" {
+		buf = append(buf, '[')
+		buf = appendHeaderField(buf, m.procID, maxProcIDLen)
+		buf = append(buf, ']')
+	}
+	buf = append(buf, ": "...)
+	return append(buf, m.msg...)
+}
+
+func appendPriority(buf []byte, priority int) []byte {
+	buf = append(buf, '<')
+	buf = strconv.AppendInt(buf, int64(priority), 10)
+	return append(buf, '>')
+}
+
+// appendHeaderField appends the value truncated to maxLen
+// replacing the characters which aren't printable US-ASCII ones with the underscore.
+func appendHeaderField(buf []byte, value string, maxLen int) []byte {
+	if value == "" {
+		return append(buf, nilValue...)
+	}
+
+	if len(value) > maxLen {
+		value = value[:maxLen]
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < '!' || c > '~' {
+			c = '_'
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
@@ -0,0 +1,537 @@
+package syslog
+
+import (
+	"context"
+	"crypto/tls"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ozontech/file.d/cfg"
+	"github.com/ozontech/file.d/fd"
+	"github.com/ozontech/file.d/metric"
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/xtls"
+	"github.com/prometheus/client_golang/prometheus"
+	insaneJSON "github.com/vitkovskii/insane-json"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+/*{ introduction
+It sends events to the syslog server, e.g. to SIEM, in [RFC 5424](https://datatracker.ietf.org/doc/html/rfc5424)
+or [RFC 3164](https://datatracker.ietf.org/doc/html/rfc3164) format over UDP, TCP or TLS.
+
+The header of the syslog message is taken from the fields of the event: `hostname_field`, `app_name_field`, `proc_id_field`,
+`msg_id_field` and `severity_field`. The message is the value of `message_field` or the whole event if the event has no such field.
+The characters of the header fields which aren't printable US-ASCII ones are replaced with `_`.
+
+The messages are framed by the octet counting or by the new line over TCP and TLS,
+the new lines in the messages are replaced with the spaces for the latter.
+The connection is reestablished on the errors, the batch is retried with the exponential backoff.
+
+Every message is sent in its own datagram over UDP. The sending over UDP doesn't block the pipeline:
+the messages which can't be sent are dropped and counted in `output_syslog_send_error` metric.
+
+**Example:**
+```yaml
+pipelines:
+  example_pipeline:
+    ...
+    output:
+      type: syslog
+      network: tls
+      address: siem.example.com:6514
+      ca_cert: /etc/file.d/siem-ca.pem
+      format: rfc5424
+      facility: local0
+      app_name_field: service
+      severity_field: level
+      msg_id_field: event_type
+    ...
+```
+}*/
+
+const (
+	outPluginType = "syslog"
+
+	networkUDP = "udp"
+	networkTCP = "tcp"
+	networkTLS = "tls"
+
+	formatRFC5424 = "rfc5424"
+	formatRFC3164 = "rfc3164"
+
+	framingNewLine = "new_line"
+)
+
+type Plugin struct {
+	config     *Config
+	logger     *zap.SugaredLogger
+	batcher    *pipeline.RetriableBatcher
+	controller pipeline.OutputPluginController
+
+	tlsConfig       *tls.Config
+	facility        int
+	defaultSeverity pipeline.LogLevel
+	hostname        string
+
+	// plugin metrics
+	sendErrorMetric prometheus.Counter
+}
+
+// ! config-params
+// ^ config-params
+type Config struct {
+	// > @3@4@5@6
+	// >
+	// > The address of the syslog server. Format: `127.0.0.1:514`.
+	Address string `json:"address" required:"true"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The transport of the messages.
+	Network string `json:"network" default:"udp" options:"udp|tcp|tls"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Path or content of a PEM-encoded CA file for the `tls` network.
+	CACert string `json:"ca_cert"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Path or content of a PEM-encoded client certificate file for the `tls` network.
+	ClientCert string `json:"client_cert"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Path or content of a PEM-encoded client key file for the `tls` network.
+	ClientKey string `json:"client_key"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Skip the verification of the server certificate for the `tls` network.
+	InsecureSkipVerify bool `json:"insecure_skip_verify" default:"false"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The format of the messages.
+	Format string `json:"format" default:"rfc5424" options:"rfc5424|rfc3164"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The framing of the messages over TCP and TLS, see [RFC 6587](https://datatracker.ietf.org/doc/html/rfc6587).
+	Framing string `json:"framing" default:"octet_counting" options:"octet_counting|new_line"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The facility of the messages, e.g. `user`, `daemon`, `local0`.
+	Facility string `json:"facility" default:"user"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The event field with the severity of the message.
+	// > The severity is the RFC 5424 number or the name, e.g. `warn`, `error`.
+	SeverityField  cfg.FieldSelector `json:"severity_field" default:"level" parse:"selector"` // *
+	SeverityField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The severity of the events without or with the unknown `severity_field`.
+	DefaultSeverity string `json:"default_severity" default:"informational"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The event field with the hostname of the message.
+	HostnameField  cfg.FieldSelector `json:"hostname_field" default:"host" parse:"selector"` // *
+	HostnameField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The hostname of the events without `hostname_field`. The hostname of the machine is used if empty.
+	Hostname string `json:"hostname"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The event field with the app-name of the message, it's the tag for RFC 3164.
+	AppNameField  cfg.FieldSelector `json:"app_name_field" default:"app" parse:"selector"` // *
+	AppNameField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The app-name of the events without `app_name_field`.
+	AppName string `json:"app_name" default:"file.d"` // *
+
+	// > @3@4@5@6
+	// >
+	// > The event field with the procid of the message.
+	ProcIDField  cfg.FieldSelector `json:"proc_id_field" default:"pid" parse:"selector"` // *
+	ProcIDField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The event field with the msgid of the message. It's ignored for RFC 3164.
+	MsgIDField  cfg.FieldSelector `json:"msg_id_field" parse:"selector"` // *
+	MsgIDField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The event field with the message.
+	MessageField  cfg.FieldSelector `json:"message_field" default:"message" parse:"selector"` // *
+	MessageField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The event field with the time of the message. The events without it get the time of sending.
+	TimeField  cfg.FieldSelector `json:"time_field" default:"time" parse:"selector"` // *
+	TimeField_ []string
+
+	// > @3@4@5@6
+	// >
+	// > The format of `time_field`. It can be the name of the predefined format, e.g. `rfc3339nano`, or the Go time layout.
+	TimeFormat string `json:"time_format" default:"rfc3339nano"` // *
+
+	// > @3@4@5@6
+	// >
+	// > How often to reconnect to the server.
+	ReconnectInterval  cfg.Duration `json:"reconnect_interval" default:"1m" parse:"duration"` // *
+	ReconnectInterval_ time.Duration
+
+	// > @3@4@5@6
+	// >
+	// > How much time to wait for the connection.
+	ConnectionTimeout  cfg.Duration `json:"connection_timeout" default:"5s" parse:"duration"` // *
+	ConnectionTimeout_ time.Duration
+
+	// > @3@4@5@6
+	// >
+	// > How much time to wait for the write.
+	WriteTimeout  cfg.Duration `json:"write_timeout" default:"10s" parse:"duration"` // *
+	WriteTimeout_ time.Duration
+
+	// > @3@4@5@6
+	// >
+	// > How many workers will be instantiated to send batches.
+	WorkersCount  cfg.Expression `json:"workers_count" default:"gomaxprocs*4" parse:"expression"` // *
+	WorkersCount_ int
+
+	// > @3@4@5@6
+	// >
+	// > A maximum quantity of events to pack into one batch.
+	BatchSize  cfg.Expression `json:"batch_size" default:"capacity/4" parse:"expression"` // *
+	BatchSize_ int
+
+	// > @3@4@5@6
+	// >
+	// > A minimum size of events in a batch to send.
+	// > If both batch_size and batch_size_bytes are set, they will work together.
+	BatchSizeBytes  cfg.Expression `json:"batch_size_bytes" default:"0" parse:"expression"` // *
+	BatchSizeBytes_ int
+
+	// > @3@4@5@6
+	// >
+	// > After this timeout the batch will be sent even if batch isn't completed.
+	BatchFlushTimeout  cfg.Duration `json:"batch_flush_timeout" default:"200ms" parse:"duration"` // *
+	BatchFlushTimeout_ time.Duration
+
+	// > @3@4@5@6
+	// >
+	// > Retries of sending over TCP and TLS. If File.d cannot send for this number of attempts,
+	// > File.d will fall with non-zero exit code or skip message (see fatal_on_failed_insert).
+	Retry int `json:"retry" default:"10"` // *
+
+	// > @3@4@5@6
+	// >
+	// > After a send error, fall with a non-zero exit code or not
+	// > **Experimental feature**
+	FatalOnFailedInsert bool `json:"fatal_on_failed_insert" default:"false"` // *
+
+	// > @3@4@5@6
+	// >
+	// > Retention milliseconds for retry.
+	Retention  cfg.Duration `json:"retention" default:"1s" parse:"duration"` // *
+	Retention_ time.Duration
+
+	// > @3@4@5@6
+	// >
+	// > Multiplier for exponential increase of retention between retries
+	RetentionExponentMultiplier int `json:"retention_exponentially_multiplier" default:"2"` // *
+}
+
+type data struct {
+	outBuf []byte
+	msgBuf []byte
+	client *client
+}
+
+func init() {
+	fd.DefaultPluginRegistry.RegisterOutput(&pipeline.PluginStaticInfo{
+		Type:    outPluginType,
+		Factory: Factory,
+	})
+}
+
+func Factory() (pipeline.AnyPlugin, pipeline.AnyConfig) {
+	return &Plugin{}, &Config{}
+}
+
+func (p *Plugin) Start(config pipeline.AnyConfig, params *pipeline.OutputPluginParams) {
+	p.controller = params.Controller
+	p.logger = params.Logger
+	p.config = config.(*Config)
+	p.registerMetrics(params.MetricCtl)
+
+	p.prepare()
+
+	batcherOpts := pipeline.BatcherOptions{
+		PipelineName:        params.PipelineName,
+		OutputType:          outPluginType,
+		MaintenanceFn:       p.maintenance,
+		Controller:          p.controller,
+		Workers:             p.config.WorkersCount_,
+		BatchSizeCount:      p.config.BatchSize_,
+		BatchSizeBytes:      p.config.BatchSizeBytes_,
+		FlushTimeout:        p.config.BatchFlushTimeout_,
+		MaintenanceInterval: p.config.ReconnectInterval_,
+		MetricCtl:           params.MetricCtl,
+	}
+
+	backoffOpts := pipeline.BackoffOpts{
+		MinRetention: p.config.Retention_,
+		Multiplier:   float64(p.config.RetentionExponentMultiplier),
+		AttemptNum:   p.config.Retry,
+	}
+
+	onError := func(err error) {
+		var level zapcore.Level
+		if p.config.FatalOnFailedInsert {
+			level = zapcore.FatalLevel
+		} else {
+			level = zapcore.ErrorLevel
+		}
+
+		p.logger.Desugar().Log(level, "can't send to syslog", zap.Error(err),
+			zap.Int("retries", p.config.Retry),
+		)
+	}
+
+	p.batcher = pipeline.NewRetriableBatcher(
+		&batcherOpts,
+		p.out,
+		backoffOpts,
+		onError,
+	)
+
+	p.batcher.Start(context.TODO())
+}
+
+// prepare validates the config and initializes everything but the batcher.
+func (p *Plugin) prepare() {
+	facility, ok := parseFacility(p.config.Facility)
+	if !ok {
+		p.logger.Fatalf("unknown facility %q, it must be one of: %s", p.config.Facility, strings.Join(facilities, ", "))
+	}
+	p.facility = facility
+
+	p.defaultSeverity = pipeline.ParseLevelAsNumber(p.config.DefaultSeverity)
+	if p.defaultSeverity == pipeline.LevelUnknown {
+		p.logger.Fatalf("unknown default_severity %q", p.config.DefaultSeverity)
+	}
+
+	p.hostname = p.config.Hostname
+	if p.hostname == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			p.logger.Fatalf("can't get hostname: %s", err.Error())
+		}
+		p.hostname = hostname
+	}
+
+	format, err := pipeline.ParseFormatName(p.config.TimeFormat)
+	if err != nil {
+		format = p.config.TimeFormat
+	}
+	p.config.TimeFormat = format
+
+	if p.config.Network == networkTLS {
+		b := xtls.NewConfigBuilder()
+		if p.config.CACert != "" {
+			if err := b.AppendCARoot(p.config.CACert); err != nil {
+				p.logger.Fatalf("can't append CA root: %s", err.Error())
+			}
+		}
+		if p.config.ClientCert != "" || p.config.ClientKey != "" {
+			if err := b.AppendX509KeyPair(p.config.ClientCert, p.config.ClientKey); err != nil {
+				p.logger.Fatalf("can't append client certificate: %s", err.Error())
+			}
+		}
+		b.SetSkipVerify(p.config.InsecureSkipVerify)
+		p.tlsConfig = b.Build()
+	}
+}
+
+func (p *Plugin) Stop() {
+	p.batcher.Stop()
+}
+
+func (p *Plugin) Out(event *pipeline.Event) {
+	p.batcher.Add(event)
+}
+
+func (p *Plugin) registerMetrics(ctl *metric.Ctl) {
+	p.sendErrorMetric = ctl.RegisterCounter("output_syslog_send_error", "Total syslog send errors")
+}
+
+func (p *Plugin) out(workerData *pipeline.WorkerData, batch *pipeline.Batch) error {
+	if *workerData == nil {
+		*workerData = &data{}
+	}
+	data := (*workerData).(*data)
+
+	if data.client == nil {
+		p.logger.Infof("connecting to syslog address=%s", p.config.Address)
+
+		c, err := newClient(p.config.Network, p.config.Address, p.config.ConnectionTimeout_, p.config.WriteTimeout_, p.tlsConfig)
+		if err != nil {
+			p.sendErrorMetric.Inc()
+			p.logger.Errorf("can't connect to syslog address=%s: %s", p.config.Address, err.Error())
+			return err
+		}
+		data.client = c
+	}
+
+	if p.config.Network == networkUDP {
+		p.outUDP(data, batch)
+		return nil
+	}
+
+	outBuf := data.outBuf[:0]
+	now := time.Now()
+	batch.ForEach(func(event *pipeline.Event) {
+		data.msgBuf = p.formatEvent(data.msgBuf[:0], event.Root, now)
+		if p.config.Framing == framingNewLine {
+			outBuf = appendWithoutNewLines(outBuf, data.msgBuf)
+			outBuf = append(outBuf, '\n')
+		} else {
+			outBuf = strconv.AppendInt(outBuf, int64(len(data.msgBuf)), 10)
+			outBuf = append(outBuf, ' ')
+			outBuf = append(outBuf, data.msgBuf...)
+		}
+	})
+	data.outBuf = outBuf
+
+	_, err := data.client.send(outBuf)
+	if err != nil {
+		p.sendErrorMetric.Inc()
+		p.logger.Errorf("can't send data to syslog address=%s, err: %s", p.config.Address, err.Error())
+		_ = data.client.close()
+		data.client = nil
+		return err
+	}
+
+	return nil
+}
+
+// outUDP sends every message in its own datagram, the messages which can't be sent are dropped.
+func (p *Plugin) outUDP(data *data, batch *pipeline.Batch) {
+	now := time.Now()
+	batch.ForEach(func(event *pipeline.Event) {
+		data.msgBuf = p.formatEvent(data.msgBuf[:0], event.Root, now)
+		if _, err := data.client.send(data.msgBuf); err != nil {
+			p.sendErrorMetric.Inc()
+			p.logger.Errorf("can't send data to syslog address=%s, err: %s", p.config.Address, err.Error())
+		}
+	})
+}
+
+func (p *Plugin) maintenance(workerData *pipeline.WorkerData) {
+	if *workerData == nil {
+		return
+	}
+
+	data := (*workerData).(*data)
+	if data.client == nil {
+		return
+	}
+
+	p.logger.Infof("reconnecting worker...")
+	_ = data.client.close()
+	data.client = nil
+}
+
+func (p *Plugin) formatEvent(buf []byte, root *insaneJSON.Root, now time.Time) []byte {
+	m := message{
+		priority: p.facility*8 + int(p.severity(root)),
+		time:     now,
+		hostname: p.hostname,
+		appName:  p.config.AppName,
+	}
+
+	if node := root.Dig(p.config.TimeField_...); node != nil {
+		if t, err := pipeline.ParseTime(p.config.TimeFormat, node.AsString()); err == nil {
+			m.time = t
+		}
+	}
+	if node := root.Dig(p.config.HostnameField_...); node != nil && node.AsString() != "" {
+		m.hostname = node.AsString()
+	}
+	if node := root.Dig(p.config.AppNameField_...); node != nil && node.AsString() != "" {
+		m.appName = node.AsString()
+	}
+	if node := root.Dig(p.config.ProcIDField_...); node != nil {
+		m.procID = node.AsString()
+	}
+	if len(p.config.MsgIDField_) > 0 {
+		if node := root.Dig(p.config.MsgIDField_...); node != nil {
+			m.msgID = node.AsString()
+		}
+	}
+
+	// the message is appended to the end of the buffer to avoid the allocation
+	node := root.Dig(p.config.MessageField_...)
+	if node == nil {
+		node = root.Node
+	}
+	start := len(buf)
+	if node.IsString() {
+		buf = append(buf, node.AsString()...)
+	} else {
+		buf = node.Encode(buf)
+	}
+	m.msg = buf[start:]
+
+	end := len(buf)
+	if p.config.Format == formatRFC3164 {
+		buf = appendRFC3164(buf, &m)
+	} else {
+		buf = appendRFC5424(buf, &m)
+	}
+
+	// move the formatted message to the beginning of the buffer
+	n := copy(buf[start:], buf[end:])
+	return buf[:start+n]
+}
+
+func (p *Plugin) severity(root *insaneJSON.Root) pipeline.LogLevel {
+	node := root.Dig(p.config.SeverityField_...)
+	if node == nil {
+		return p.defaultSeverity
+	}
+
+	severity := pipeline.ParseLevelAsNumber(node.AsString())
+	if severity == pipeline.LevelUnknown {
+		return p.defaultSeverity
+	}
+	return severity
+}
+
+func appendWithoutNewLines(buf, msg []byte) []byte {
+	for _, c := range msg {
+		if c == '\n' || c == '\r' {
+			c = ' '
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
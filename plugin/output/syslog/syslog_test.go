@@ -0,0 +1,208 @@
+package syslog
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ozontech/file.d/metric"
+	"github.com/ozontech/file.d/pipeline"
+	"github.com/ozontech/file.d/test"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	insaneJSON "github.com/vitkovskii/insane-json"
+	"go.uber.org/zap"
+)
+
+func newTestPlugin(t *testing.T, config *Config) *Plugin {
+	t.Helper()
+
+	test.NewConfig(config, map[string]int{"gomaxprocs": 1, "capacity": 64})
+	p := &Plugin{
+		config: config,
+		logger: zap.NewExample().Sugar(),
+	}
+	p.registerMetrics(metric.NewCtl("test", prometheus.NewRegistry()))
+	p.prepare()
+	return p
+}
+
+func newTestBatch(t *testing.T, events ...string) *pipeline.Batch {
+	t.Helper()
+
+	batch := make([]*pipeline.Event, 0, len(events))
+	for _, e := range events {
+		root, err := insaneJSON.DecodeString(e)
+		require.NoError(t, err)
+		t.Cleanup(func() { insaneJSON.Release(root) })
+		batch = append(batch, &pipeline.Event{Root: root})
+	}
+	return pipeline.NewPreparedBatch(batch)
+}
+
+func TestFormatEvent(t *testing.T) {
+	now := time.Date(2023, 10, 19, 10, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name   string
+		config Config
+		event  string
+		want   string
+	}{
+		{
+			name:   "rfc5424",
+			config: Config{Facility: "local0", MsgIDField: "type"},
+			event:  `{"time":"2023-10-19T09:00:00.123456789Z","host":"node-1","app":"my app","pid":42,"type":"login","level":"warn","message":"user logged in"}`,
+			want:   `<132>1 2023-10-19T09:00:00.123456Z node-1 my_app 42 login - user logged in`,
+		},
+		{
+			name:   "rfc5424_defaults",
+			config: Config{Hostname: "default-host"},
+			event:  `{"level":"unknown","data":{"k":"v"}}`,
+			want:   `<14>1 2023-10-19T10:00:00.000000Z default-host file.d - - - {"level":"unknown","data":{"k":"v"}}`,
+		},
+		{
+			name:   "rfc3164",
+			config: Config{Format: formatRFC3164, Facility: "auth"},
+			event:  `{"time":"2023-10-19T09:00:00Z","host":"node-1","app":"sshd","pid":"7","level":"3","message":"auth failed"}`,
+			want:   `<35>Oct 19 09:00:00 node-1 sshd[7]: auth failed`,
+		},
+		{
+			name:   "rfc3164_no_pid",
+			config: Config{Format: formatRFC3164, Hostname: "default-host"},
+			event:  `{"message":"hello"}`,
+			want:   `<14>Oct 19 10:00:00 default-host file.d: hello`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.config.Address = "127.0.0.1:514"
+			p := newTestPlugin(t, &tc.config)
+
+			root, err := insaneJSON.DecodeString(tc.event)
+			require.NoError(t, err)
+			defer insaneJSON.Release(root)
+
+			require.Equal(t, tc.want, string(p.formatEvent([]byte("garbage"), root, now)[len("garbage"):]))
+		})
+	}
+}
+
+func readTCP(t *testing.T, lis net.Listener, n int) chan []byte {
+	t.Helper()
+
+	result := make(chan []byte, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			close(result)
+			return
+		}
+		defer conn.Close()
+
+		buf := make([]byte, n)
+		_, _ = io.ReadFull(conn, buf)
+		result <- buf
+	}()
+	return result
+}
+
+func TestTCP(t *testing.T) {
+	events := []string{
+		`{"time":"2023-10-19T10:00:00Z","host":"h","message":"first\nline"}`,
+		`{"time":"2023-10-19T10:00:00Z","host":"h","message":"second"}`,
+	}
+
+	cases := []struct {
+		name    string
+		framing string
+		want    string
+	}{
+		{
+			name: "octet_counting",
+			want: "59 <14>1 2023-10-19T10:00:00.000000Z h file.d - - - first\nline" +
+				"55 <14>1 2023-10-19T10:00:00.000000Z h file.d - - - second",
+		},
+		{
+			name:    "new_line",
+			framing: framingNewLine,
+			want: "<14>1 2023-10-19T10:00:00.000000Z h file.d - - - first line\n" +
+				"<14>1 2023-10-19T10:00:00.000000Z h file.d - - - second\n",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			defer lis.Close()
+			result := readTCP(t, lis, len(tc.want))
+
+			p := newTestPlugin(t, &Config{
+				Address: lis.Addr().String(),
+				Network: networkTCP,
+				Framing: tc.framing,
+			})
+
+			data := pipeline.WorkerData(nil)
+			require.NoError(t, p.out(&data, newTestBatch(t, events...)))
+			require.Equal(t, tc.want, string(<-result))
+		})
+	}
+}
+
+func TestTLS(t *testing.T) {
+	server := httptest.NewUnstartedServer(nil)
+	server.StartTLS()
+	server.Close()
+	caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", server.TLS)
+	require.NoError(t, err)
+	defer lis.Close()
+
+	const want = `52 <14>1 2023-10-19T10:00:00.000000Z h file.d - - - tls`
+	result := readTCP(t, lis, len(want))
+
+	p := newTestPlugin(t, &Config{
+		Address: lis.Addr().String(),
+		Network: networkTLS,
+		CACert:  string(caCert),
+	})
+
+	data := pipeline.WorkerData(nil)
+	require.NoError(t, p.out(&data, newTestBatch(t, `{"time":"2023-10-19T10:00:00Z","host":"h","message":"tls"}`)))
+	require.Equal(t, want, string(<-result))
+}
+
+func TestUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	p := newTestPlugin(t, &Config{
+		Address:  conn.LocalAddr().String(),
+		Network:  networkUDP,
+		Format:   formatRFC3164,
+		Hostname: "h",
+	})
+
+	data := pipeline.WorkerData(nil)
+	require.NoError(t, p.out(&data, newTestBatch(t,
+		`{"time":"2023-10-19T09:00:00Z","message":"first"}`,
+		`{"time":"2023-10-19T09:00:01Z","message":"second"}`,
+	)))
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 1024)
+	for _, want := range []string{
+		`<14>Oct 19 09:00:00 h file.d: first`,
+		`<14>Oct 19 09:00:01 h file.d: second`,
+	} {
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		require.Equal(t, want, string(buf[:n]))
+	}
+}
@@ -229,6 +229,7 @@ func newDefaultParams() pipeline.PluginDefaultParams {
 		PipelineName:     "test_pipeline",
 		PipelineSettings: &pipeline.Settings{},
 		MetricCtl:        metric.NewCtl("test", prometheus.NewRegistry()),
+		MetricHolder:     metric.NewHolder(pipeline.DefaultMetricHoldDuration),
 	}
 }
 
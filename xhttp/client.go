@@ -0,0 +1,112 @@
+package xhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ozontech/file.d/xtls"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type ClientConfig struct {
+	// Timeout limits the time of sending one request.
+	Timeout time.Duration
+	// CACert is a path or content of a PEM-encoded CA file, the host's root CA set is used if empty.
+	CACert string
+
+	// BearerToken takes precedence over Username and Password.
+	BearerToken string
+	Username    string
+	Password    string
+}
+
+// Client sends the requests of the output plugins which push the batches over HTTP.
+type Client struct {
+	client     *http.Client
+	authHeader string
+}
+
+func NewClient(cfg *ClientConfig) (*Client, error) {
+	transport := &http.Transport{}
+	if cfg.CACert != "" {
+		b := xtls.NewConfigBuilder()
+		if err := b.AppendCARoot(cfg.CACert); err != nil {
+			return nil, fmt.Errorf("can't append CA root: %w", err)
+		}
+		transport.TLSClientConfig = b.Build()
+	}
+
+	return &Client{
+		client: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: transport,
+		},
+		authHeader: authHeader(cfg),
+	}, nil
+}
+
+func authHeader(cfg *ClientConfig) string {
+	if cfg.BearerToken != "" {
+		return "Bearer " + cfg.BearerToken
+	}
+	if cfg.Username != "" && cfg.Password != "" {
+		credentials := []byte(cfg.Username + ":" + cfg.Password)
+		return "Basic " + base64.StdEncoding.EncodeToString(credentials)
+	}
+	return ""
+}
+
+// Send sends the body and returns the status code and the body of the response.
+// The status code is zero if the request isn't sent.
+// The responses with non 2xx status codes are returned as the error with the response body.
+// The header overrides the headers set by the client, e.g. `Authorization`.
+func (c *Client) Send(ctx context.Context, method, url string, header http.Header, body []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, fmt.Errorf("can't create request: %w", err)
+	}
+
+	if c.authHeader != "" {
+		req.Header.Set("Authorization", c.authHeader)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("can't send request: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("can't read response: %w", err)
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, b, fmt.Errorf("bad response: code=%s, body=%s", resp.Status, bytes.TrimSpace(b))
+	}
+	return resp.StatusCode, b, nil
+}
+
+// OnError returns the error handler of the retriable batcher,
+// it logs the batch which isn't sent after all the retries and exits if fatal is set.
+func OnError(logger *zap.SugaredLogger, msg string, fatal bool, retries int) func(err error) {
+	level := zapcore.ErrorLevel
+	if fatal {
+		level = zapcore.FatalLevel
+	}
+
+	return func(err error) {
+		logger.Desugar().Log(level, msg, zap.Error(err), zap.Int("retries", retries))
+	}
+}
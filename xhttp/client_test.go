@@ -0,0 +1,61 @@
+package xhttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthHeader(t *testing.T) {
+	require.Equal(t, "", authHeader(&ClientConfig{Username: "user"}))
+	require.Equal(t, "Basic dXNlcjpwYXNz", authHeader(&ClientConfig{Username: "user", Password: "pass"}))
+	require.Equal(t, "Bearer token", authHeader(&ClientConfig{BearerToken: "token", Username: "user", Password: "pass"}))
+}
+
+func TestClientSend(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		body, _ := io.ReadAll(r.Body)
+		if string(body) == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("rejected\n"))
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(&ClientConfig{Timeout: time.Second, BearerToken: "token"})
+	require.NoError(t, err)
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+
+	code, body, err := client.Send(context.Background(), http.MethodPost, server.URL, header, []byte("ok"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "ok", string(body))
+	require.Equal(t, "Bearer token", headers.Get("Authorization"))
+	require.Equal(t, "application/json", headers.Get("Content-Type"))
+
+	code, _, err = client.Send(context.Background(), http.MethodPost, server.URL, header, []byte("bad"))
+	require.EqualError(t, err, "bad response: code=400 Bad Request, body=rejected")
+	require.Equal(t, http.StatusBadRequest, code)
+
+	// the header overrides the authorization of the client
+	header.Set("Authorization", "Custom")
+	_, _, err = client.Send(context.Background(), http.MethodPost, server.URL, header, []byte("ok"))
+	require.NoError(t, err)
+	require.Equal(t, "Custom", headers.Get("Authorization"))
+
+	server.Close()
+	code, _, err = client.Send(context.Background(), http.MethodPost, server.URL, header, []byte("ok"))
+	require.Error(t, err)
+	require.Zero(t, code)
+}